@@ -2,17 +2,30 @@ package handler
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"slices"
 	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/JohnSalinas123/linguachat-backend-go/internal/database"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/learning"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/permissions"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/translation"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/transliteration"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/websockets"
 	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5"
 )
 
 func GetChatsHandler(c *gin.Context) {
@@ -55,14 +68,20 @@ func GetChatMessagesHandler(c *gin.Context) {
 	pageNum := c.Query("pageNum")
 	langCode := c.Query("langCode")
 
-	// format langCode into database compatible array
-	langCode = "{" + langCode + "}"
-
 	if len(langCode) == 0 {
 		log.Println("LangCode query parameter missing")
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
 	}
 
+	langCode, ok := normalizeLangCode(c, langCode)
+	if !ok {
+		return
+	}
+
+	// format langCode into database compatible array
+	langCode = translation.StoredLangCode(langCode)
+
 	if len(chatId) == 0 {
 		log.Println("ChatId path parameter missing")
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
@@ -84,12 +103,25 @@ func GetChatMessagesHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
 	}
 
-	messagesResponse, dbError := db.GetChatMessages(context.Background(),langCode, chatId, pageNumInt)
+	userID := c.MustGet("userID").(string)
+
+	messagesResponse, dbError := db.GetChatMessages(context.Background(), userID, langCode, chatId, pageNumInt)
 	if dbError != nil {
 		log.Println("Failed to retrieve messages for chat: %w", dbError)
 		c.JSON(http.StatusInternalServerError, gin.H{"error":"Internal server error"})
 	}
 
+	// learners with learning mode on read messages in their target language with glosses
+	err = learning.ApplyLearningMode(context.Background(), chatId, userID, messagesResponse)
+	if err != nil {
+		log.Println("Failed to apply learning mode: %w", err)
+	}
+
+	err = transliteration.ApplyRomanization(context.Background(), userID, messagesResponse)
+	if err != nil {
+		log.Println("Failed to apply romanization: %w", err)
+	}
+
 	// reverse the orderof messagesResponse slice
 	slices.Reverse(messagesResponse)
 
@@ -116,7 +148,53 @@ func PostNewInviteHandler(c *gin.Context) {
 		return
 	}
 
-	inviteCode, dbError := db.CreateInvite(context.Background(), userID) 
+	// chat_id in body is optional, when present the invite joins an existing chat
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		log.Println("Failed to read request body")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	var chatID string
+	if len(body) > 0 {
+		var bodyMap map[string]interface{}
+		err = json.Unmarshal(body, &bodyMap)
+		if err != nil {
+			log.Println("Failed to unmarshal body to map[string]interface: %w", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+
+		if chatIDAny, exists := bodyMap["chat_id"]; exists {
+			chatID, ok = chatIDAny.(string)
+			if !ok || chatID == "" {
+				log.Println("Invalid chat_id in body")
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+				return
+			}
+
+			if _, err := uuid.FromString(chatID); err != nil {
+				log.Println("Invalid chat_id in body: %w", err)
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+				return
+			}
+
+			_, permErr := permissions.Authorize(context.Background(), chatID, userID, permissions.ActionInvite)
+			if permErr != nil {
+				if errors.Is(permErr, permissions.ErrNotParticipant) || errors.Is(permErr, permissions.ErrForbidden) {
+					log.Printf("User %s not permitted to invite to chat %s: %v", userID, chatID, permErr)
+					c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+					return
+				}
+				log.Println("Failed to authorize invite: %w", permErr)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+				return
+			}
+		}
+	}
+
+	inviteCode, dbError := db.CreateInvite(context.Background(), userID, chatID) 
 	if dbError != nil {
 		log.Println("Failed to create new invite: %w", dbError)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to create new chat invite"})
@@ -205,12 +283,131 @@ func PostAcceptChatInviteHandler(c *gin.Context) {
 		return
 	}	
 
-	newChatResponse, dbError := db.PostNewChatFromInvite(context.Background(), userID, inviteCode)
+	mayInvite := func(role models.ChatRole) bool {
+		return permissions.Can(role, permissions.ActionInvite)
+	}
+
+	newChatResponse, dbError := db.PostNewChatFromInvite(context.Background(), userID, inviteCode, mayInvite)
 	if dbError != nil {
+		// the creator left the chat or lost the role to invite since creating it
+		if errors.Is(dbError, database.ErrInviteRevoked) {
+			log.Printf("Invite %s revoked: %v", inviteCode, dbError)
+			c.JSON(http.StatusForbidden, gin.H{"error": "Invite no longer valid"})
+			return
+		}
 		log.Println("Failed to create new chat: %w", dbError)
 		c.JSON(http.StatusInternalServerError, gin.H{"error":"Internal server error"})
+		return
 	}
 
 	c.JSON(http.StatusOK, newChatResponse)
 
-}
\ No newline at end of file
+}
+
+const (
+	maxChatTitleLength       = 100
+	maxChatDescriptionLength = 500
+	maxChatAvatarURLLength   = 2048
+)
+
+func PatchChatHandler(c *gin.Context) {
+
+	// access database instance
+	db := database.GetPostgresConn()
+
+	userID := c.MustGet("userID").(string)
+	chatID := c.Param("chatID")
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		log.Println("Missing requests body")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	var bodyMap map[string]interface{}
+	err = json.Unmarshal(body, &bodyMap)
+	if err != nil {
+		log.Println("Failed to unmarshal body to map[string]interface: %w", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	chat, dbError := db.GetChatMetadata(context.Background(), chatID)
+	if dbError != nil {
+		if dbError == pgx.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Chat not found"})
+			return
+		}
+		log.Println("Failed to retrieve chat: %w", dbError)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// only fields present in the body are changed, null or "" clears a field
+	fields := []struct {
+		key       string
+		maxLength int
+		target    *sql.NullString
+	}{
+		{"title", maxChatTitleLength, &chat.Title},
+		{"description", maxChatDescriptionLength, &chat.Description},
+		{"avatar_url", maxChatAvatarURLLength, &chat.AvatarURL},
+	}
+
+	for _, field := range fields {
+		valueAny, exists := bodyMap[field.key]
+		if !exists {
+			continue
+		}
+
+		var value string
+		if valueAny != nil {
+			valueStr, ok := valueAny.(string)
+			if !ok {
+				log.Printf("Invalid %s in body", field.key)
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+				return
+			}
+			value = strings.TrimSpace(valueStr)
+		}
+
+		if utf8.RuneCountInString(value) > field.maxLength {
+			log.Printf("Field %s exceeds %d characters", field.key, field.maxLength)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+
+		if field.key == "avatar_url" && value != "" {
+			avatarURL, err := url.Parse(value)
+			if err != nil || (avatarURL.Scheme != "https" && avatarURL.Scheme != "http") || avatarURL.Host == "" {
+				log.Println("Invalid avatar_url in body")
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+				return
+			}
+		}
+
+		*field.target = sql.NullString{String: value, Valid: value != ""}
+	}
+
+	dbError = db.UpdateChatMetadata(context.Background(), &chat)
+	if dbError != nil {
+		log.Println("Failed to update chat metadata: %w", dbError)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	chatMetadata := models.ChatMetadataResponse{
+		ID:          chat.ID,
+		Title:       chat.Title.String,
+		Description: chat.Description.String,
+		AvatarURL:   chat.AvatarURL.String,
+		UpdatedBy:   userID,
+	}
+
+	// let connected participants update the chat title and header in place
+	websockets.GetHub().Publish(models.ChatEvent{Type: models.EventChatUpdated, ChatID: chat.ID, Payload: chatMetadata})
+
+	c.JSON(http.StatusOK, chatMetadata)
+
+}
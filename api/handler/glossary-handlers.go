@@ -0,0 +1,223 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/database"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/translation"
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// maxGlossaryTermLength is the number of characters a glossary term or its translation may have
+	maxGlossaryTermLength = 100
+
+	// maxGlossaryTerms bounds a chat glossary, every term is looked for in every message translated
+	maxGlossaryTerms = 500
+)
+
+func GetGlossaryHandler(c *gin.Context) {
+
+	// access database instance
+	db := database.GetPostgresConn()
+
+	chatID := c.Param("chatID")
+
+	terms, dbError := db.GetChatGlossary(context.Background(), chatID)
+	if dbError != nil {
+		log.Println("Failed to retrieve chat glossary: %w", dbError)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, terms)
+
+}
+
+func PostGlossaryTermHandler(c *gin.Context) {
+
+	// access database instance
+	db := database.GetPostgresConn()
+
+	userID := c.MustGet("userID").(string)
+
+	chatUUID, err := uuid.FromString(c.Param("chatID"))
+	if err != nil {
+		log.Println("Invalid chatID path parameter: %w", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	term, ok := bindGlossaryTerm(c)
+	if !ok {
+		return
+	}
+	term.ChatID = chatUUID
+	term.CreatedBy = userID
+
+	created, dbError := db.CreateGlossaryTerm(context.Background(), &term, maxGlossaryTerms)
+	if dbError != nil {
+		if errors.Is(dbError, database.ErrGlossaryTermExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Term already in glossary"})
+			return
+		}
+		if errors.Is(dbError, database.ErrGlossaryFull) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Glossary is full"})
+			return
+		}
+		log.Println("Failed to create glossary term: %w", dbError)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	invalidateGlossary(chatUUID.String())
+
+	c.JSON(http.StatusCreated, created)
+
+}
+
+func PutGlossaryTermHandler(c *gin.Context) {
+
+	// access database instance
+	db := database.GetPostgresConn()
+
+	chatUUID, err := uuid.FromString(c.Param("chatID"))
+	if err != nil {
+		log.Println("Invalid chatID path parameter: %w", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	termUUID, err := uuid.FromString(c.Param("termID"))
+	if err != nil {
+		log.Println("Invalid termID path parameter: %w", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	term, ok := bindGlossaryTerm(c)
+	if !ok {
+		return
+	}
+	term.ID = termUUID
+	term.ChatID = chatUUID
+
+	updated, dbError := db.UpdateGlossaryTerm(context.Background(), &term)
+	if dbError != nil {
+		switch {
+		case dbError == pgx.ErrNoRows:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Glossary term not found"})
+		case errors.Is(dbError, database.ErrGlossaryTermExists):
+			c.JSON(http.StatusConflict, gin.H{"error": "Term already in glossary"})
+		default:
+			log.Println("Failed to update glossary term: %w", dbError)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		}
+		return
+	}
+
+	invalidateGlossary(chatUUID.String())
+
+	c.JSON(http.StatusOK, updated)
+
+}
+
+func DeleteGlossaryTermHandler(c *gin.Context) {
+
+	// access database instance
+	db := database.GetPostgresConn()
+
+	chatUUID, err := uuid.FromString(c.Param("chatID"))
+	if err != nil {
+		log.Println("Invalid chatID path parameter: %w", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	termUUID, err := uuid.FromString(c.Param("termID"))
+	if err != nil {
+		log.Println("Invalid termID path parameter: %w", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	dbError := db.DeleteGlossaryTerm(context.Background(), chatUUID.String(), termUUID.String())
+	if dbError != nil {
+		if dbError == pgx.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Glossary term not found"})
+			return
+		}
+		log.Println("Failed to delete glossary term: %w", dbError)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	invalidateGlossary(chatUUID.String())
+
+	c.JSON(http.StatusOK, gin.H{"deleted": termUUID})
+
+}
+
+// bindGlossaryTerm reads and validates a GlossaryTermRequest body,
+// the translation of a term kept as written is ignored
+func bindGlossaryTerm(c *gin.Context) (models.GlossaryTerm, bool) {
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		log.Println("Missing requests body")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return models.GlossaryTerm{}, false
+	}
+
+	var req models.GlossaryTermRequest
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		log.Println("Failed to unmarshal body to GlossaryTermRequest: %w", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return models.GlossaryTerm{}, false
+	}
+
+	term := models.GlossaryTerm{
+		Term:           strings.TrimSpace(req.Term),
+		DoNotTranslate: req.DoNotTranslate,
+	}
+
+	// an empty target_lang_code applies the term to every language
+	if strings.TrimSpace(req.TargetLangCode) != "" {
+		var ok bool
+		term.TargetLangCode, ok = normalizeLangCode(c, req.TargetLangCode)
+		if !ok {
+			return models.GlossaryTerm{}, false
+		}
+	}
+	if !term.DoNotTranslate {
+		term.Translation = strings.TrimSpace(req.Translation)
+	}
+
+	if term.Term == "" || utf8.RuneCountInString(term.Term) > maxGlossaryTermLength ||
+		(!term.DoNotTranslate && term.Translation == "") || utf8.RuneCountInString(term.Translation) > maxGlossaryTermLength {
+		log.Println("Glossary term needs a term and either a translation or do_not_translate")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return models.GlossaryTerm{}, false
+	}
+
+	return term, true
+}
+
+// invalidateGlossary drops cached translations made with the previous glossary of chatID,
+// failing to do so only leaves unused cache entries behind
+func invalidateGlossary(chatID string) {
+	if err := translation.InvalidateGlossary(context.Background(), chatID); err != nil {
+		log.Printf("Failed to invalidate glossary translations of chat %s: %v", chatID, err)
+	}
+}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/database"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultDeadJobs = 50
+	maxDeadJobs     = 500
+)
+
+// GetDeadJobsHandler lists the background jobs that failed for good, most recent first,
+// optionally of a single kind
+func GetDeadJobsHandler(c *gin.Context) {
+
+	// access database instance
+	db := database.GetPostgresConn()
+
+	limit := defaultDeadJobs
+	if limitStr := c.Query("limit"); len(limitStr) != 0 {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > maxDeadJobs {
+			log.Println("Invalid limit query parameter")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+	}
+
+	deadJobs, dbError := db.GetDeadJobs(context.Background(), c.Query("kind"), limit)
+	if dbError != nil {
+		log.Println("Failed to retrieve dead jobs: %w", dbError)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	response := make([]models.DeadJobResponse, 0, len(deadJobs))
+	for _, job := range deadJobs {
+		response = append(response, models.DeadJobResponse{
+			ID:        job.ID,
+			Kind:      job.Kind,
+			Payload:   job.Payload,
+			Attempts:  job.Attempts,
+			LastError: job.LastError.String,
+			CreatedAt: job.CreatedAt,
+			FailedAt:  job.UpdatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, response)
+
+}
@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/languages"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// GetLanguagesHandler lists the supported languages, ?translation=true keeps only
+// the ones offered for translation
+func GetLanguagesHandler(c *gin.Context) {
+
+	registered := languages.All()
+
+	if c.Query("translation") == "true" {
+		translatable := []models.LanguageResponse{}
+		for _, language := range registered {
+			if language.Translation {
+				translatable = append(translatable, language)
+			}
+		}
+		registered = translatable
+	}
+
+	c.JSON(http.StatusOK, registered)
+
+}
+
+// normalizeLangCode returns the registry code of langCode, responding with 400 when
+// it is not a supported language
+func normalizeLangCode(c *gin.Context, langCode string) (string, bool) {
+
+	normalized, err := languages.Normalize(langCode)
+	if err != nil {
+		log.Printf("Invalid lang_code %q: %v", langCode, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported language"})
+		return "", false
+	}
+
+	return normalized, true
+}
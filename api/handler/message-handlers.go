@@ -0,0 +1,434 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/database"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/learning"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/permissions"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/translation"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/transliteration"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/websockets"
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+func PutMessageHandler(c *gin.Context) {
+
+	userID := c.MustGet("userID").(string)
+	chatID := c.Param("chatID")
+	messageID, ok := messageIDParam(c)
+	if !ok {
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		log.Println("Missing requests body")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	var bodyMap map[string]interface{}
+	err = json.Unmarshal(body, &bodyMap)
+	if err != nil {
+		log.Println("Failed to unmarshal body to map[string]interface: %w", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	content, ok := bodyMap["content"].(string)
+	if !ok || strings.TrimSpace(content) == "" {
+		log.Println("Missing or invalid content in body")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	editedMessage, err := websockets.EditMessage(context.Background(), websockets.GetHub(), chatID, messageID, userID, content)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		case errors.Is(err, database.ErrNotMessageSender):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		case errors.Is(err, database.ErrMessageDeleted):
+			c.JSON(http.StatusConflict, gin.H{"error": "Message has been deleted"})
+		case errors.Is(err, websockets.ErrContentTooLong):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Content too long"})
+		default:
+			log.Println("Failed to edit message: %w", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		}
+		return
+	}
+
+	// the editor is the sender, respond with their original content
+	c.JSON(http.StatusOK, editedMessage.ForViewer(models.Viewer{UserID: userID}))
+
+}
+
+func GetMessageEditsHandler(c *gin.Context) {
+
+	// access database instance
+	db := database.GetPostgresConn()
+
+	chatID := c.Param("chatID")
+	messageID, ok := messageIDParam(c)
+	if !ok {
+		return
+	}
+
+	edits, dbError := db.GetMessageEdits(context.Background(), chatID, messageID)
+	if dbError != nil {
+		log.Println("Failed to retrieve message edits: %w", dbError)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, edits)
+
+}
+
+func DeleteMessageHandler(c *gin.Context) {
+
+	// access database instance
+	db := database.GetPostgresConn()
+
+	userID := c.MustGet("userID").(string)
+	role := c.MustGet("chatRole").(models.ChatRole)
+	chatID := c.Param("chatID")
+	messageID, ok := messageIDParam(c)
+	if !ok {
+		return
+	}
+
+	// scope=everyone tombstones the message, scope=me only hides it for the user
+	scope := c.DefaultQuery("scope", "me")
+
+	switch scope {
+	case "everyone":
+		deleted, err := websockets.DeleteMessageForEveryone(context.Background(), websockets.GetHub(), chatID, messageID, userID, role)
+		if err != nil {
+			switch {
+			case errors.Is(err, pgx.ErrNoRows):
+				c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+			case errors.Is(err, database.ErrNotMessageSender):
+				c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			default:
+				log.Println("Failed to delete message: %w", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			}
+			return
+		}
+
+		c.JSON(http.StatusOK, deleted)
+
+	case "me":
+		dbError := db.HideMessageForUser(context.Background(), chatID, messageID, userID)
+		if dbError != nil {
+			if errors.Is(dbError, pgx.ErrNoRows) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+				return
+			}
+			log.Println("Failed to hide message: %w", dbError)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message_id": messageID, "hidden": true})
+
+	default:
+		log.Println("Invalid scope query parameter")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+	}
+
+}
+
+func GetMessageThreadHandler(c *gin.Context) {
+
+	// access database instance
+	db := database.GetPostgresConn()
+
+	userID := c.MustGet("userID").(string)
+	chatID := c.Param("chatID")
+	messageID, ok := messageIDParam(c)
+	if !ok {
+		return
+	}
+	langCode := c.Query("langCode")
+
+	if len(langCode) == 0 {
+		log.Println("LangCode query parameter missing")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	langCode, ok = normalizeLangCode(c, langCode)
+	if !ok {
+		return
+	}
+
+	// format langCode into database compatible array
+	langCode = translation.StoredLangCode(langCode)
+
+	thread, dbError := db.GetMessageThread(context.Background(), userID, langCode, chatID, messageID)
+	if dbError != nil {
+		log.Println("Failed to retrieve message thread: %w", dbError)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	if len(thread) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		return
+	}
+
+	err := learning.ApplyLearningMode(context.Background(), chatID, userID, thread)
+	if err != nil {
+		log.Println("Failed to apply learning mode: %w", err)
+	}
+
+	err = transliteration.ApplyRomanization(context.Background(), userID, thread)
+	if err != nil {
+		log.Println("Failed to apply romanization: %w", err)
+	}
+
+	c.JSON(http.StatusOK, thread)
+
+}
+
+func PostReactionHandler(c *gin.Context) {
+
+	userID := c.MustGet("userID").(string)
+	chatID := c.Param("chatID")
+	messageID, ok := messageIDParam(c)
+	if !ok {
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		log.Println("Missing requests body")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	var bodyMap map[string]interface{}
+	err = json.Unmarshal(body, &bodyMap)
+	if err != nil {
+		log.Println("Failed to unmarshal body to map[string]interface: %w", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	emoji, ok := bodyMap["emoji"].(string)
+	if ok {
+		emoji, ok = websockets.NormalizeEmoji(emoji)
+	}
+	if !ok {
+		log.Println("Missing or invalid emoji in body")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	// posting an existing reaction again removes it
+	reaction, err := websockets.ToggleReaction(context.Background(), websockets.GetHub(), chatID, messageID, userID, emoji)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+			return
+		}
+		log.Println("Failed to toggle reaction: %w", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, reaction)
+
+}
+
+func GetMessageGlossHandler(c *gin.Context) {
+
+	// access database instance
+	db := database.GetPostgresConn()
+
+	userID := c.MustGet("userID").(string)
+	messageID, ok := messageIDParam(c)
+	if !ok {
+		return
+	}
+
+	tokenIndex, err := strconv.Atoi(c.Query("token"))
+	if err != nil || tokenIndex < 0 {
+		log.Println("Missing or invalid token query parameter")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	msg, dbError := db.GetMessage(context.Background(), messageID)
+	if dbError != nil {
+		if dbError == pgx.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+			return
+		}
+		log.Println("Failed to retrieve message: %w", dbError)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// the route carries no chat, so membership of the message's chat is checked here
+	_, err = permissions.Authorize(context.Background(), msg.ChatID.String(), userID, permissions.ActionViewChat)
+	if err != nil {
+		if errors.Is(err, permissions.ErrNotParticipant) || errors.Is(err, permissions.ErrForbidden) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+			return
+		}
+		log.Println("Failed to authorize gloss lookup: %w", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	if msg.DeletedAt != nil {
+		c.JSON(http.StatusGone, gin.H{"error": "Message deleted"})
+		return
+	}
+
+	// gloss into the requested language, otherwise the user's native language
+	targetLang := c.Query("lang")
+	if len(targetLang) != 0 {
+		var ok bool
+		targetLang, ok = normalizeLangCode(c, targetLang)
+		if !ok {
+			return
+		}
+	} else {
+		targetLang, dbError = db.GetUserLangCode(context.Background(), userID)
+		if dbError != nil {
+			log.Println("Failed to retrieve user lang code: %w", dbError)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+	}
+
+	gloss, err := learning.GlossWord(context.Background(), msg.Content, msg.LangCode, tokenIndex, targetLang)
+	if err != nil {
+		if errors.Is(err, learning.ErrTokenOutOfRange) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Token out of range"})
+			return
+		}
+		log.Println("Failed to gloss word: %w", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	gloss.MessageID = msg.ID
+
+	c.JSON(http.StatusOK, gloss)
+
+}
+
+func PostCorrectionHandler(c *gin.Context) {
+
+	userID := c.MustGet("userID").(string)
+	chatID := c.Param("chatID")
+	messageID, ok := messageIDParam(c)
+	if !ok {
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		log.Println("Missing requests body")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	var bodyMap map[string]interface{}
+	err = json.Unmarshal(body, &bodyMap)
+	if err != nil {
+		log.Println("Failed to unmarshal body to map[string]interface: %w", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	content, ok := bodyMap["content"].(string)
+	if !ok || len(strings.TrimSpace(content)) == 0 {
+		log.Println("Missing or empty content in body")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	correction, err := websockets.CreateCorrection(context.Background(), websockets.GetHub(), chatID, messageID, userID, content)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		case errors.Is(err, database.ErrMessageDeleted):
+			c.JSON(http.StatusGone, gin.H{"error": "Message deleted"})
+		case errors.Is(err, websockets.ErrCorrectOwnMessage):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		case errors.Is(err, database.ErrCorrectionAccepted):
+			c.JSON(http.StatusConflict, gin.H{"error": "Correction already accepted"})
+		case errors.Is(err, websockets.ErrContentTooLong):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Content too long"})
+		default:
+			log.Println("Failed to correct message: %w", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, correction)
+
+}
+
+func PostCorrectionAcceptHandler(c *gin.Context) {
+
+	userID := c.MustGet("userID").(string)
+	chatID := c.Param("chatID")
+	messageID, ok := messageIDParam(c)
+	if !ok {
+		return
+	}
+	correctionID := c.Param("correctionID")
+
+	correction, err := websockets.AcceptCorrection(context.Background(), websockets.GetHub(), chatID, messageID, correctionID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Correction not found"})
+		case errors.Is(err, database.ErrNotMessageSender):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		case errors.Is(err, database.ErrCorrectionAccepted):
+			c.JSON(http.StatusConflict, gin.H{"error": "Correction already accepted"})
+		default:
+			log.Println("Failed to accept correction: %w", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, correction)
+
+}
+
+// messageIDParam returns the messageID path parameter, responding 400 when it is not a uuid
+func messageIDParam(c *gin.Context) (string, bool) {
+
+	messageID := c.Param("messageID")
+	if _, err := uuid.FromString(messageID); err != nil {
+		log.Println("Invalid messageID path parameter: %w", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return "", false
+	}
+
+	return messageID, true
+}
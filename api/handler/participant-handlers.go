@@ -0,0 +1,290 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/database"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/permissions"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/websockets"
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+func GetChatParticipantsHandler(c *gin.Context) {
+
+	// access database instance
+	db := database.GetPostgresConn()
+
+	chatID := c.Param("chatID")
+
+	participants, dbError := db.GetChatParticipants(context.Background(), chatID)
+	if dbError != nil {
+		log.Println("Failed to retrieve chat participants: %w", dbError)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, participants)
+
+}
+
+func PutParticipantRoleHandler(c *gin.Context) {
+
+	// access database instance
+	db := database.GetPostgresConn()
+
+	userID := c.MustGet("userID").(string)
+	actorRole := c.MustGet("chatRole").(models.ChatRole)
+
+	chatID := c.Param("chatID")
+	targetUserID := c.Param("userID")
+	if len(targetUserID) == 0 {
+		log.Println("UserId path parameter missing")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	if targetUserID == userID {
+		log.Println("Participants can not change their own role")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		log.Println("Missing requests body")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	var bodyMap map[string]interface{}
+	err = json.Unmarshal(body, &bodyMap)
+	if err != nil {
+		log.Println("Failed to unmarshal body to map[string]interface: %w", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	roleStr, ok := bodyMap["role"].(string)
+	newRole := models.ChatRole(roleStr)
+	if !ok || !permissions.ValidRole(newRole) {
+		log.Println("Missing or invalid role in body")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	targetRole, dbError := db.GetParticipantRole(context.Background(), chatID, targetUserID)
+	if dbError != nil {
+		if dbError == pgx.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Participant not found"})
+			return
+		}
+		log.Println("Failed to retrieve participant role: %w", dbError)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// participants may only manage roles below their own and never grant above it
+	if !permissions.Outranks(actorRole, targetRole) || permissions.Outranks(newRole, actorRole) {
+		log.Printf("Role %s can not change role %s to %s", actorRole, targetRole, newRole)
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return
+	}
+
+	dbError = db.UpdateParticipantRole(context.Background(), chatID, targetUserID, newRole)
+	if dbError != nil {
+		log.Println("Failed to update participant role: %w", dbError)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// open sockets of the participant act with the new role from their next frame
+	websockets.GetHub().SetRole(uuid.FromStringOrNil(chatID), targetUserID, newRole)
+
+	c.JSON(http.StatusOK, gin.H{"user_id": targetUserID, "role": newRole})
+
+}
+
+func DeleteParticipantHandler(c *gin.Context) {
+
+	// access database instance
+	db := database.GetPostgresConn()
+
+	userID := c.MustGet("userID").(string)
+	actorRole := c.MustGet("chatRole").(models.ChatRole)
+
+	chatID := c.Param("chatID")
+	targetUserID := c.Param("userID")
+	if len(targetUserID) == 0 {
+		log.Println("UserId path parameter missing")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	// any participant may leave, removing others requires permission and a higher role
+	if targetUserID != userID {
+
+		if !permissions.Can(actorRole, permissions.ActionRemoveParticipant) {
+			log.Printf("Role %s not permitted to remove participants", actorRole)
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			return
+		}
+
+		targetRole, dbError := db.GetParticipantRole(context.Background(), chatID, targetUserID)
+		if dbError != nil {
+			if dbError == pgx.ErrNoRows {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Participant not found"})
+				return
+			}
+			log.Println("Failed to retrieve participant role: %w", dbError)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		if !permissions.Outranks(actorRole, targetRole) {
+			log.Printf("Role %s can not remove role %s", actorRole, targetRole)
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			return
+		}
+	}
+
+	dbError := db.RemoveParticipant(context.Background(), chatID, targetUserID)
+	if dbError != nil {
+		if dbError == pgx.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Participant not found"})
+			return
+		}
+		// a chat with participants always keeps an admin
+		if errors.Is(dbError, database.ErrLastAdmin) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Promote another admin before leaving"})
+			return
+		}
+		log.Println("Failed to remove participant: %w", dbError)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// a removed participant stops receiving the chat's events
+	websockets.GetHub().Disconnect(uuid.FromStringOrNil(chatID), targetUserID)
+
+	c.JSON(http.StatusOK, gin.H{"removed": targetUserID})
+
+}
+
+func PostMessagePinHandler(c *gin.Context) {
+	setMessagePinned(c, true)
+}
+
+func DeleteMessagePinHandler(c *gin.Context) {
+	setMessagePinned(c, false)
+}
+
+// setMessagePinned handles both pinning and unpinning of a chat message
+func setMessagePinned(c *gin.Context, pinned bool) {
+
+	// access database instance
+	db := database.GetPostgresConn()
+
+	userID := c.MustGet("userID").(string)
+	chatID := c.Param("chatID")
+	messageID, ok := messageIDParam(c)
+	if !ok {
+		return
+	}
+
+	dbError := db.SetMessagePinned(context.Background(), chatID, messageID, userID, pinned)
+	if dbError != nil {
+		if dbError == pgx.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+			return
+		}
+		log.Println("Failed to update message pin: %w", dbError)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message_id": messageID, "pinned": pinned})
+
+}
+
+func GetLearningModeHandler(c *gin.Context) {
+
+	// access database instance
+	db := database.GetPostgresConn()
+
+	userID := c.MustGet("userID").(string)
+	chatID := c.Param("chatID")
+
+	_, enabled, dbError := db.GetLearningProfile(context.Background(), chatID, userID)
+	if dbError != nil {
+		log.Println("Failed to retrieve learning mode: %w", dbError)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"chat_id": chatID, "enabled": enabled})
+
+}
+
+func PutLearningModeHandler(c *gin.Context) {
+
+	// access database instance
+	db := database.GetPostgresConn()
+
+	userID := c.MustGet("userID").(string)
+	chatID := c.Param("chatID")
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		log.Println("Missing requests body")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	var bodyMap map[string]interface{}
+	err = json.Unmarshal(body, &bodyMap)
+	if err != nil {
+		log.Println("Failed to unmarshal body to map[string]interface: %w", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	enabled, ok := bodyMap["enabled"].(bool)
+	if !ok {
+		log.Println("Missing or invalid enabled in body")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	// learning mode glosses messages in a target language, it needs at least one
+	if enabled {
+		languages, dbError := db.GetUserLanguages(context.Background(), userID)
+		if dbError != nil {
+			log.Println("Failed to retrieve user languages: %w", dbError)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+		if languages.Native == "" || len(languages.Targets) == 0 {
+			log.Println("Learning mode requires a native and at least one target language")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Set a native and target language first"})
+			return
+		}
+	}
+
+	dbError := db.SetLearningMode(context.Background(), chatID, userID, enabled)
+	if dbError != nil {
+		log.Println("Failed to update learning mode: %w", dbError)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"chat_id": chatID, "enabled": enabled})
+
+}
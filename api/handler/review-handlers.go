@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/database"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/srs"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	defaultDueCards = 20
+	maxDueCards     = 100
+)
+
+func GetDueCardsHandler(c *gin.Context) {
+
+	// access database instance
+	db := database.GetPostgresConn()
+
+	userID := c.MustGet("userID").(string)
+	langCode := c.Query("lang")
+	if len(langCode) != 0 {
+		var ok bool
+		langCode, ok = normalizeLangCode(c, langCode)
+		if !ok {
+			return
+		}
+	}
+
+	limit := defaultDueCards
+	if limitStr := c.Query("limit"); len(limitStr) != 0 {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > maxDueCards {
+			log.Println("Invalid limit query parameter")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+	}
+
+	dueCards, dbError := db.GetDueCards(context.Background(), userID, langCode, time.Now().UTC(), limit)
+	if dbError != nil {
+		log.Println("Failed to retrieve due cards: %w", dbError)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, dueCards)
+
+}
+
+func PostReviewHandler(c *gin.Context) {
+
+	userID := c.MustGet("userID").(string)
+	itemID := c.Param("itemID")
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		log.Println("Missing requests body")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	var bodyMap map[string]interface{}
+	err = json.Unmarshal(body, &bodyMap)
+	if err != nil {
+		log.Println("Failed to unmarshal body to map[string]interface: %w", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	grade, ok := bodyMap["grade"].(float64)
+	if !ok || grade != float64(int(grade)) {
+		log.Println("Missing or invalid grade in body")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	dueCard, err := srs.Review(context.Background(), userID, itemID, int(grade))
+	if err != nil {
+		switch {
+		case errors.Is(err, srs.ErrInvalidGrade):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Grade must be between 0 and 5"})
+		case errors.Is(err, pgx.ErrNoRows):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Vocabulary item not found"})
+		default:
+			log.Println("Failed to review card: %w", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, dueCard)
+
+}
+
+func GetReviewStatsHandler(c *gin.Context) {
+
+	// access database instance
+	db := database.GetPostgresConn()
+
+	userID := c.MustGet("userID").(string)
+	langCode := c.Query("lang")
+	if len(langCode) != 0 {
+		var ok bool
+		langCode, ok = normalizeLangCode(c, langCode)
+		if !ok {
+			return
+		}
+	}
+
+	// reviews today are counted from midnight in the user's timezone
+	location := time.UTC
+	if tz := c.Query("tz"); len(tz) != 0 {
+		var err error
+		location, err = time.LoadLocation(tz)
+		if err != nil {
+			log.Printf("Invalid tz query parameter %s", tz)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+	}
+
+	now := time.Now().In(location)
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location)
+
+	stats, dbError := db.GetReviewStats(context.Background(), userID, langCode, now.UTC(), dayStart.UTC())
+	if dbError != nil {
+		log.Println("Failed to retrieve review stats: %w", dbError)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+
+}
@@ -0,0 +1,242 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/database"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/translation"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/websockets"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// defaultFeedbackDays is the window of translation feedback stats without ?days
+const defaultFeedbackDays = 30
+
+func GetTranslationCacheStatsHandler(c *gin.Context) {
+
+	c.JSON(http.StatusOK, translation.CacheStats())
+
+}
+
+// DeleteTranslationCacheHandler drops the cached translations of ?provider, or of every provider without it
+func DeleteTranslationCacheHandler(c *gin.Context) {
+
+	provider := c.Query("provider")
+
+	err := translation.InvalidateCache(context.Background(), provider, "")
+	if err != nil {
+		log.Println("Failed to invalidate translation cache: %w", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"provider": provider})
+
+}
+
+func PostRetranslateHandler(c *gin.Context) {
+
+	userID := c.MustGet("userID").(string)
+	chatID := c.Param("chatID")
+	messageID, ok := messageIDParam(c)
+	if !ok {
+		return
+	}
+
+	bodyMap, ok := bindOptionalBody(c)
+	if !ok {
+		return
+	}
+
+	provider, _ := bodyMap["provider"].(string)
+	langCode, ok := requestLangCode(c, bodyMap, userID)
+	if !ok {
+		return
+	}
+
+	retranslated, err := websockets.RetranslateMessage(context.Background(), websockets.GetHub(), chatID, messageID, langCode, provider)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		case errors.Is(err, database.ErrMessageDeleted):
+			c.JSON(http.StatusGone, gin.H{"error": "Message deleted"})
+		case errors.Is(err, translation.ErrUnknownProvider), errors.Is(err, translation.ErrSameLanguage):
+			log.Println("Invalid retranslation request: %w", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		case errors.Is(err, translation.ErrNoAlternativeProvider):
+			c.JSON(http.StatusConflict, gin.H{"error": "No other translation provider available"})
+		default:
+			log.Println("Failed to retranslate message: %w", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, retranslated)
+
+}
+
+func PutTranslationFeedbackHandler(c *gin.Context) {
+
+	// access database instance
+	db := database.GetPostgresConn()
+
+	userID := c.MustGet("userID").(string)
+	chatID := c.Param("chatID")
+	messageID, ok := messageIDParam(c)
+	if !ok {
+		return
+	}
+
+	bodyMap, ok := bindOptionalBody(c)
+	if !ok {
+		return
+	}
+
+	var rating int
+	switch bodyMap["rating"] {
+	case "up":
+		rating = models.FeedbackUp
+	case "down":
+		rating = models.FeedbackDown
+	default:
+		log.Println("Missing or invalid rating in body")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	langCode, ok := requestLangCode(c, bodyMap, userID)
+	if !ok {
+		return
+	}
+
+	feedback, dbError := db.SaveTranslationFeedback(context.Background(), chatID, messageID, langCode, userID, rating)
+	if dbError != nil {
+		if dbError == pgx.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Translation not found"})
+			return
+		}
+		log.Println("Failed to save translation feedback: %w", dbError)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, feedback)
+
+}
+
+func DeleteTranslationFeedbackHandler(c *gin.Context) {
+
+	// access database instance
+	db := database.GetPostgresConn()
+
+	userID := c.MustGet("userID").(string)
+	chatID := c.Param("chatID")
+	messageID, ok := messageIDParam(c)
+	if !ok {
+		return
+	}
+
+	langCode, ok := requestLangCode(c, map[string]interface{}{"lang_code": c.Query("lang")}, userID)
+	if !ok {
+		return
+	}
+
+	dbError := db.DeleteTranslationFeedback(context.Background(), chatID, messageID, langCode, userID)
+	if dbError != nil {
+		if dbError == pgx.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Feedback not found"})
+			return
+		}
+		log.Println("Failed to delete translation feedback: %w", dbError)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message_id": messageID, "lang_code": langCode, "deleted": true})
+
+}
+
+func GetTranslationFeedbackStatsHandler(c *gin.Context) {
+
+	// access database instance
+	db := database.GetPostgresConn()
+
+	days := defaultFeedbackDays
+	if daysStr := c.Query("days"); daysStr != "" {
+		var err error
+		days, err = strconv.Atoi(daysStr)
+		if err != nil || days < 1 {
+			log.Println("Invalid days query parameter")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+	}
+
+	since := time.Now().UTC().AddDate(0, 0, -days)
+
+	stats, dbError := db.GetTranslationFeedbackStats(context.Background(), since)
+	if dbError != nil {
+		log.Println("Failed to retrieve translation feedback stats: %w", dbError)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"since": since, "stats": stats})
+
+}
+
+// bindOptionalBody reads a JSON object body, an empty body is an empty object
+func bindOptionalBody(c *gin.Context) (map[string]interface{}, bool) {
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		log.Println("Missing requests body")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return nil, false
+	}
+
+	bodyMap := map[string]interface{}{}
+	if len(body) == 0 {
+		return bodyMap, true
+	}
+
+	err = json.Unmarshal(body, &bodyMap)
+	if err != nil {
+		log.Println("Failed to unmarshal body to map[string]interface: %w", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return nil, false
+	}
+
+	return bodyMap, true
+}
+
+// requestLangCode returns lang_code of bodyMap, the language of userID when it is missing
+func requestLangCode(c *gin.Context, bodyMap map[string]interface{}, userID string) (string, bool) {
+
+	if langCode, ok := bodyMap["lang_code"].(string); ok && langCode != "" {
+		return normalizeLangCode(c, langCode)
+	}
+
+	// access database instance
+	db := database.GetPostgresConn()
+
+	langCode, dbError := db.GetUserLangCode(context.Background(), userID)
+	if dbError != nil {
+		log.Println("Failed to retrieve user lang code: %w", dbError)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return "", false
+	}
+
+	return translation.BareLangCode(langCode), true
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/JohnSalinas123/linguachat-backend-go/internal/clerk"
 	"github.com/JohnSalinas123/linguachat-backend-go/internal/database"
 	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/stats"
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5"
 )
@@ -205,6 +206,11 @@ func SetUserLanguageHandler(c *gin.Context) {
 		return
 	}
 
+	userLangCode, ok = normalizeLangCode(c, userLangCode)
+	if !ok {
+		return
+	}
+
 	// start database transaction for updating user lang_code in database and clerk metadata
 	tx, err := db.Pool().BeginTx(context.Background(), pgx.TxOptions{})
 	if err != nil {
@@ -238,4 +244,246 @@ func SetUserLanguageHandler(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"lang_code" : newUserLangCode})
 
-}
\ No newline at end of file
+}
+
+// maxTargetLanguages bounds how many languages a user can learn at once
+const maxTargetLanguages = 10
+
+func GetUserLanguagesHandler(c *gin.Context) {
+
+	// access database instance
+	db := database.GetPostgresConn()
+
+	userID := c.MustGet("userID").(string)
+
+	languages, dbError := db.GetUserLanguages(context.Background(), userID)
+	if dbError != nil {
+		log.Println("Failed to retrieve user languages: %w", dbError)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, languages)
+
+}
+
+func PutUserLanguagesHandler(c *gin.Context) {
+
+	// access database instance
+	db := database.GetPostgresConn()
+
+	userID := c.MustGet("userID").(string)
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		log.Println("Missing requests body")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	var languages models.UserLanguagesResponse
+	err = json.Unmarshal(body, &languages)
+	if err != nil {
+		log.Println("Failed to unmarshal body to UserLanguagesResponse: %w", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	if languages.Native == "" {
+		log.Println("Missing native language in body")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	native, ok := normalizeLangCode(c, languages.Native)
+	if !ok {
+		return
+	}
+	languages.Native = native
+
+	if len(languages.Targets) > maxTargetLanguages {
+		log.Println("Too many target languages in body")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	// every language appears once, targets need a CEFR level
+	seenLangCodes := map[string]bool{languages.Native: true}
+	for i, target := range languages.Targets {
+		target.LangCode, ok = normalizeLangCode(c, target.LangCode)
+		if !ok {
+			return
+		}
+		languages.Targets[i].LangCode = target.LangCode
+
+		if seenLangCodes[target.LangCode] || !target.Level.Valid() {
+			log.Printf("Invalid target language %s with level %s", target.LangCode, target.Level)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+		seenLangCodes[target.LangCode] = true
+	}
+
+	if languages.Targets == nil {
+		languages.Targets = []models.TargetLanguage{}
+	}
+
+	// start database transaction for updating user languages in database and clerk metadata
+	tx, err := db.Pool().BeginTx(context.Background(), pgx.TxOptions{})
+	if err != nil {
+		log.Println("Failed to begin user languages transaction: %w", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	dbError := db.ReplaceUserLanguages(context.Background(), tx, userID, languages)
+	if dbError != nil {
+		log.Println("Failed to update user languages: %w", dbError)
+		tx.Rollback(context.Background())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to update user languages"})
+		return
+	}
+
+	// update clerk publicMetadata, lang_code stays the native language for existing clients
+	err = clerk.UpdateUserPublicFields(map[string]interface{}{
+		"lang_code": languages.Native,
+		"languages": languages,
+	}, userID)
+	if err != nil {
+		log.Println("Failed to update user public metadata")
+		tx.Rollback(context.Background())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update public metadata"})
+		return
+	}
+
+	err = tx.Commit(context.Background())
+	if err != nil {
+		log.Println("Failed to commit updating user languages transaction: %w", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, languages)
+
+}
+
+func GetUserRomanizationHandler(c *gin.Context) {
+
+	// access database instance
+	db := database.GetPostgresConn()
+
+	userID := c.MustGet("userID").(string)
+
+	enabled, dbError := db.GetUserRomanization(context.Background(), userID)
+	if dbError != nil {
+		log.Println("Failed to retrieve romanization setting: %w", dbError)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"enabled": enabled})
+
+}
+
+// PutUserRomanizationHandler turns romanized messages on or off, open chat connections
+// pick up the change when they reconnect
+func PutUserRomanizationHandler(c *gin.Context) {
+
+	// access database instance
+	db := database.GetPostgresConn()
+
+	userID := c.MustGet("userID").(string)
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		log.Println("Missing requests body")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	var bodyMap map[string]interface{}
+	err = json.Unmarshal(body, &bodyMap)
+	if err != nil {
+		log.Println("Failed to unmarshal body to map[string]interface: %w", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	enabled, ok := bodyMap["enabled"].(bool)
+	if !ok {
+		log.Println("Missing or invalid enabled in body")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	dbError := db.SetUserRomanization(context.Background(), userID, enabled)
+	if dbError != nil {
+		log.Println("Failed to update romanization setting: %w", dbError)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"enabled": enabled})
+
+}
+
+func GetUserStatsHandler(c *gin.Context) {
+
+	userID := c.MustGet("userID").(string)
+
+	// days are counted in the timezone set with PUT /user/timezone
+	userStats, err := stats.Get(context.Background(), userID)
+	if err != nil {
+		log.Println("Failed to retrieve user stats: %w", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, userStats)
+
+}
+
+func PutUserTimezoneHandler(c *gin.Context) {
+
+	userID := c.MustGet("userID").(string)
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		log.Println("Missing requests body")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	var bodyMap map[string]interface{}
+	err = json.Unmarshal(body, &bodyMap)
+	if err != nil {
+		log.Println("Failed to unmarshal body to map[string]interface: %w", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	timezone, ok := bodyMap["timezone"].(string)
+	if !ok || len(timezone) == 0 {
+		log.Println("Missing or invalid timezone in body")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		log.Printf("Invalid timezone %s", timezone)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	// stats counted in another timezone are counted again on the next read
+	err = stats.SetTimezone(context.Background(), userID, location)
+	if err != nil {
+		log.Println("Failed to update stats timezone: %w", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"timezone": location.String()})
+
+}
@@ -0,0 +1,229 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/database"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/export"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/vocabulary"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+func GetVocabularyHandler(c *gin.Context) {
+
+	// access database instance
+	db := database.GetPostgresConn()
+
+	userID := c.MustGet("userID").(string)
+	langCode := c.Query("lang")
+	if len(langCode) != 0 {
+		var ok bool
+		langCode, ok = normalizeLangCode(c, langCode)
+		if !ok {
+			return
+		}
+	}
+	tag := c.Query("tag")
+
+	items, dbError := db.GetVocabulary(context.Background(), userID, langCode, tag)
+	if dbError != nil {
+		log.Println("Failed to retrieve vocabulary: %w", dbError)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, items)
+
+}
+
+func PostVocabularyHandler(c *gin.Context) {
+
+	userID := c.MustGet("userID").(string)
+
+	req, ok := bindVocabularyRequest(c)
+	if !ok {
+		return
+	}
+
+	item, err := vocabulary.Save(context.Background(), userID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, vocabulary.ErrInvalidItem):
+			log.Println("Invalid vocabulary item: %w", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		case errors.Is(err, vocabulary.ErrMessageNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		case errors.Is(err, database.ErrVocabularyExists):
+			c.JSON(http.StatusConflict, gin.H{"error": "Term already saved"})
+		default:
+			log.Println("Failed to save vocabulary item: %w", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, item)
+
+}
+
+func PutVocabularyHandler(c *gin.Context) {
+
+	userID := c.MustGet("userID").(string)
+	itemID := c.Param("itemID")
+
+	req, ok := bindVocabularyRequest(c)
+	if !ok {
+		return
+	}
+
+	item, err := vocabulary.Update(context.Background(), userID, itemID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, vocabulary.ErrInvalidItem):
+			log.Println("Invalid vocabulary item: %w", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		case errors.Is(err, pgx.ErrNoRows):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Vocabulary item not found"})
+		default:
+			log.Println("Failed to update vocabulary item: %w", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, item)
+
+}
+
+func DeleteVocabularyHandler(c *gin.Context) {
+
+	// access database instance
+	db := database.GetPostgresConn()
+
+	userID := c.MustGet("userID").(string)
+	itemID := c.Param("itemID")
+
+	dbError := db.DeleteVocabularyItem(context.Background(), userID, itemID)
+	if dbError != nil {
+		if dbError == pgx.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Vocabulary item not found"})
+			return
+		}
+		log.Println("Failed to delete vocabulary item: %w", dbError)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deleted": itemID})
+
+}
+
+func GetVocabularyExportHandler(c *gin.Context) {
+
+	// access database instance
+	db := database.GetPostgresConn()
+
+	userID := c.MustGet("userID").(string)
+	langCode := c.Query("lang")
+	if len(langCode) == 0 {
+		log.Println("Lang query parameter missing")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	langCode, ok := normalizeLangCode(c, langCode)
+	if !ok {
+		return
+	}
+
+	format := c.DefaultQuery("format", export.FormatJSON)
+	if format != export.FormatJSON && format != export.FormatCSV && format != export.FormatTSV && format != export.FormatAnki {
+		log.Printf("Unknown export format %s", format)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	items, dbError := db.GetVocabulary(context.Background(), userID, langCode, "")
+	if dbError != nil {
+		log.Println("Failed to retrieve vocabulary: %w", dbError)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	filename := fmt.Sprintf("vocabulary-%s.%s", langCode, format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+
+	if format == export.FormatJSON {
+		c.JSON(http.StatusOK, models.VocabularyExport{
+			LangCode:   langCode,
+			ExportedAt: time.Now().UTC(),
+			Items:      items,
+		})
+		return
+	}
+
+	// decks also carry the corrections the user accepted on their own messages
+	corrections, dbError := db.GetAcceptedCorrections(context.Background(), userID, langCode)
+	if dbError != nil {
+		log.Println("Failed to retrieve accepted corrections: %w", dbError)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	cards := export.BuildCards(items, corrections)
+
+	var buf bytes.Buffer
+	var contentType string
+	var err error
+	switch format {
+	case export.FormatCSV:
+		contentType = "text/csv; charset=utf-8"
+		err = export.WriteDelimited(&buf, cards, ',')
+	case export.FormatTSV:
+		contentType = "text/tab-separated-values; charset=utf-8"
+		err = export.WriteDelimited(&buf, cards, '\t')
+	case export.FormatAnki:
+		contentType = "application/octet-stream"
+		err = export.WriteAnkiPackage(&buf, "LinguaChat::"+langCode, cards)
+	}
+	if err != nil {
+		log.Println("Failed to write vocabulary export: %w", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+
+}
+
+// bindVocabularyRequest reads the vocabulary item of the request body,
+// responding with 400 and returning false when it is missing or malformed
+func bindVocabularyRequest(c *gin.Context) (models.VocabularyRequest, bool) {
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		log.Println("Missing requests body")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return models.VocabularyRequest{}, false
+	}
+
+	var req models.VocabularyRequest
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		log.Println("Failed to unmarshal body to VocabularyRequest: %w", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return models.VocabularyRequest{}, false
+	}
+
+	return req, true
+}
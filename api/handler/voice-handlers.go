@@ -0,0 +1,258 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/blobstore"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/database"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/permissions"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/translation"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/voice"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/websockets"
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// maxVoiceMessageSize bounds an uploaded recording, several minutes of Opus speech
+	maxVoiceMessageSize = 2 << 20
+
+	// maxVoiceDuration bounds the duration clients may report for a recording
+	maxVoiceDuration = 5 * 60 * 1000
+
+	// maxSpeechLength bounds the characters of a message read out loud
+	maxSpeechLength = 2000
+)
+
+// PostVoiceMessageHandler creates a voice message from a multipart upload with the recording
+// in "audio" and optional "lang_code", "reply_to_id" and "duration_ms" fields
+func PostVoiceMessageHandler(c *gin.Context) {
+
+	userID := c.MustGet("userID").(string)
+
+	chatID, err := uuid.FromString(c.Param("chatID"))
+	if err != nil {
+		log.Println("Invalid chatID path parameter")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	// leave room for the other form fields next to the recording
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxVoiceMessageSize+64<<10)
+
+	file, _, err := c.Request.FormFile("audio")
+	if err != nil {
+		log.Println("Missing or oversized audio in form: %w", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxVoiceMessageSize+1))
+	if err != nil {
+		log.Println("Failed to read audio: %w", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	if len(data) == 0 || len(data) > maxVoiceMessageSize {
+		log.Printf("Voice message of %d bytes rejected", len(data))
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Recording too large"})
+		return
+	}
+
+	// a missing lang_code is left to the transcriber
+	var langCode string
+	if langCodeStr := c.Request.FormValue("lang_code"); langCodeStr != "" {
+		bareLangCode, ok := normalizeLangCode(c, langCodeStr)
+		if !ok {
+			return
+		}
+		langCode = translation.StoredLangCode(bareLangCode)
+	}
+
+	var replyToID *uuid.UUID
+	if replyToIDStr := c.Request.FormValue("reply_to_id"); replyToIDStr != "" {
+		id, err := uuid.FromString(replyToIDStr)
+		if err != nil {
+			log.Printf("Invalid reply_to_id %s", replyToIDStr)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+		replyToID = &id
+	}
+
+	var durationMs *int
+	if durationStr := c.Request.FormValue("duration_ms"); durationStr != "" {
+		duration, err := strconv.Atoi(durationStr)
+		if err != nil || duration <= 0 || duration > maxVoiceDuration {
+			log.Printf("Invalid duration_ms %s", durationStr)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+		durationMs = &duration
+	}
+
+	newMessage, err := websockets.CreateVoiceMessage(context.Background(), websockets.GetHub(), chatID, userID, langCode, replyToID, data, durationMs)
+	if err != nil {
+		switch {
+		case errors.Is(err, voice.ErrUnsupportedAudio):
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Recording must be Opus audio in WebM or Ogg"})
+		case errors.Is(err, pgx.ErrNoRows):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Replied message not found"})
+		default:
+			log.Println("Failed to create voice message: %w", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, newMessage)
+
+}
+
+// GetVoiceRecordingHandler streams the recording of a voice message, range requests
+// are supported so players can seek
+func GetVoiceRecordingHandler(c *gin.Context) {
+
+	// access database instance
+	db := database.GetPostgresConn()
+
+	chatID := c.Param("chatID")
+	messageID, ok := messageIDParam(c)
+	if !ok {
+		return
+	}
+
+	recording, dbError := db.GetVoiceAttachment(context.Background(), chatID, messageID)
+	if dbError != nil {
+		switch {
+		case errors.Is(dbError, pgx.ErrNoRows):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Voice message not found"})
+		case errors.Is(dbError, database.ErrMessageDeleted):
+			c.JSON(http.StatusGone, gin.H{"error": "Message deleted"})
+		default:
+			log.Println("Failed to retrieve voice message: %w", dbError)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		}
+		return
+	}
+
+	audio, info, err := voice.OpenRecording(c.Request.Context(), recording)
+	if err != nil {
+		if errors.Is(err, blobstore.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Voice message not found"})
+			return
+		}
+		log.Println("Failed to open recording: %w", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	defer audio.Close()
+
+	// recordings never change, the key is unique per upload
+	c.Header("Content-Type", recording.ContentType)
+	c.Header("Cache-Control", "private, max-age=31536000, immutable")
+	http.ServeContent(c.Writer, c.Request, "", info.ModTime, audio)
+
+}
+
+// GetMessageAudioHandler reads a message out loud, in its original language or in
+// the translation into the ?lang query, audio is synthesized once per text and cached
+func GetMessageAudioHandler(c *gin.Context) {
+
+	// access database instance
+	db := database.GetPostgresConn()
+
+	userID := c.MustGet("userID").(string)
+	messageID, ok := messageIDParam(c)
+	if !ok {
+		return
+	}
+
+	msg, dbError := db.GetMessage(context.Background(), messageID)
+	if dbError != nil {
+		if dbError == pgx.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+			return
+		}
+		log.Println("Failed to retrieve message: %w", dbError)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// the route carries no chat, so membership of the message's chat is checked here
+	_, err := permissions.Authorize(context.Background(), msg.ChatID.String(), userID, permissions.ActionViewChat)
+	if err != nil {
+		if errors.Is(err, permissions.ErrNotParticipant) || errors.Is(err, permissions.ErrForbidden) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+			return
+		}
+		log.Println("Failed to authorize message audio: %w", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	if msg.DeletedAt != nil {
+		c.JSON(http.StatusGone, gin.H{"error": "Message deleted"})
+		return
+	}
+
+	// the original text unless another language is asked for
+	text := msg.Content
+	langCode := translation.BareLangCode(msg.LangCode)
+	if targetLang := c.Query("lang"); len(targetLang) != 0 {
+		targetLang, ok := normalizeLangCode(c, targetLang)
+		if !ok {
+			return
+		}
+		if targetLang != langCode {
+			translated, dbError := db.GetTranslation(context.Background(), messageID, targetLang)
+			if dbError != nil {
+				if dbError == pgx.ErrNoRows {
+					c.JSON(http.StatusNotFound, gin.H{"error": "Translation not found"})
+					return
+				}
+				log.Println("Failed to retrieve translation: %w", dbError)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+				return
+			}
+			text = translated.Content
+			langCode = targetLang
+		}
+	}
+
+	// voice messages have no text until transcribed
+	if len(text) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Message has no text"})
+		return
+	}
+	if utf8.RuneCountInString(text) > maxSpeechLength {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Message too long to read out"})
+		return
+	}
+
+	audio, info, contentType, err := voice.Speak(c.Request.Context(), text, langCode)
+	if err != nil {
+		if errors.Is(err, voice.ErrNoSynthesizer) || errors.Is(err, voice.ErrNoStore) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Speech unavailable"})
+			return
+		}
+		log.Println("Failed to synthesize message audio: %w", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	defer audio.Close()
+
+	// edits change the text and with it the audio, so clients revalidate instead of caching for good
+	c.Header("Content-Type", contentType)
+	c.Header("Cache-Control", "private, no-cache")
+	http.ServeContent(c.Writer, c.Request, "", info.ModTime, audio)
+
+}
@@ -5,10 +5,23 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/JohnSalinas123/linguachat-backend-go/api/handler"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/blobstore"
 	"github.com/JohnSalinas123/linguachat-backend-go/internal/clerk"
 	"github.com/JohnSalinas123/linguachat-backend-go/internal/database"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/jobs"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/langdetect"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/learning"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/learning/dictionary"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/learning/lexicon"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/permissions"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/ratelimit"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/translation"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/transliteration"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/voice"
 	"github.com/JohnSalinas123/linguachat-backend-go/internal/websockets"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -37,6 +50,9 @@ func main() {
 		log.Fatalf("Failed to connect to the database: %v", err)
 	}
 
+	// users allowed on operator endpoints, a comma separated list of user ids
+	permissions.SetAdmins(strings.Split(os.Getenv("ADMIN_USER_IDS"), ","))
+
 	// clerk setup
 	clerkSecret := os.Getenv("CLERK_SECRET")
 	clerkWHSecret := os.Getenv("CLERK_WH_SECRET")
@@ -44,33 +60,161 @@ func main() {
 		log.Fatalf("Failed to complete clerk setup: %v", err)
 	}
 
+	// word difficulty lexicon for learning mode
+	lex, err := lexicon.NewEmbeddedLexicon()
+	if err != nil {
+		log.Fatalf("Failed to load lexicon: %v", err)
+	}
+	learning.SetLexicon(lex)
+
+	// offline dictionary for word glosses
+	dict, err := dictionary.NewOfflineDictionary()
+	if err != nil {
+		log.Fatalf("Failed to load dictionary: %v", err)
+	}
+	learning.SetDictionary(dict)
+
+	// translation providers routed by language pair, from a config file or the environment
+	translationRouter, err := translation.LoadRouter(os.Getenv("TRANSLATION_CONFIG"))
+	if err != nil {
+		log.Fatalf("Failed to configure translation providers: %v", err)
+	}
+	translation.SetRouter(translationRouter)
+
+	// language detection of incoming messages
+	detector, err := langdetect.NewNGramDetector()
+	if err != nil {
+		log.Fatalf("Failed to load language profiles: %v", err)
+	}
+	translation.SetDetector(detector)
+
+	// romanization of messages written in a non-Latin script, by bundled tables and
+	// a romanization server for kanji and rare Han characters when one is configured
+	transliterator, err := transliteration.TransliteratorFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure transliteration: %v", err)
+	}
+	transliteration.SetTransliterator(transliterator)
+
+	// recordings of voice messages, kept on local disk
+	blobDir := os.Getenv("BLOB_STORE_DIR")
+	if blobDir == "" {
+		blobDir = "data/blobs"
+	}
+	blobs, err := blobstore.NewFileStore(blobDir)
+	if err != nil {
+		log.Fatalf("Failed to open blob store: %v", err)
+	}
+	voice.SetStore(blobs)
+
+	// speech-to-text of voice messages, they stay without text when none is configured
+	transcriber, err := voice.TranscriberFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure transcriber: %v", err)
+	}
+	if transcriber != nil {
+		voice.SetTranscriber(transcriber)
+	}
+
+	// text-to-speech of messages, message audio is unavailable when none is configured
+	synthesizer, err := voice.SynthesizerFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure synthesizer: %v", err)
+	}
+	if synthesizer != nil {
+		voice.SetSynthesizer(synthesizer)
+	}
+
 	router := gin.Default()
 	
 	config := cors.DefaultConfig()
     config.AllowOrigins = []string{"http://localhost:5173"}
-    config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
+    config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
     config.AllowHeaders = []string{"Authorization", "Content-Type"}
 	config.AllowCredentials = true
 
 	router.Use(cors.New(config))
 
+	// retranslating calls a paid provider and replaces the translation for the whole chat
+	retranslateLimiter := ratelimit.New(30, time.Hour)
+
 	// authorized endpoints
 	authorized := router.Group("/api")
 	authorized.Use(clerk.ClerkAuthMiddleware()) 
 	{
+		authorized.GET("/languages", handler.GetLanguagesHandler)
 		authorized.POST("/user/language", handler.SetUserLanguageHandler)
+		authorized.GET("/user/languages", handler.GetUserLanguagesHandler)
+		authorized.PUT("/user/languages", handler.PutUserLanguagesHandler)
+		authorized.GET("/user/romanization", handler.GetUserRomanizationHandler)
+		authorized.PUT("/user/romanization", handler.PutUserRomanizationHandler)
+		authorized.PUT("/user/timezone", handler.PutUserTimezoneHandler)
+		authorized.GET("/me/stats", handler.GetUserStatsHandler)
+
+		authorized.GET("/translation/cache/stats", permissions.RequireAdmin(), handler.GetTranslationCacheStatsHandler)
+		authorized.DELETE("/translation/cache", permissions.RequireAdmin(), handler.DeleteTranslationCacheHandler)
+		authorized.GET("/translation/feedback/stats", permissions.RequireAdmin(), handler.GetTranslationFeedbackStatsHandler)
+		authorized.GET("/jobs/dead", permissions.RequireAdmin(), handler.GetDeadJobsHandler)
 		authorized.POST("/chats/invites", handler.PostNewInviteHandler)
 		authorized.POST("/chats", handler.PostAcceptChatInviteHandler)
 
 		authorized.GET("/chats", handler.GetChatsHandler)
-		authorized.GET("/chats/:chatID/messages", handler.GetChatMessagesHandler)
+		authorized.GET("/chats/:chatID/messages", permissions.RequireChatPermission(permissions.ActionViewChat), handler.GetChatMessagesHandler)
 		authorized.GET("/chats/invites/:inviteCode", handler.GetInviteExistsHandler)
+
+		// chat participants and roles
+		authorized.PATCH("/chats/:chatID", permissions.RequireChatPermission(permissions.ActionRenameChat), handler.PatchChatHandler)
+
+		authorized.GET("/chats/:chatID/learning-mode", permissions.RequireChatPermission(permissions.ActionViewChat), handler.GetLearningModeHandler)
+		authorized.PUT("/chats/:chatID/learning-mode", permissions.RequireChatPermission(permissions.ActionViewChat), handler.PutLearningModeHandler)
+
+		authorized.GET("/chats/:chatID/glossary", permissions.RequireChatPermission(permissions.ActionViewChat), handler.GetGlossaryHandler)
+		authorized.POST("/chats/:chatID/glossary", permissions.RequireChatPermission(permissions.ActionEditGlossary), handler.PostGlossaryTermHandler)
+		authorized.PUT("/chats/:chatID/glossary/:termID", permissions.RequireChatPermission(permissions.ActionEditGlossary), handler.PutGlossaryTermHandler)
+		authorized.DELETE("/chats/:chatID/glossary/:termID", permissions.RequireChatPermission(permissions.ActionEditGlossary), handler.DeleteGlossaryTermHandler)
+
+		authorized.GET("/chats/:chatID/participants", permissions.RequireChatPermission(permissions.ActionViewChat), handler.GetChatParticipantsHandler)
+		authorized.PUT("/chats/:chatID/participants/:userID/role", permissions.RequireChatPermission(permissions.ActionChangeRole), handler.PutParticipantRoleHandler)
+		authorized.DELETE("/chats/:chatID/participants/:userID", permissions.RequireChatPermission(permissions.ActionViewChat), handler.DeleteParticipantHandler)
+
+		authorized.POST("/chats/:chatID/voice-messages", permissions.RequireChatPermission(permissions.ActionSendMessage), handler.PostVoiceMessageHandler)
+		authorized.GET("/chats/:chatID/messages/:messageID/voice", permissions.RequireChatPermission(permissions.ActionViewChat), handler.GetVoiceRecordingHandler)
+		authorized.PUT("/chats/:chatID/messages/:messageID", permissions.RequireChatPermission(permissions.ActionSendMessage), handler.PutMessageHandler)
+		authorized.DELETE("/chats/:chatID/messages/:messageID", permissions.RequireChatPermission(permissions.ActionViewChat), handler.DeleteMessageHandler)
+		authorized.GET("/chats/:chatID/messages/:messageID/edits", permissions.RequireChatPermission(permissions.ActionViewChat), handler.GetMessageEditsHandler)
+		authorized.GET("/chats/:chatID/messages/:messageID/thread", permissions.RequireChatPermission(permissions.ActionViewChat), handler.GetMessageThreadHandler)
+		authorized.POST("/chats/:chatID/messages/:messageID/reactions", permissions.RequireChatPermission(permissions.ActionReact), handler.PostReactionHandler)
+		authorized.POST("/chats/:chatID/messages/:messageID/corrections", permissions.RequireChatPermission(permissions.ActionCorrectMessage), handler.PostCorrectionHandler)
+		authorized.POST("/chats/:chatID/messages/:messageID/corrections/:correctionID/accept", permissions.RequireChatPermission(permissions.ActionViewChat), handler.PostCorrectionAcceptHandler)
+
+		authorized.POST("/chats/:chatID/messages/:messageID/retranslate", permissions.RequireChatPermission(permissions.ActionSendMessage), ratelimit.PerUser(retranslateLimiter), handler.PostRetranslateHandler)
+		authorized.PUT("/chats/:chatID/messages/:messageID/translation-feedback", permissions.RequireChatPermission(permissions.ActionViewChat), handler.PutTranslationFeedbackHandler)
+		authorized.DELETE("/chats/:chatID/messages/:messageID/translation-feedback", permissions.RequireChatPermission(permissions.ActionViewChat), handler.DeleteTranslationFeedbackHandler)
+
+		authorized.POST("/chats/:chatID/messages/:messageID/pin", permissions.RequireChatPermission(permissions.ActionPinMessage), handler.PostMessagePinHandler)
+		authorized.DELETE("/chats/:chatID/messages/:messageID/pin", permissions.RequireChatPermission(permissions.ActionPinMessage), handler.DeleteMessagePinHandler)
+
+		authorized.GET("/messages/:messageID/gloss", handler.GetMessageGlossHandler)
+		authorized.GET("/messages/:messageID/audio", handler.GetMessageAudioHandler)
+
+		authorized.GET("/vocabulary", handler.GetVocabularyHandler)
+		authorized.POST("/vocabulary", handler.PostVocabularyHandler)
+		authorized.GET("/vocabulary/export", handler.GetVocabularyExportHandler)
+		authorized.PUT("/vocabulary/:itemID", handler.PutVocabularyHandler)
+		authorized.DELETE("/vocabulary/:itemID", handler.DeleteVocabularyHandler)
+
+		authorized.GET("/reviews/due", handler.GetDueCardsHandler)
+		authorized.GET("/reviews/stats", handler.GetReviewStatsHandler)
+		authorized.POST("/reviews/:itemID", handler.PostReviewHandler)
 		
 	}
 
-	hub := websockets.NewHub()
+	hub := websockets.GetHub()
 	go hub.Run()
 
+	// background translation workers, they broadcast message.translated through the hub
+	jobs.Start(ctx, hub, 4)
+
 	// clerk webhooks
 	authorizedClerkWebHooks := router.Group("/api/clerk/webhook")
 	authorizedClerkWebHooks.Use(clerk.ClerkWebhookAuthMiddleware())
@@ -0,0 +1,82 @@
+// speech-standin is a local stand-in for a speech-to-text server speaking the OpenAI
+// transcription API (/v1/audio/transcriptions). It does not listen to the recording, every
+// upload is answered with -text in the requested language or -lang, for example
+//
+//	go run ./cmd/speech-standin -addr localhost:5100 -text "hola, ¿qué tal?" -lang es
+//	WHISPER_URL=http://localhost:5100 go run ./cmd/linguachat-backend-go
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+var (
+	addr  = flag.String("addr", "localhost:5100", "address to listen on")
+	delay = flag.Duration("delay", 0, "time to wait before answering each request")
+	text  = flag.String("text", "this is a transcribed voice message", "transcript returned for every recording")
+	lang  = flag.String("lang", "en", "language reported when the request does not name one")
+)
+
+// maxUploadSize bounds the multipart uploads the stand-in accepts
+const maxUploadSize = 8 << 20
+
+func main() {
+
+	flag.Parse()
+
+	http.HandleFunc("/v1/audio/transcriptions", handleTranscription)
+
+	log.Printf("Speech stand-in listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+
+}
+
+func handleTranscription(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	time.Sleep(*delay)
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+		return
+	}
+	defer file.Close()
+
+	size, err := io.Copy(io.Discard, file)
+	if err != nil || size == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+		return
+	}
+
+	language := r.FormValue("language")
+	if language == "" {
+		language = *lang
+	}
+
+	log.Printf("Transcribing %s (%s, %d bytes) in %s", header.Filename, header.Header.Get("Content-Type"), size, language)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"text":     fmt.Sprintf("[%s] %s", language, *text),
+		"language": language,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+// translation-standin is a local stand-in for the HTTP translation providers, it answers
+// both the LibreTranslate (/translate) and DeepL (/v2/translate) protocols by tagging the
+// text with the provider and target language. -delay and -fail-rate exercise the timeouts
+// and fallback of the translation router, for example
+//
+//	go run ./cmd/translation-standin -addr localhost:5000 -fail-rate 0.3
+//	LIBRETRANSLATE_URL=http://localhost:5000 go run ./cmd/linguachat-backend-go
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	addr     = flag.String("addr", "localhost:5000", "address to listen on")
+	delay    = flag.Duration("delay", 0, "time to wait before answering each request")
+	failRate = flag.Float64("fail-rate", 0, "share of requests answered with 503, between 0 and 1")
+)
+
+func main() {
+
+	flag.Parse()
+
+	http.HandleFunc("/translate", standIn(handleLibreTranslate))
+	http.HandleFunc("/v2/translate", standIn(handleDeepL))
+
+	log.Printf("Translation stand-in listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+
+}
+
+// standIn applies the configured delay and failure rate before handler runs
+func standIn(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		time.Sleep(*delay)
+
+		if rand.Float64() < *failRate {
+			log.Printf("Failing %s as configured", r.URL.Path)
+			http.Error(w, "stand-in failure", http.StatusServiceUnavailable)
+			return
+		}
+
+		handler(w, r)
+	}
+}
+
+func handleLibreTranslate(w http.ResponseWriter, r *http.Request) {
+
+	var req struct {
+		Q      string `json:"q"`
+		Source string `json:"source"`
+		Target string `json:"target"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Q == "" || req.Target == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"translatedText": fmt.Sprintf("[libre:%s] %s", req.Target, req.Q),
+	})
+}
+
+func handleDeepL(w http.ResponseWriter, r *http.Request) {
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "DeepL-Auth-Key ") {
+		writeJSON(w, http.StatusForbidden, map[string]string{"message": "Authorization failed"})
+		return
+	}
+
+	var req struct {
+		Text       []string `json:"text"`
+		SourceLang string   `json:"source_lang"`
+		TargetLang string   `json:"target_lang"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Text) == 0 || req.TargetLang == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+		return
+	}
+
+	type translation struct {
+		DetectedSourceLanguage string `json:"detected_source_language"`
+		Text                   string `json:"text"`
+	}
+	translations := make([]translation, len(req.Text))
+	for i, text := range req.Text {
+		translations[i] = translation{
+			DetectedSourceLanguage: req.SourceLang,
+			Text:                   fmt.Sprintf("[deepl:%s] %s", strings.ToLower(req.TargetLang), text),
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"translations": translations})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound   = errors.New("blob not found")
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+// Info describes a stored blob
+type Info struct {
+	Size    int64
+	ModTime time.Time
+}
+
+// Store keeps binary objects such as audio recordings under slash separated keys
+type Store interface {
+	// Put stores the content of r under key, replacing any blob stored under it before
+	Put(ctx context.Context, key string, r io.Reader) (Info, error)
+	// Open returns the blob stored under key, ErrNotFound when there is none
+	Open(ctx context.Context, key string) (io.ReadSeekCloser, Info, error)
+	// Delete removes the blob stored under key, deleting a missing blob is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// validKey reports whether key is made of non-empty segments of letters, digits, '.', '-' and '_'
+// separated by '/', segments of dots only are rejected so keys can not leave the store
+func validKey(key string) bool {
+	if key == "" || len(key) > 512 {
+		return false
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || strings.Trim(segment, ".") == "" {
+			return false
+		}
+		for _, r := range segment {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '_') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// FileStore keeps blobs as files below a root directory
+type FileStore struct {
+	root string
+}
+
+// NewFileStore creates root when it does not exist yet
+func NewFileStore(root string) (*FileStore, error) {
+
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("unable to create blob directory %s: %w", root, err)
+	}
+
+	return &FileStore{root: root}, nil
+}
+
+func (s *FileStore) path(key string) (string, error) {
+	if !validKey(key) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
+
+func (s *FileStore) Put(ctx context.Context, key string, r io.Reader) (Info, error) {
+
+	path, err := s.path(key)
+	if err != nil {
+		return Info{}, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return Info{}, fmt.Errorf("unable to create blob directory: %w", err)
+	}
+
+	// written next to the final path and renamed so readers never see a partial blob
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return Info{}, fmt.Errorf("unable to create blob file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	size, err := io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return Info{}, fmt.Errorf("unable to write blob %s: %w", key, err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return Info{}, fmt.Errorf("unable to store blob %s: %w", key, err)
+	}
+
+	return Info{Size: size, ModTime: time.Now().UTC()}, nil
+}
+
+func (s *FileStore) Open(ctx context.Context, key string) (io.ReadSeekCloser, Info, error) {
+
+	path, err := s.path(key)
+	if err != nil {
+		return nil, Info{}, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, Info{}, ErrNotFound
+		}
+		return nil, Info{}, fmt.Errorf("unable to open blob %s: %w", key, err)
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, Info{}, fmt.Errorf("unable to stat blob %s: %w", key, err)
+	}
+
+	return file, Info{Size: stat.Size(), ModTime: stat.ModTime()}, nil
+}
+
+func (s *FileStore) Delete(ctx context.Context, key string) error {
+
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("unable to delete blob %s: %w", key, err)
+	}
+
+	return nil
+}
@@ -41,13 +41,15 @@ func InitialClerkSetup(clerkSecret string, clerkWHSecret string) error {
 
 
 // updateUserPublicData updates a user's public data
-func UpdateUserPublicData(fieldName string, fieldValue string, userID string) error {
+func UpdateUserPublicData(fieldName string, fieldValue interface{}, userID string) error {
+	return UpdateUserPublicFields(map[string]interface{}{fieldName: fieldValue}, userID)
+}
 
-	UserLangCode := map[string]interface{}{
-		fieldName : fieldValue,
-	}
+// UpdateUserPublicFields updates several fields of a user's public data at once,
+// fields not included are left as they are
+func UpdateUserPublicFields(fields map[string]interface{}, userID string) error {
 
-	publicMetadataJSON, err := json.Marshal(UserLangCode)
+	publicMetadataJSON, err := json.Marshal(fields)
 	if err != nil {
 		return fmt.Errorf("error marshaling metadata")
 	}
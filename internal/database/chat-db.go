@@ -2,28 +2,45 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
 	"github.com/gofrs/uuid"
 	"github.com/jackc/pgx/v5"
 )
 
-func (pg *postgres) PostNewChatFromInvite(ctx context.Context, userID string, inviteCode string) (bool, error) {
+var ErrLastAdmin = errors.New("last admin can not leave a chat with other participants")
+
+var ErrInviteRevoked = errors.New("invite creator may no longer invite to the chat")
+
+// PostNewChatFromInvite consumes the invite and either creates a chat between its creator and userID
+// or, for invites to an existing chat, adds userID to it while mayInvite allows the creator's current role
+func (pg *postgres) PostNewChatFromInvite(ctx context.Context, userID string, inviteCode string, mayInvite func(role models.ChatRole) bool) (bool, error) {
+
+	// the invite row stays locked until it is consumed so it is redeemed once
+	tx, err := pg.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("unable to begin invite transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
 
 	// retrieve userID of invite creator
-	inviteCreatorQuery := `SELECT creator_id, exp_date, consumed
+	inviteCreatorQuery := `SELECT creator_id, exp_date, consumed, chat_id::TEXT
 		FROM invite
-		WHERE invite_code=$1`
+		WHERE invite_code=$1
+		FOR UPDATE`
 
 	var inviteDetails struct {
 		creator_id 	string
 		exp_date 	time.Time
 		consumed	bool
+		chat_id		*string
 	}
 
 
-	err := pg.db.QueryRow(ctx, inviteCreatorQuery, inviteCode).Scan(&inviteDetails.creator_id, &inviteDetails.exp_date, &inviteDetails.consumed )
+	err = tx.QueryRow(ctx, inviteCreatorQuery, inviteCode).Scan(&inviteDetails.creator_id, &inviteDetails.exp_date, &inviteDetails.consumed, &inviteDetails.chat_id)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return false, fmt.Errorf("invite not found: %w", err)
@@ -41,6 +58,21 @@ func (pg *postgres) PostNewChatFromInvite(ctx context.Context, userID string, in
 		return false, fmt.Errorf("invite with invite_code %s has expired", inviteCode)
 	}
 
+	// invite was created for an existing chat, join it as member
+	if inviteDetails.chat_id != nil {
+		err = joinChatFromInvite(ctx, tx, userID, *inviteDetails.chat_id, inviteDetails.creator_id, inviteCode, mayInvite)
+		if err != nil {
+			return false, err
+		}
+
+		err = tx.Commit(ctx)
+		if err != nil {
+			return false, fmt.Errorf("unable to commit invite transaction: %w", err)
+		}
+
+		return true, nil
+	}
+
 	// create chat row
 	createChatQuery := `INSERT INTO chat (id, created_at)
 		VALUES ($1::UUID, $2)`
@@ -50,7 +82,7 @@ func (pg *postgres) PostNewChatFromInvite(ctx context.Context, userID string, in
 		return false, fmt.Errorf("failed to generate invite UUID: %w", err)
 	}
 
-	_, err = pg.db.Exec(ctx, createChatQuery, chatUUID.String(), time.Now().UTC())
+	_, err = tx.Exec(ctx, createChatQuery, chatUUID.String(), time.Now().UTC())
 	if err != nil {
 		return false, fmt.Errorf("failed to create new chat: %w", err)
 	}
@@ -60,14 +92,14 @@ func (pg *postgres) PostNewChatFromInvite(ctx context.Context, userID string, in
 	createChatParticipantQuery := `INSERT INTO chat_participant (created_at, role, chat_id, user_id)
 		VALUES ($1, $2, $3, $4)`
 
-	_, err = pg.db.Exec(ctx, createChatParticipantQuery, time.Now().UTC(), "admin", chatUUID.String(), inviteDetails.creator_id)
+	_, err = tx.Exec(ctx, createChatParticipantQuery, time.Now().UTC(), models.RoleAdmin, chatUUID.String(), inviteDetails.creator_id)
 	if err != nil {
 		return false, fmt.Errorf("failed to create new chat_participant for creator: %w", err)
 	}
 
 	// create chat_partipant row for member
 	// role: member
-	_, err = pg.db.Exec(ctx, createChatParticipantQuery, time.Now().UTC(), "member", chatUUID.String(), userID)
+	_, err = tx.Exec(ctx, createChatParticipantQuery, time.Now().UTC(), models.RoleMember, chatUUID.String(), userID)
 	if err != nil {
 		return false, fmt.Errorf("failed to create new chat_participant for member: %w", err)
 	}
@@ -76,11 +108,259 @@ func (pg *postgres) PostNewChatFromInvite(ctx context.Context, userID string, in
 	updateInviteQuery := `UPDATE invite SET chat_id=$1, consumed=$2, consumed_at=$3
 		WHERE invite_code=$4`
 
-	_, err = pg.db.Exec(ctx, updateInviteQuery,chatUUID.String(), true, time.Now().UTC(), inviteCode)
+	_, err = tx.Exec(ctx, updateInviteQuery,chatUUID.String(), true, time.Now().UTC(), inviteCode)
 	if err != nil {
 		return false, fmt.Errorf("failed to update invite row with invite code %s: %w", inviteCode, err)
 	}
 
+	err = tx.Commit(ctx)
+	if err != nil {
+		return false, fmt.Errorf("unable to commit invite transaction: %w", err)
+	}
+
 	return true, nil
 
-}
\ No newline at end of file
+}
+
+// joinChatFromInvite adds userID as a member of an existing chat and consumes the invite within tx,
+// the creator must still be a participant whose role mayInvite allows
+func joinChatFromInvite(ctx context.Context, tx pgx.Tx, userID string, chatID string, creatorID string, inviteCode string, mayInvite func(role models.ChatRole) bool) error {
+
+	participantRoleQuery := `SELECT role FROM chat_participant
+		WHERE chat_id=$1 AND user_id=$2
+		FOR SHARE`
+
+	// the creator's role is kept from changing until the invite is consumed
+	var creatorRole models.ChatRole
+	err := tx.QueryRow(ctx, participantRoleQuery, chatID, creatorID).Scan(&creatorRole)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrInviteRevoked
+		}
+		return fmt.Errorf("unable to scan chat_participant role: %w", err)
+	}
+	if !mayInvite(creatorRole) {
+		return ErrInviteRevoked
+	}
+
+	// reject users that are already part of the chat
+	var role models.ChatRole
+	err = tx.QueryRow(ctx, participantRoleQuery, chatID, userID).Scan(&role)
+	if err == nil {
+		return fmt.Errorf("user %s already a participant of chat %s", userID, chatID)
+	}
+	if err != pgx.ErrNoRows {
+		return fmt.Errorf("unable to scan chat_participant role: %w", err)
+	}
+
+	createChatParticipantQuery := `INSERT INTO chat_participant (created_at, role, chat_id, user_id)
+		VALUES ($1, $2, $3, $4)`
+
+	_, err = tx.Exec(ctx, createChatParticipantQuery, time.Now().UTC(), models.RoleMember, chatID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to create new chat_participant for member: %w", err)
+	}
+
+	updateInviteQuery := `UPDATE invite SET consumed=$1, consumed_at=$2
+		WHERE invite_code=$3`
+
+	_, err = tx.Exec(ctx, updateInviteQuery, true, time.Now().UTC(), inviteCode)
+	if err != nil {
+		return fmt.Errorf("failed to update invite row with invite code %s: %w", inviteCode, err)
+	}
+
+	return nil
+}
+
+// GetParticipantRole returns the role of userID in chatID,
+// pgx.ErrNoRows is returned as is when the user is not a participant
+func (pg *postgres) GetParticipantRole(ctx context.Context, chatID string, userID string) (models.ChatRole, error) {
+
+	participantRoleQuery := `SELECT role FROM chat_participant
+		WHERE chat_id=$1 AND user_id=$2`
+
+	var role string
+	err := pg.db.QueryRow(ctx, participantRoleQuery, chatID, userID).Scan(&role)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", err
+		}
+		return "", fmt.Errorf("unable to scan participant role: %w", err)
+	}
+
+	return models.ChatRole(role), nil
+}
+
+// GetChatParticipants returns every participant of chatID with their role
+func (pg *postgres) GetChatParticipants(ctx context.Context, chatID string) ([]models.ParticipantResponse, error) {
+
+	participantsQuery := `SELECT cp.user_id, u.username, cp.role, cp.created_at
+		FROM chat_participant cp
+		JOIN user_account u ON cp.user_id = u.id
+		WHERE cp.chat_id = $1
+		ORDER BY cp.created_at ASC`
+
+	rows, err := pg.db.Query(ctx, participantsQuery, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query chat participants: %w", err)
+	}
+	defer rows.Close()
+
+	participants := []models.ParticipantResponse{}
+	for rows.Next() {
+		var participant models.ParticipantResponse
+		var role string
+		err := rows.Scan(&participant.UserID, &participant.Username, &role, &participant.JoinedAt)
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan row of chat participants: %w", err)
+		}
+		participant.Role = models.ChatRole(role)
+		participants = append(participants, participant)
+	}
+
+	return participants, nil
+}
+
+// UpdateParticipantRole changes the role of userID in chatID
+func (pg *postgres) UpdateParticipantRole(ctx context.Context, chatID string, userID string, role models.ChatRole) error {
+
+	updateRoleQuery := `UPDATE chat_participant SET role=$1
+		WHERE chat_id=$2 AND user_id=$3`
+
+	tag, err := pg.db.Exec(ctx, updateRoleQuery, role, chatID, userID)
+	if err != nil {
+		return fmt.Errorf("unable to update chat_participant role: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// RemoveParticipant deletes the chat_participant row of userID in chatID. ErrLastAdmin is returned
+// when userID is the only admin and other participants remain, one of them has to be promoted first
+func (pg *postgres) RemoveParticipant(ctx context.Context, chatID string, userID string) error {
+
+	tx, err := pg.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to begin remove participant transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// the participants are locked so two admins leaving at once can not both pass the check
+	participantRolesQuery := `SELECT user_id, role FROM chat_participant
+		WHERE chat_id=$1
+		FOR UPDATE`
+
+	rows, err := tx.Query(ctx, participantRolesQuery, chatID)
+	if err != nil {
+		return fmt.Errorf("unable to query participant roles: %w", err)
+	}
+
+	var targetRole models.ChatRole
+	found := false
+	participants, admins := 0, 0
+	for rows.Next() {
+		var participantID, role string
+		if err := rows.Scan(&participantID, &role); err != nil {
+			rows.Close()
+			return fmt.Errorf("unable to scan row of participant roles: %w", err)
+		}
+		participants++
+		if models.ChatRole(role) == models.RoleAdmin {
+			admins++
+		}
+		if participantID == userID {
+			targetRole = models.ChatRole(role)
+			found = true
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("unable to query participant roles: %w", err)
+	}
+
+	if !found {
+		return pgx.ErrNoRows
+	}
+	if targetRole == models.RoleAdmin && admins == 1 && participants > 1 {
+		return ErrLastAdmin
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM chat_participant WHERE chat_id=$1 AND user_id=$2`, chatID, userID)
+	if err != nil {
+		return fmt.Errorf("unable to delete chat_participant row: %w", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to commit remove participant transaction: %w", err)
+	}
+
+	return nil
+}
+
+// SetMessagePinned pins or unpins a message of chatID
+func (pg *postgres) SetMessagePinned(ctx context.Context, chatID string, messageID string, userID string, pinned bool) error {
+
+	pinMessageQuery := `UPDATE message SET pinned_at=$1, pinned_by=$2
+		WHERE id=$3 AND chat_id=$4`
+
+	var pinnedAt *time.Time
+	var pinnedBy *string
+	if pinned {
+		now := time.Now().UTC()
+		pinnedAt = &now
+		pinnedBy = &userID
+	}
+
+	tag, err := pg.db.Exec(ctx, pinMessageQuery, pinnedAt, pinnedBy, messageID, chatID)
+	if err != nil {
+		return fmt.Errorf("unable to update message pin: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// GetChatMetadata returns the chat row of chatID
+func (pg *postgres) GetChatMetadata(ctx context.Context, chatID string) (models.Chat, error) {
+
+	chatQuery := `SELECT id, created_at, title, description, avatar_url
+		FROM chat
+		WHERE id=$1`
+
+	var chat models.Chat
+	err := pg.db.QueryRow(ctx, chatQuery, chatID).Scan(&chat.ID, &chat.CreatedAt, &chat.Title, &chat.Description, &chat.AvatarURL)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return models.Chat{}, err
+		}
+		return models.Chat{}, fmt.Errorf("unable to scan chat row: %w", err)
+	}
+
+	return chat, nil
+}
+
+// UpdateChatMetadata overwrites the title, description and avatar_url of a chat
+func (pg *postgres) UpdateChatMetadata(ctx context.Context, chat *models.Chat) error {
+
+	updateChatQuery := `UPDATE chat SET title=$1, description=$2, avatar_url=$3
+		WHERE id=$4`
+
+	tag, err := pg.db.Exec(ctx, updateChatQuery, chat.Title, chat.Description, chat.AvatarURL, chat.ID.String())
+	if err != nil {
+		return fmt.Errorf("unable to update chat metadata: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
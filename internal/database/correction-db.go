@@ -0,0 +1,193 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrCorrectionAccepted = errors.New("correction has already been accepted")
+
+const correctionColumns = `mc.id, mc.message_id, m.chat_id, mc.corrector_id, u.username, mc.original_content, mc.content, mc.diff, mc.created_at, mc.accepted_at`
+
+// scanCorrection scans a row selected with correctionColumns
+func scanCorrection(row pgx.Row) (models.CorrectionResponse, error) {
+	var correction models.CorrectionResponse
+	err := row.Scan(&correction.ID, &correction.MessageID, &correction.ChatID, &correction.CorrectorID, &correction.CorrectorUsername,
+		&correction.OriginalContent, &correction.Content, &correction.Diff, &correction.CreatedAt, &correction.AcceptedAt)
+	return correction, err
+}
+
+// SaveCorrection stores the correction of correction.CorrectorID for a message, replacing
+// their earlier correction unless the sender already accepted it
+func (pg *postgres) SaveCorrection(ctx context.Context, correction *models.CorrectionResponse) (models.CorrectionResponse, error) {
+
+	correctionUUID, err := uuid.NewV4()
+	if err != nil {
+		return models.CorrectionResponse{}, fmt.Errorf("unable to generate uuid %w", err)
+	}
+
+	correction.ID = correctionUUID
+	correction.CreatedAt = time.Now().UTC()
+
+	upsertCorrectionQuery := `INSERT INTO message_correction (id, message_id, corrector_id, original_content, content, diff, created_at)
+		VALUES ($1::UUID, $2::UUID, $3, $4, $5, $6, $7)
+		ON CONFLICT (message_id, corrector_id) DO UPDATE
+		SET original_content=EXCLUDED.original_content, content=EXCLUDED.content, diff=EXCLUDED.diff, created_at=EXCLUDED.created_at
+		WHERE message_correction.accepted_at IS NULL
+		RETURNING id`
+
+	err = pg.db.QueryRow(ctx, upsertCorrectionQuery, correction.ID.String(), correction.MessageID.String(), correction.CorrectorID,
+		correction.OriginalContent, correction.Content, correction.Diff, correction.CreatedAt).Scan(&correction.ID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return models.CorrectionResponse{}, ErrCorrectionAccepted
+		}
+		return models.CorrectionResponse{}, fmt.Errorf("unable to upsert message_correction row: %w", err)
+	}
+
+	return pg.GetCorrection(ctx, correction.ChatID.String(), correction.MessageID.String(), correction.ID.String())
+}
+
+// GetCorrection returns a correction of a message of chatID
+func (pg *postgres) GetCorrection(ctx context.Context, chatID string, messageID string, correctionID string) (models.CorrectionResponse, error) {
+
+	correctionQuery := `SELECT ` + correctionColumns + `
+		FROM message_correction mc
+		JOIN message m ON mc.message_id = m.id
+		JOIN user_account u ON mc.corrector_id = u.id
+		WHERE mc.id = $1 AND mc.message_id = $2 AND m.chat_id = $3`
+
+	correction, err := scanCorrection(pg.db.QueryRow(ctx, correctionQuery, correctionID, messageID, chatID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return models.CorrectionResponse{}, err
+		}
+		return models.CorrectionResponse{}, fmt.Errorf("unable to scan message_correction row: %w", err)
+	}
+
+	return correction, nil
+}
+
+// AcceptCorrection marks a correction of a message sent by userID as accepted
+func (pg *postgres) AcceptCorrection(ctx context.Context, chatID string, messageID string, correctionID string, userID string) (models.CorrectionResponse, error) {
+
+	tx, err := pg.db.Begin(ctx)
+	if err != nil {
+		return models.CorrectionResponse{}, fmt.Errorf("unable to begin accept correction transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	messageQuery := `SELECT m.sender_id, mc.accepted_at
+		FROM message_correction mc
+		JOIN message m ON mc.message_id = m.id
+		WHERE mc.id = $1 AND mc.message_id = $2 AND m.chat_id = $3
+		FOR UPDATE OF mc`
+
+	var senderID string
+	var acceptedAt *time.Time
+	err = tx.QueryRow(ctx, messageQuery, correctionID, messageID, chatID).Scan(&senderID, &acceptedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return models.CorrectionResponse{}, err
+		}
+		return models.CorrectionResponse{}, fmt.Errorf("unable to scan message_correction row: %w", err)
+	}
+
+	if senderID != userID {
+		return models.CorrectionResponse{}, ErrNotMessageSender
+	}
+
+	if acceptedAt != nil {
+		return models.CorrectionResponse{}, ErrCorrectionAccepted
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE message_correction SET accepted_at=$1 WHERE id=$2`, time.Now().UTC(), correctionID)
+	if err != nil {
+		return models.CorrectionResponse{}, fmt.Errorf("unable to update message_correction row: %w", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return models.CorrectionResponse{}, fmt.Errorf("unable to commit accept correction transaction: %w", err)
+	}
+
+	return pg.GetCorrection(ctx, chatID, messageID, correctionID)
+}
+
+// attachCorrections sets the Corrections of every message in messages, oldest first
+func (pg *postgres) attachCorrections(ctx context.Context, messages []models.MessageResponse) error {
+
+	if len(messages) == 0 {
+		return nil
+	}
+
+	messageIDStrs := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		messageIDStrs = append(messageIDStrs, msg.ID.String())
+	}
+
+	correctionsQuery := `SELECT ` + correctionColumns + `
+		FROM message_correction mc
+		JOIN message m ON mc.message_id = m.id
+		JOIN user_account u ON mc.corrector_id = u.id
+		WHERE mc.message_id = ANY($1::UUID[])
+		ORDER BY mc.created_at`
+
+	rows, err := pg.db.Query(ctx, correctionsQuery, messageIDStrs)
+	if err != nil {
+		return fmt.Errorf("unable to query message corrections: %w", err)
+	}
+	defer rows.Close()
+
+	corrections := make(map[uuid.UUID][]models.CorrectionResponse)
+	for rows.Next() {
+		correction, err := scanCorrection(rows)
+		if err != nil {
+			return fmt.Errorf("unable to scan row of message corrections: %w", err)
+		}
+		corrections[correction.MessageID] = append(corrections[correction.MessageID], correction)
+	}
+
+	for i := range messages {
+		messages[i].Corrections = corrections[messages[i].ID]
+	}
+
+	return nil
+}
+
+// GetAcceptedCorrections returns the accepted corrections of messages userID sent,
+// langCode narrows them down to messages written in it when it is not empty
+func (pg *postgres) GetAcceptedCorrections(ctx context.Context, userID string, langCode string) ([]models.CorrectionResponse, error) {
+
+	// message lang_codes may be stored as "{es}" array literals
+	correctionsQuery := `SELECT ` + correctionColumns + `
+		FROM message_correction mc
+		JOIN message m ON mc.message_id = m.id
+		JOIN user_account u ON mc.corrector_id = u.id
+		WHERE m.sender_id = $1 AND mc.accepted_at IS NOT NULL AND m.deleted_at IS NULL
+			AND ($2 = '' OR btrim(m.lang_code, '{}') = $2)
+		ORDER BY mc.accepted_at`
+
+	rows, err := pg.db.Query(ctx, correctionsQuery, userID, langCode)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query accepted corrections: %w", err)
+	}
+	defer rows.Close()
+
+	corrections := []models.CorrectionResponse{}
+	for rows.Next() {
+		correction, err := scanCorrection(rows)
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan row of accepted corrections: %w", err)
+		}
+		corrections = append(corrections, correction)
+	}
+
+	return corrections, nil
+}
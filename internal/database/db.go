@@ -9,6 +9,7 @@ import (
 	"os"
 	"sync"
 
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/languages"
 	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
 	"github.com/gofrs/uuid"
 	"github.com/jackc/pgx/v5"
@@ -113,10 +114,15 @@ func (pg *postgres) GetChats(ctx context.Context, userID string) ([]models.ChatR
 	if userLangErr != nil {
 		return nil, fmt.Errorf("unable to query user's language: %w", userLangErr)
 	}
+
+	// lang_code predating validation is used as stored, it simply matches no translation
+	if normalized, err := languages.Normalize(userLangCode); err == nil {
+		userLangCode = normalized
+	}
 	userLangCode = "{" + userLangCode + "}"
 
 	// QUERY: retrieve chat ids user is a part of
-	chatIdsQuery := `SELECT id FROM chat where id in (SELECT chat_id FROM chat_participant WHERE user_id = $1)`
+	chatIdsQuery := `SELECT id, title, description, avatar_url FROM chat where id in (SELECT chat_id FROM chat_participant WHERE user_id = $1)`
 
 	rows, err := pg.db.Query(ctx, chatIdsQuery, userID)
 	if err != nil {
@@ -124,25 +130,28 @@ func (pg *postgres) GetChats(ctx context.Context, userID string) ([]models.ChatR
 	}
 	defer rows.Close()
 	
-	var chatIDs []uuid.UUID
+	var chats []models.Chat
 	for rows.Next() {
-		var chatID uuid.UUID
-		err := rows.Scan(&chatID)
+		var chat models.Chat
+		err := rows.Scan(&chat.ID, &chat.Title, &chat.Description, &chat.AvatarURL)
 		if err != nil {
 			return nil, fmt.Errorf("unable to scan row of chatIds: %w", err)
 		}
-		chatIDs = append(chatIDs, chatID)
+		chats = append(chats, chat)
 	}
 
 	// loop through chatids, for every one get the participants of the chat
 	// and the last message and last message time
 	var chatResponseArray []models.ChatResponse
-	for _, chatID := range chatIDs {
+	for _, chat := range chats {
 		 
-		chatIDStr := chatID.String()
+		chatIDStr := chat.ID.String()
 
 		var chatResponse models.ChatResponse
 		chatResponse.ID = chatIDStr
+		chatResponse.Title = chat.Title.String
+		chatResponse.Description = chat.Description.String
+		chatResponse.AvatarURL = chat.AvatarURL.String
 
 		// QUERY: retrieve usernames of participants of chat
 		// append to ChatResponse.Participants
@@ -174,7 +183,12 @@ func (pg *postgres) GetChats(ctx context.Context, userID string) ([]models.ChatR
 				WHEN t.content IS NOT NULL THEN t.content
 				ELSE m.content
 			END AS last_message,
-			m.created_at AS last_message_time
+			m.created_at AS last_message_time,
+			m.deleted_at IS NOT NULL AS last_message_deleted,
+			m.content AS original_content,
+			m.lang_code AS original_lang_code,
+			t.content AS translated_content,
+			t.lang_code AS translated_lang_code
 		FROM 
 			message m
 		LEFT JOIN 
@@ -183,6 +197,7 @@ func (pg *postgres) GetChats(ctx context.Context, userID string) ([]models.ChatR
 			m.id = t.message_id AND t.lang_code = $1
 		WHERE 
 			m.chat_id = $2
+			AND NOT EXISTS (SELECT 1 FROM message_hidden h WHERE h.message_id = m.id AND h.user_id = $3)
 		ORDER BY 
 			m.created_at DESC
 		LIMIT 1;`
@@ -208,7 +223,11 @@ func (pg *postgres) GetChats(ctx context.Context, userID string) ([]models.ChatR
 
 		var lastMessage string
 		var lastMessageTime time.Time	
-		lastMessageErr := pg.db.QueryRow(ctx, lastMessageQuery, userLangCode, chatIDStr).Scan(&lastMessage, &lastMessageTime)
+		var lastMessageDeleted bool
+		var preview models.TranslationPair
+		var translated translatedColumns
+		lastMessageErr := pg.db.QueryRow(ctx, lastMessageQuery, userLangCode, chatIDStr, userID).Scan(&lastMessage, &lastMessageTime, &lastMessageDeleted,
+			&preview.OriginalContent, &preview.OriginalLangCode, &translated.content, &translated.langCode)
 		
 		//lastmsg_err := row.Scan(&lastMessage, &lastMessageTime)
 		if lastMessageErr != nil && lastMessageErr != pgx.ErrNoRows {
@@ -217,6 +236,9 @@ func (pg *postgres) GetChats(ctx context.Context, userID string) ([]models.ChatR
 
 		chatResponse.LastMessage = lastMessage
 		chatResponse.LastMessageTime = lastMessageTime
+		chatResponse.LastMessageDeleted = lastMessageDeleted
+		translated.apply(&preview)
+		chatResponse.LastMessagePreview = preview
 
 		// bug fixing last message not updating
 		log.Println(chatResponse.LastMessage)
@@ -233,8 +255,9 @@ func (pg *postgres) GetChats(ctx context.Context, userID string) ([]models.ChatR
 	return chatResponseArray, nil
 }
 
-// GetChatMessages responds with a slice of Messages given a specific ChatID
-func (pg *postgres) GetChatMessages(ctx context.Context	,langCode string, chatID string, pageNum int,) ([]models.MessageResponse , error) {
+// GetChatMessages responds with a slice of Messages given a specific ChatID,
+// messages userID deleted for themselves are left out
+func (pg *postgres) GetChatMessages(ctx context.Context	,userID string, langCode string, chatID string, pageNum int,) ([]models.MessageResponse , error) {
 
 
 	/*
@@ -255,7 +278,20 @@ func (pg *postgres) GetChatMessages(ctx context.Context	,langCode string, chatID
 		CASE
 			WHEN m.lang_code != $2 AND t.content IS NOT NULL THEN t.lang_code
 			ELSE m.lang_code
-		END AS lang_code
+		END AS lang_code,
+		m.edited_at,
+		m.deleted_at,
+		m.reply_to_id,
+		ru.username AS reply_sender_username,
+		CASE
+			WHEN r.lang_code != $1 AND rt.content IS NOT NULL THEN rt.content
+			ELSE r.content
+		END AS reply_content,
+		r.deleted_at IS NOT NULL AS reply_deleted,
+		m.content AS original_content,
+		m.lang_code AS original_lang_code,
+		t.content AS translated_content,
+		t.lang_code AS translated_lang_code
 		FROM 
 			message m
 		JOIN user_account u
@@ -264,13 +300,17 @@ func (pg *postgres) GetChatMessages(ctx context.Context	,langCode string, chatID
 			translation t
 		ON 
 			m.id = t.message_id AND t.lang_code = $3
+		LEFT JOIN message r ON m.reply_to_id = r.id
+		LEFT JOIN user_account ru ON r.sender_id = ru.id
+		LEFT JOIN translation rt ON r.id = rt.message_id AND rt.lang_code = $3
 		WHERE 
 			m.chat_id= $4
+			AND NOT EXISTS (SELECT 1 FROM message_hidden h WHERE h.message_id = m.id AND h.user_id = $6)
 		ORDER BY 
 			m.created_at DESC
 		LIMIT 10 OFFSET $5`
 
-	rows, err := pg.db.Query(ctx, messagesQuery,langCode, langCode, langCode, chatID, 10*pageNum)
+	rows, err := pg.db.Query(ctx, messagesQuery,langCode, langCode, langCode, chatID, 10*pageNum, userID)
 	if err != nil {
 		return nil, fmt.Errorf("unable to query chatIds: %w", err)
 	}
@@ -280,11 +320,17 @@ func (pg *postgres) GetChatMessages(ctx context.Context	,langCode string, chatID
 
 		var msg models.MessageResponse
 		var	MessageID uuid.UUID
+		var reply replyColumns
+		var translated translatedColumns
 
-		err = rows.Scan(&MessageID, &msg.ChatID, &msg.SenderUsername, &msg.SenderID, &msg.Content, &msg.CreatedAt, &msg.LangCode)
+		err = rows.Scan(&MessageID, &msg.ChatID, &msg.SenderUsername, &msg.SenderID, &msg.Content, &msg.CreatedAt, &msg.LangCode, &msg.EditedAt, &msg.DeletedAt,
+			&reply.id, &reply.senderUsername, &reply.content, &reply.deleted,
+			&msg.OriginalContent, &msg.OriginalLangCode, &translated.content, &translated.langCode)
 		if err != nil {
 			return nil, fmt.Errorf("unable to scan row: %w", err)
 		}
+		reply.apply(&msg)
+		translated.apply(&msg.TranslationPair)
 
 		// convert MessageID uuid.UUID into string
 		msg.ID = MessageID
@@ -294,6 +340,21 @@ func (pg *postgres) GetChatMessages(ctx context.Context	,langCode string, chatID
 
 	}
 
+	err = pg.attachReactions(ctx, userID, chatMessages)
+	if err != nil {
+		return nil, err
+	}
+
+	err = pg.attachCorrections(ctx, chatMessages)
+	if err != nil {
+		return nil, err
+	}
+
+	err = pg.attachVoice(ctx, chatMessages)
+	if err != nil {
+		return nil, err
+	}
+
 	return chatMessages, nil
 }
 
@@ -311,16 +372,49 @@ func (pg *postgres) CreateMessage(ctx context.Context, newMessage *models.Messag
 	log.Printf("Chat UUID: %T : %v ", newMessage.ChatID, newMessage.ChatID)
 
 
-	query := `INSERT INTO message (id, chat_id, sender_id, content, created_at, lang_code) VALUES ($1::UUID, $2::UUID, $3, $4, $5, $6)`
+	// replies must quote a message of the same chat
+	var replyToID *string
+	if newMessage.ReplyToID != nil {
+		replyTo, err := pg.GetReplySnippet(ctx, newMessage.ChatID.String(), newMessage.ReplyToID.String())
+		if err != nil {
+			return models.MessageResponse{}, fmt.Errorf("unable to retrieve replied message: %w", err)
+		}
+		newMessage.ReplyTo = &replyTo
+		replyToIDStr := newMessage.ReplyToID.String()
+		replyToID = &replyToIDStr
+	}
+
+	detectedLangCode, langConfidence := detectionValues(newMessage)
+
+	query := `INSERT INTO message (id, chat_id, sender_id, content, created_at, lang_code, reply_to_id, detected_lang_code, lang_confidence) VALUES ($1::UUID, $2::UUID, $3, $4, $5, $6, $7::UUID, $8, $9)`
 
 	newMessage.CreatedAt =  time.Now().UTC()
 
-	_, err = pg.db.Exec(ctx, query,
-		newMessage.ID.String(), newMessage.ChatID.String(), newMessage.SenderID, newMessage.Content, newMessage.CreatedAt, newMessage.LangCode)
+	tx, err := pg.db.Begin(ctx)
+	if err != nil {
+		return models.MessageResponse{}, fmt.Errorf("unable to begin create message transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, query,
+		newMessage.ID.String(), newMessage.ChatID.String(), newMessage.SenderID, newMessage.Content, newMessage.CreatedAt, newMessage.LangCode, replyToID, detectedLangCode, langConfidence)
 	if err != nil {
 		return models.MessageResponse{} ,fmt.Errorf("unable to insert new user row: %w", err)
 	}
 
+	// voice messages keep their recording next to the message row
+	if newMessage.Voice != nil {
+		err = insertVoiceMessage(ctx, tx, newMessage.ID.String(), newMessage.Voice, newMessage.CreatedAt)
+		if err != nil {
+			return models.MessageResponse{}, err
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return models.MessageResponse{}, fmt.Errorf("unable to commit create message transaction: %w", err)
+	}
+
 	return *newMessage, nil
 
 }
@@ -372,10 +466,54 @@ func (pg *postgres) UpdateUserLanguage(ctx context.Context, tx pgx.Tx,  userID s
 		return "", fmt.Errorf("unable to update user_account row's lang_code")
 	}
 
+	// lang_code is the user's native language, keep user_language in step with it
+	_, err = tx.Exec(ctx, `DELETE FROM user_language WHERE user_id=$1 AND (kind=$2 OR lang_code=$3)`, userID, models.LanguageNative, langCode)
+	if err != nil {
+		return "", fmt.Errorf("unable to delete native user_language row: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `INSERT INTO user_language (user_id, lang_code, kind, level, created_at) VALUES ($1, $2, $3, NULL, $4)`,
+		userID, langCode, models.LanguageNative, time.Now().UTC())
+	if err != nil {
+		return "", fmt.Errorf("unable to insert native user_language row: %w", err)
+	}
+
 	return langCode, nil
 }
 
-func (pg *postgres) CreateInvite(ctx context.Context, userID string) (string, error) {
+// GetUserRomanization reports whether userID opted in to romanized messages
+func (pg *postgres) GetUserRomanization(ctx context.Context, userID string) (bool, error) {
+
+	var enabled bool
+	err := pg.db.QueryRow(ctx, `SELECT show_romanization FROM user_account WHERE id=$1`, userID).Scan(&enabled)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return false, err
+		}
+		return false, fmt.Errorf("unable to scan user_account show_romanization: %w", err)
+	}
+
+	return enabled, nil
+}
+
+// SetUserRomanization turns romanized messages on or off for userID
+func (pg *postgres) SetUserRomanization(ctx context.Context, userID string, enabled bool) error {
+
+	tag, err := pg.db.Exec(ctx, `UPDATE user_account SET show_romanization=$1 WHERE id=$2`, enabled, userID)
+	if err != nil {
+		return fmt.Errorf("unable to update user_account show_romanization: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// CreateInvite creates a new invite row, chatID is left empty for invites
+// that start a new chat and set for invites into an existing chat
+func (pg *postgres) CreateInvite(ctx context.Context, userID string, chatID string) (string, error) {
 
 	// generate invite code
 	inviteCode, err := uuid.NewV4()
@@ -398,7 +536,12 @@ func (pg *postgres) CreateInvite(ctx context.Context, userID string) (string, er
 	expDate := now.AddDate(0,0,1)
 
 
-	_, err = pg.db.Exec(ctx, createInviteQuery, inviteUUID.String(), inviteCode, nil , userID, now, expDate, false, nil )
+	var inviteChatID *string
+	if chatID != "" {
+		inviteChatID = &chatID
+	}
+
+	_, err = pg.db.Exec(ctx, createInviteQuery, inviteUUID.String(), inviteCode, inviteChatID , userID, now, expDate, false, nil )
 	if err != nil {
 		return "" ,fmt.Errorf("unable to insert new invite row: %w", err)
 	}
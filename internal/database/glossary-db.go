@@ -0,0 +1,161 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrGlossaryTermExists = errors.New("term already in chat glossary for this language")
+
+var ErrGlossaryFull = errors.New("chat glossary has the most terms allowed")
+
+const glossaryColumns = `id, chat_id, term, target_lang_code, translation, created_by, created_at, updated_at`
+
+// scanGlossaryTerm scans a row selected with glossaryColumns, a NULL translation marks a term kept as written
+func scanGlossaryTerm(row pgx.Row) (models.GlossaryTerm, error) {
+	var term models.GlossaryTerm
+	var translation *string
+	err := row.Scan(&term.ID, &term.ChatID, &term.Term, &term.TargetLangCode, &translation, &term.CreatedBy, &term.CreatedAt, &term.UpdatedAt)
+	if translation != nil {
+		term.Translation = *translation
+	} else {
+		term.DoNotTranslate = true
+	}
+	return term, err
+}
+
+// glossaryTranslation is the value stored in chat_glossary_term.translation
+func glossaryTranslation(term *models.GlossaryTerm) *string {
+	if term.DoNotTranslate {
+		return nil
+	}
+	return &term.Translation
+}
+
+// GetChatGlossary returns the glossary of chatID ordered by term
+func (pg *postgres) GetChatGlossary(ctx context.Context, chatID string) ([]models.GlossaryTerm, error) {
+
+	glossaryQuery := `SELECT ` + glossaryColumns + `
+		FROM chat_glossary_term
+		WHERE chat_id = $1
+		ORDER BY lower(term), target_lang_code`
+
+	rows, err := pg.db.Query(ctx, glossaryQuery, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query chat glossary: %w", err)
+	}
+	defer rows.Close()
+
+	terms := []models.GlossaryTerm{}
+	for rows.Next() {
+		term, err := scanGlossaryTerm(rows)
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan row of chat glossary: %w", err)
+		}
+		terms = append(terms, term)
+	}
+
+	return terms, nil
+}
+
+// CreateGlossaryTerm adds a term to a chat glossary of fewer than maxTerms terms, ErrGlossaryFull
+// is returned for a full glossary and ErrGlossaryTermExists
+// when the chat already has the term for the same target language
+func (pg *postgres) CreateGlossaryTerm(ctx context.Context, term *models.GlossaryTerm, maxTerms int) (models.GlossaryTerm, error) {
+
+	tx, err := pg.db.Begin(ctx)
+	if err != nil {
+		return models.GlossaryTerm{}, fmt.Errorf("unable to begin glossary transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// the chat row serializes concurrent additions so the glossary can not outgrow maxTerms
+	_, err = tx.Exec(ctx, `SELECT 1 FROM chat WHERE id=$1 FOR UPDATE`, term.ChatID.String())
+	if err != nil {
+		return models.GlossaryTerm{}, fmt.Errorf("unable to lock chat row: %w", err)
+	}
+
+	var termCount int
+	err = tx.QueryRow(ctx, `SELECT COUNT(*) FROM chat_glossary_term WHERE chat_id=$1`, term.ChatID.String()).Scan(&termCount)
+	if err != nil {
+		return models.GlossaryTerm{}, fmt.Errorf("unable to count chat_glossary_term rows: %w", err)
+	}
+	if termCount >= maxTerms {
+		return models.GlossaryTerm{}, ErrGlossaryFull
+	}
+
+	termUUID, err := uuid.NewV4()
+	if err != nil {
+		return models.GlossaryTerm{}, fmt.Errorf("unable to generate uuid %w", err)
+	}
+
+	term.ID = termUUID
+	term.CreatedAt = time.Now().UTC()
+	term.UpdatedAt = term.CreatedAt
+
+	insertTermQuery := `INSERT INTO chat_glossary_term (` + glossaryColumns + `)
+		VALUES ($1::UUID, $2::UUID, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT DO NOTHING
+		RETURNING ` + glossaryColumns
+
+	created, err := scanGlossaryTerm(tx.QueryRow(ctx, insertTermQuery,
+		term.ID.String(), term.ChatID.String(), term.Term, term.TargetLangCode, glossaryTranslation(term),
+		term.CreatedBy, term.CreatedAt, term.UpdatedAt))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return models.GlossaryTerm{}, ErrGlossaryTermExists
+		}
+		return models.GlossaryTerm{}, fmt.Errorf("unable to insert chat_glossary_term row: %w", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return models.GlossaryTerm{}, fmt.Errorf("unable to commit glossary transaction: %w", err)
+	}
+
+	return created, nil
+}
+
+// UpdateGlossaryTerm replaces a term of the glossary of term.ChatID
+func (pg *postgres) UpdateGlossaryTerm(ctx context.Context, term *models.GlossaryTerm) (models.GlossaryTerm, error) {
+
+	updateTermQuery := `UPDATE chat_glossary_term
+		SET term=$1, target_lang_code=$2, translation=$3, updated_at=$4
+		WHERE id=$5 AND chat_id=$6
+		RETURNING ` + glossaryColumns
+
+	updated, err := scanGlossaryTerm(pg.db.QueryRow(ctx, updateTermQuery,
+		term.Term, term.TargetLangCode, glossaryTranslation(term), time.Now().UTC(), term.ID.String(), term.ChatID.String()))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return models.GlossaryTerm{}, err
+		}
+		if isUniqueViolation(err) {
+			return models.GlossaryTerm{}, ErrGlossaryTermExists
+		}
+		return models.GlossaryTerm{}, fmt.Errorf("unable to update chat_glossary_term row: %w", err)
+	}
+
+	return updated, nil
+}
+
+// DeleteGlossaryTerm removes a term from the glossary of chatID
+func (pg *postgres) DeleteGlossaryTerm(ctx context.Context, chatID string, termID string) error {
+
+	tag, err := pg.db.Exec(ctx, `DELETE FROM chat_glossary_term WHERE id=$1 AND chat_id=$2`, termID, chatID)
+	if err != nil {
+		return fmt.Errorf("unable to delete chat_glossary_term row: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
@@ -0,0 +1,185 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const jobColumns = `id, kind, payload, dedupe_key, status, attempts, max_attempts, run_at, last_error, locked_at, created_at, updated_at`
+
+// scanJob scans a row selected with jobColumns
+func scanJob(row pgx.Row) (models.Job, error) {
+	var job models.Job
+	err := row.Scan(&job.ID, &job.Kind, &job.Payload, &job.DedupeKey, &job.Status, &job.Attempts, &job.MaxAttempts,
+		&job.RunAt, &job.LastError, &job.LockedAt, &job.CreatedAt, &job.UpdatedAt)
+	return job, err
+}
+
+// EnqueueJob stores a pending job, a job with the same kind and dedupe key still
+// waiting to run absorbs it and false is returned
+func (pg *postgres) EnqueueJob(ctx context.Context, job *models.Job) (bool, error) {
+
+	jobUUID, err := uuid.NewV4()
+	if err != nil {
+		return false, fmt.Errorf("unable to generate uuid %w", err)
+	}
+
+	job.ID = jobUUID
+	job.Status = models.JobPending
+	job.CreatedAt = time.Now().UTC()
+	job.UpdatedAt = job.CreatedAt
+	if job.RunAt.IsZero() {
+		job.RunAt = job.CreatedAt
+	}
+
+	enqueueJobQuery := `INSERT INTO job (id, kind, payload, dedupe_key, status, attempts, max_attempts, run_at, created_at, updated_at)
+		VALUES ($1::UUID, $2, $3, $4, $5, 0, $6, $7, $8, $9)
+		ON CONFLICT (kind, dedupe_key) WHERE status = 'pending' DO NOTHING`
+
+	tag, err := pg.db.Exec(ctx, enqueueJobQuery, job.ID.String(), job.Kind, job.Payload, job.DedupeKey, job.Status,
+		job.MaxAttempts, job.RunAt, job.CreatedAt, job.UpdatedAt)
+	if err != nil {
+		return false, fmt.Errorf("unable to insert job row: %w", err)
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+// ClaimJob marks the pending job that is due longest as running and returns it,
+// pgx.ErrNoRows is returned when no job is due. Jobs locked by other workers are skipped,
+// as are jobs whose kind and dedupe key are those of a job still running
+func (pg *postgres) ClaimJob(ctx context.Context, now time.Time) (models.Job, error) {
+
+	claimJobQuery := `UPDATE job
+		SET status = 'running', attempts = attempts + 1, locked_at = $1, updated_at = $1
+		WHERE id = (
+			SELECT pending.id FROM job pending
+			WHERE pending.status = 'pending' AND pending.run_at <= $1
+				AND NOT EXISTS (
+					SELECT 1 FROM job running
+					WHERE running.status = 'running' AND running.kind = pending.kind AND running.dedupe_key = pending.dedupe_key
+				)
+			ORDER BY pending.run_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + jobColumns
+
+	job, err := scanJob(pg.db.QueryRow(ctx, claimJobQuery, now))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return models.Job{}, err
+		}
+		return models.Job{}, fmt.Errorf("unable to claim job: %w", err)
+	}
+
+	return job, nil
+}
+
+// CompleteJob marks a running job as done
+func (pg *postgres) CompleteJob(ctx context.Context, jobID string) error {
+
+	_, err := pg.db.Exec(ctx, `UPDATE job SET status = 'done', locked_at = NULL, updated_at = $1 WHERE id = $2`, time.Now().UTC(), jobID)
+	if err != nil {
+		return fmt.Errorf("unable to complete job: %w", err)
+	}
+
+	return nil
+}
+
+// FailJob records the error of a running job and schedules it to run again at retryAt,
+// a nil retryAt dead-letters the job
+func (pg *postgres) FailJob(ctx context.Context, jobID string, jobError string, retryAt *time.Time) error {
+
+	status := models.JobDead
+	if retryAt != nil {
+		status = models.JobPending
+	}
+
+	failJobQuery := `UPDATE job
+		SET status = $1, last_error = $2, run_at = COALESCE($3, run_at), locked_at = NULL, updated_at = $4
+		WHERE id = $5`
+
+	_, err := pg.db.Exec(ctx, failJobQuery, status, jobError, retryAt, time.Now().UTC(), jobID)
+	if err != nil {
+		// a newer pending job with the same dedupe key makes the retry redundant
+		if retryAt != nil && isUniqueViolation(err) {
+			return pg.CompleteJob(ctx, jobID)
+		}
+		return fmt.Errorf("unable to fail job: %w", err)
+	}
+
+	return nil
+}
+
+// RequeueStaleJobs returns running jobs locked before staleBefore to pending,
+// their worker is assumed to have crashed
+func (pg *postgres) RequeueStaleJobs(ctx context.Context, staleBefore time.Time) (int64, error) {
+
+	requeueJobsQuery := `UPDATE job
+		SET status = 'pending', locked_at = NULL, last_error = 'worker lease expired', updated_at = $2
+		WHERE status = 'running' AND locked_at < $1
+			AND NOT EXISTS (
+				SELECT 1 FROM job pending
+				WHERE pending.status = 'pending' AND pending.kind = job.kind AND pending.dedupe_key = job.dedupe_key
+			)`
+
+	tag, err := pg.db.Exec(ctx, requeueJobsQuery, staleBefore, time.Now().UTC())
+	if err != nil {
+		return 0, fmt.Errorf("unable to requeue stale jobs: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// GetDeadJobs returns the most recent dead-lettered jobs of kind, every kind when kind is empty
+func (pg *postgres) GetDeadJobs(ctx context.Context, kind string, limit int) ([]models.Job, error) {
+
+	deadJobsQuery := `SELECT ` + jobColumns + `
+		FROM job
+		WHERE status = 'dead' AND ($1 = '' OR kind = $1)
+		ORDER BY updated_at DESC
+		LIMIT $2`
+
+	rows, err := pg.db.Query(ctx, deadJobsQuery, kind, limit)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query dead jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []models.Job{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan row of dead jobs: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
+
+// DeleteFinishedJobs deletes the jobs of status last updated before updatedBefore,
+// status is JobDone or JobDead
+func (pg *postgres) DeleteFinishedJobs(ctx context.Context, status models.JobStatus, updatedBefore time.Time) (int64, error) {
+
+	tag, err := pg.db.Exec(ctx, `DELETE FROM job WHERE status = $1 AND updated_at < $2`, status, updatedBefore)
+	if err != nil {
+		return 0, fmt.Errorf("unable to delete finished jobs: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// isUniqueViolation reports whether err is a postgres unique constraint violation
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
@@ -0,0 +1,92 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// ReplaceUserLanguages replaces every user_language row of userID and keeps
+// user_account.lang_code pointing at the native language
+func (pg *postgres) ReplaceUserLanguages(ctx context.Context, tx pgx.Tx, userID string, languages models.UserLanguagesResponse) error {
+
+	_, err := tx.Exec(ctx, `DELETE FROM user_language WHERE user_id=$1`, userID)
+	if err != nil {
+		return fmt.Errorf("unable to delete user_language rows: %w", err)
+	}
+
+	insertLanguageQuery := `INSERT INTO user_language (user_id, lang_code, kind, level, created_at)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	now := time.Now().UTC()
+
+	_, err = tx.Exec(ctx, insertLanguageQuery, userID, languages.Native, models.LanguageNative, nil, now)
+	if err != nil {
+		return fmt.Errorf("unable to insert native user_language row: %w", err)
+	}
+
+	for _, target := range languages.Targets {
+		_, err = tx.Exec(ctx, insertLanguageQuery, userID, target.LangCode, models.LanguageTarget, string(target.Level), now)
+		if err != nil {
+			return fmt.Errorf("unable to insert target user_language row: %w", err)
+		}
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE user_account SET lang_code=$1 WHERE id=$2`, languages.Native, userID)
+	if err != nil {
+		return fmt.Errorf("unable to update user_account row's lang_code: %w", err)
+	}
+
+	return nil
+}
+
+// GetUserLanguages returns the native and target languages of userID,
+// users that only set user_account.lang_code are treated as native speakers of it
+func (pg *postgres) GetUserLanguages(ctx context.Context, userID string) (models.UserLanguagesResponse, error) {
+
+	languagesQuery := `SELECT lang_code, kind, level
+		FROM user_language
+		WHERE user_id = $1
+		ORDER BY kind, created_at`
+
+	rows, err := pg.db.Query(ctx, languagesQuery, userID)
+	if err != nil {
+		return models.UserLanguagesResponse{}, fmt.Errorf("unable to query user languages: %w", err)
+	}
+	defer rows.Close()
+
+	languages := models.UserLanguagesResponse{Targets: []models.TargetLanguage{}}
+	for rows.Next() {
+		var language models.UserLanguage
+		err := rows.Scan(&language.LangCode, &language.Kind, &language.Level)
+		if err != nil {
+			return models.UserLanguagesResponse{}, fmt.Errorf("unable to scan row of user languages: %w", err)
+		}
+
+		switch language.Kind {
+		case models.LanguageNative:
+			languages.Native = language.LangCode
+		case models.LanguageTarget:
+			languages.Targets = append(languages.Targets, models.TargetLanguage{
+				LangCode: language.LangCode,
+				Level:    models.CEFRLevel(language.Level.String),
+			})
+		}
+	}
+
+	if languages.Native == "" {
+		var langCode *string
+		err := pg.db.QueryRow(ctx, `SELECT lang_code FROM user_account WHERE id=$1`, userID).Scan(&langCode)
+		if err != nil {
+			return models.UserLanguagesResponse{}, fmt.Errorf("unable to retrieve user lang code: %w", err)
+		}
+		if langCode != nil {
+			languages.Native = *langCode
+		}
+	}
+
+	return languages, nil
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// SetLearningMode turns learning mode on or off for userID in chatID
+func (pg *postgres) SetLearningMode(ctx context.Context, chatID string, userID string, enabled bool) error {
+
+	learningModeQuery := `UPDATE chat_participant SET learning_mode=$1
+		WHERE chat_id=$2 AND user_id=$3`
+
+	tag, err := pg.db.Exec(ctx, learningModeQuery, enabled, chatID, userID)
+	if err != nil {
+		return fmt.Errorf("unable to update chat_participant learning_mode: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// GetLearningProfile returns the languages of userID and whether learning mode is on in chatID
+func (pg *postgres) GetLearningProfile(ctx context.Context, chatID string, userID string) (models.LearningProfile, bool, error) {
+
+	var enabled bool
+	err := pg.db.QueryRow(ctx, `SELECT learning_mode FROM chat_participant WHERE chat_id=$1 AND user_id=$2`, chatID, userID).Scan(&enabled)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return models.LearningProfile{}, false, err
+		}
+		return models.LearningProfile{}, false, fmt.Errorf("unable to scan learning_mode: %w", err)
+	}
+
+	if !enabled {
+		return models.LearningProfile{UserID: userID}, false, nil
+	}
+
+	languages, err := pg.GetUserLanguages(ctx, userID)
+	if err != nil {
+		return models.LearningProfile{}, false, err
+	}
+
+	profile := models.LearningProfile{
+		UserID:  userID,
+		Native:  languages.Native,
+		Targets: make(map[string]models.CEFRLevel, len(languages.Targets)),
+	}
+	for _, target := range languages.Targets {
+		profile.Targets[target.LangCode] = target.Level
+	}
+
+	return profile, true, nil
+}
+
+// GetLearningProfiles returns the profiles of every participant of chatID with learning mode on
+func (pg *postgres) GetLearningProfiles(ctx context.Context, chatID string) ([]models.LearningProfile, error) {
+
+	profilesQuery := `SELECT cp.user_id, ul.lang_code, ul.kind, ul.level
+		FROM chat_participant cp
+		JOIN user_language ul ON ul.user_id = cp.user_id
+		WHERE cp.chat_id = $1 AND cp.learning_mode
+		ORDER BY cp.user_id`
+
+	rows, err := pg.db.Query(ctx, profilesQuery, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query learning profiles: %w", err)
+	}
+	defer rows.Close()
+
+	var profiles []models.LearningProfile
+	for rows.Next() {
+		var language models.UserLanguage
+		err := rows.Scan(&language.UserID, &language.LangCode, &language.Kind, &language.Level)
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan row of learning profiles: %w", err)
+		}
+
+		if len(profiles) == 0 || profiles[len(profiles)-1].UserID != language.UserID {
+			profiles = append(profiles, models.LearningProfile{
+				UserID:  language.UserID,
+				Targets: make(map[string]models.CEFRLevel),
+			})
+		}
+		profile := &profiles[len(profiles)-1]
+
+		switch language.Kind {
+		case models.LanguageNative:
+			profile.Native = language.LangCode
+		case models.LanguageTarget:
+			profile.Targets[language.LangCode] = models.CEFRLevel(language.Level.String)
+		}
+	}
+
+	return profiles, nil
+}
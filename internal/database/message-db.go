@@ -0,0 +1,605 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrNotMessageSender = errors.New("user is not the sender of message")
+	ErrMessageDeleted   = errors.New("message has been deleted")
+	ErrMessageChanged   = errors.New("message has been edited")
+)
+
+// GetMessage returns the message row of messageID
+func (pg *postgres) GetMessage(ctx context.Context, messageID string) (models.Message, error) {
+
+	messageQuery := `SELECT id, chat_id, sender_id, content, created_at, lang_code, deleted_at
+		FROM message
+		WHERE id = $1`
+
+	var msg models.Message
+	err := pg.db.QueryRow(ctx, messageQuery, messageID).Scan(&msg.ID, &msg.ChatID, &msg.SenderID, &msg.Content, &msg.CreatedAt, &msg.LangCode, &msg.DeletedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return models.Message{}, err
+		}
+		return models.Message{}, fmt.Errorf("unable to scan message row: %w", err)
+	}
+
+	return msg, nil
+}
+
+// detectionValues returns the detection results of msg as stored in a message row,
+// they are only stored when the language was detected
+func detectionValues(msg *models.MessageResponse) (*string, *float64) {
+	if msg.DetectedLangCode == "" {
+		return nil, nil
+	}
+	return &msg.DetectedLangCode, &msg.LangConfidence
+}
+
+// GetLiveMessage returns a message of any chat in the shape it is broadcast in,
+// untranslated and with the message it replies to quoted in its original language
+func (pg *postgres) GetLiveMessage(ctx context.Context, messageID string) (models.MessageResponse, error) {
+
+	liveMessageQuery := `SELECT m.id, m.chat_id, u.username, m.sender_id, m.content, m.created_at, m.lang_code,
+		COALESCE(m.detected_lang_code, ''), COALESCE(m.lang_confidence, 0), m.edited_at, m.deleted_at,
+		m.reply_to_id, ru.username, r.content, r.deleted_at IS NOT NULL
+		FROM message m
+		JOIN user_account u ON m.sender_id = u.id
+		LEFT JOIN message r ON m.reply_to_id = r.id
+		LEFT JOIN user_account ru ON r.sender_id = ru.id
+		WHERE m.id = $1`
+
+	var msg models.MessageResponse
+	var reply replyColumns
+	err := pg.db.QueryRow(ctx, liveMessageQuery, messageID).Scan(&msg.ID, &msg.ChatID, &msg.SenderUsername, &msg.SenderID, &msg.Content, &msg.CreatedAt, &msg.LangCode,
+		&msg.DetectedLangCode, &msg.LangConfidence, &msg.EditedAt, &msg.DeletedAt,
+		&reply.id, &reply.senderUsername, &reply.content, &reply.deleted)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return models.MessageResponse{}, err
+		}
+		return models.MessageResponse{}, fmt.Errorf("unable to scan message row: %w", err)
+	}
+	reply.apply(&msg)
+
+	messages := []models.MessageResponse{msg}
+	err = pg.attachVoice(ctx, messages)
+	if err != nil {
+		return models.MessageResponse{}, err
+	}
+
+	return messages[0], nil
+}
+
+// GetChatLangCodes returns the distinct native languages of participants of chatID,
+// these are the languages messages of the chat are translated into
+func (pg *postgres) GetChatLangCodes(ctx context.Context, chatID string) ([]string, error) {
+
+	langCodesQuery := `SELECT DISTINCT COALESCE(ul.lang_code, u.lang_code) AS lang_code
+		FROM chat_participant cp
+		JOIN user_account u ON cp.user_id = u.id
+		LEFT JOIN user_language ul ON ul.user_id = u.id AND ul.kind = 'native'
+		WHERE cp.chat_id = $1 AND COALESCE(ul.lang_code, u.lang_code) IS NOT NULL`
+
+	rows, err := pg.db.Query(ctx, langCodesQuery, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query chat lang codes: %w", err)
+	}
+	defer rows.Close()
+
+	var langCodes []string
+	for rows.Next() {
+		var langCode string
+		err := rows.Scan(&langCode)
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan row of chat lang codes: %w", err)
+		}
+		langCodes = append(langCodes, langCode)
+	}
+
+	return langCodes, nil
+}
+
+// ReplaceTranslations deletes every translation of messageID and inserts translations in its place.
+// translations are of the message as it read content in langCode, ErrMessageChanged is returned
+// when it was edited since and ErrMessageDeleted when it was deleted for everyone meanwhile
+func (pg *postgres) ReplaceTranslations(ctx context.Context, messageID string, content string, langCode string, translations []models.Translation) error {
+
+	tx, err := pg.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to begin translation transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// the row stays locked until commit so an edit or delete can not slip in between
+	lockMessageQuery := `SELECT content, lang_code, deleted_at IS NOT NULL
+		FROM message
+		WHERE id = $1
+		FOR UPDATE`
+
+	var currentContent, currentLangCode string
+	var deleted bool
+	err = tx.QueryRow(ctx, lockMessageQuery, messageID).Scan(&currentContent, &currentLangCode, &deleted)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return err
+		}
+		return fmt.Errorf("unable to lock message row: %w", err)
+	}
+	if deleted {
+		return ErrMessageDeleted
+	}
+	if currentContent != content || currentLangCode != langCode {
+		return ErrMessageChanged
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM translation WHERE message_id=$1`, messageID)
+	if err != nil {
+		return fmt.Errorf("unable to delete translation rows: %w", err)
+	}
+
+	insertTranslationQuery := `INSERT INTO translation (id, message_id, content, lang_code, provider, created_at)
+		VALUES ($1::UUID, $2::UUID, $3, '{' || $4::text || '}', $5, $6)`
+
+	for _, translation := range translations {
+
+		translationUUID, err := uuid.NewV4()
+		if err != nil {
+			return fmt.Errorf("unable to generate uuid %w", err)
+		}
+
+		// translation rows are matched against "{lang_code}" by the message queries
+		_, err = tx.Exec(ctx, insertTranslationQuery, translationUUID.String(), messageID, translation.Content,
+			translation.LangCode, translation.Provider, time.Now().UTC())
+		if err != nil {
+			return fmt.Errorf("unable to insert translation row: %w", err)
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to commit translation transaction: %w", err)
+	}
+
+	return nil
+}
+
+// EditMessage replaces the content of a message sent by userID in chatID, detect sets the language
+// of the new content from the one of the previous version, which is kept in message_edit.
+// The previous version is returned as it was locked along with the edited message
+func (pg *postgres) EditMessage(ctx context.Context, chatID string, messageID string, userID string, content string, detect func(msg *models.MessageResponse)) (models.Message, models.MessageResponse, error) {
+
+	tx, err := pg.db.Begin(ctx)
+	if err != nil {
+		return models.Message{}, models.MessageResponse{}, fmt.Errorf("unable to begin edit transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	messageQuery := `SELECT m.id, m.chat_id, u.username, m.sender_id, m.content, m.created_at, m.lang_code, m.deleted_at
+		FROM message m
+		JOIN user_account u ON m.sender_id = u.id
+		WHERE m.id = $1 AND m.chat_id = $2
+		FOR UPDATE OF m`
+
+	var msg models.MessageResponse
+	err = tx.QueryRow(ctx, messageQuery, messageID, chatID).Scan(&msg.ID, &msg.ChatID, &msg.SenderUsername, &msg.SenderID, &msg.Content, &msg.CreatedAt, &msg.LangCode, &msg.DeletedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return models.Message{}, models.MessageResponse{}, err
+		}
+		return models.Message{}, models.MessageResponse{}, fmt.Errorf("unable to scan message row: %w", err)
+	}
+
+	if msg.SenderID != userID {
+		return models.Message{}, models.MessageResponse{}, ErrNotMessageSender
+	}
+
+	if msg.DeletedAt != nil {
+		return models.Message{}, models.MessageResponse{}, ErrMessageDeleted
+	}
+
+	editUUID, err := uuid.NewV4()
+	if err != nil {
+		return models.Message{}, models.MessageResponse{}, fmt.Errorf("unable to generate uuid %w", err)
+	}
+
+	editedAt := time.Now().UTC()
+
+	insertEditQuery := `INSERT INTO message_edit (id, message_id, content, lang_code, edited_at)
+		VALUES ($1::UUID, $2::UUID, $3, $4, $5)`
+
+	_, err = tx.Exec(ctx, insertEditQuery, editUUID.String(), messageID, msg.Content, msg.LangCode, editedAt)
+	if err != nil {
+		return models.Message{}, models.MessageResponse{}, fmt.Errorf("unable to insert message_edit row: %w", err)
+	}
+
+	before := models.Message{ID: msg.ID, ChatID: msg.ChatID, SenderID: msg.SenderID, Content: msg.Content,
+		CreatedAt: msg.CreatedAt, LangCode: msg.LangCode, DeletedAt: msg.DeletedAt}
+
+	msg.Content = content
+	msg.DetectedLangCode, msg.LangConfidence = "", 0
+	detect(&msg)
+	detectedLangCode, langConfidence := detectionValues(&msg)
+
+	updateMessageQuery := `UPDATE message SET content=$1, lang_code=$2, detected_lang_code=$3, lang_confidence=$4, edited_at=$5 WHERE id=$6`
+
+	_, err = tx.Exec(ctx, updateMessageQuery, content, msg.LangCode, detectedLangCode, langConfidence, editedAt, messageID)
+	if err != nil {
+		return models.Message{}, models.MessageResponse{}, fmt.Errorf("unable to update message row: %w", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return models.Message{}, models.MessageResponse{}, fmt.Errorf("unable to commit edit transaction: %w", err)
+	}
+
+	msg.EditedAt = &editedAt
+
+	messages := []models.MessageResponse{msg}
+	err = pg.attachVoice(ctx, messages)
+	if err != nil {
+		return models.Message{}, models.MessageResponse{}, err
+	}
+
+	return before, messages[0], nil
+}
+
+// GetMessageEdits returns the previous versions of a message, oldest first
+func (pg *postgres) GetMessageEdits(ctx context.Context, chatID string, messageID string) ([]models.MessageEdit, error) {
+
+	editsQuery := `SELECT e.id, e.message_id, e.content, e.lang_code, e.edited_at
+		FROM message_edit e
+		JOIN message m ON e.message_id = m.id
+		WHERE e.message_id = $1 AND m.chat_id = $2
+		ORDER BY e.edited_at ASC`
+
+	rows, err := pg.db.Query(ctx, editsQuery, messageID, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query message edits: %w", err)
+	}
+	defer rows.Close()
+
+	edits := []models.MessageEdit{}
+	for rows.Next() {
+		var edit models.MessageEdit
+		err := rows.Scan(&edit.ID, &edit.MessageID, &edit.Content, &edit.LangCode, &edit.EditedAt)
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan row of message edits: %w", err)
+		}
+		edits = append(edits, edit)
+	}
+
+	return edits, nil
+}
+
+// DeleteMessageForEveryone tombstones a message of chatID, the content of the message
+// and its translations is cleared and its edit history removed.
+// Only the sender may delete unless canDeleteOthers is set
+func (pg *postgres) DeleteMessageForEveryone(ctx context.Context, chatID string, messageID string, userID string, canDeleteOthers bool) (models.MessageDeletedResponse, error) {
+
+	tx, err := pg.db.Begin(ctx)
+	if err != nil {
+		return models.MessageDeletedResponse{}, fmt.Errorf("unable to begin delete transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	messageQuery := `SELECT id, chat_id, sender_id, content, lang_code, deleted_at, deleted_by
+		FROM message
+		WHERE id = $1 AND chat_id = $2
+		FOR UPDATE`
+
+	var deleted models.MessageDeletedResponse
+	var senderID string
+	var content string
+	var langCode string
+	var deletedAt *time.Time
+	var deletedBy *string
+	err = tx.QueryRow(ctx, messageQuery, messageID, chatID).Scan(&deleted.MessageID, &deleted.ChatID, &senderID, &content, &langCode, &deletedAt, &deletedBy)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return models.MessageDeletedResponse{}, err
+		}
+		return models.MessageDeletedResponse{}, fmt.Errorf("unable to scan message row: %w", err)
+	}
+
+	if senderID != userID && !canDeleteOthers {
+		return models.MessageDeletedResponse{}, ErrNotMessageSender
+	}
+
+	// already a tombstone, nothing left to clear
+	if deletedAt != nil {
+		deleted.DeletedAt = *deletedAt
+		if deletedBy != nil {
+			deleted.DeletedBy = *deletedBy
+		}
+		return deleted, nil
+	}
+
+	deleted.DeletedAt = time.Now().UTC()
+	deleted.DeletedBy = userID
+
+	tombstoneMessageQuery := `UPDATE message SET content='', deleted_at=$1, deleted_by=$2 WHERE id=$3`
+
+	_, err = tx.Exec(ctx, tombstoneMessageQuery, deleted.DeletedAt, deleted.DeletedBy, messageID)
+	if err != nil {
+		return models.MessageDeletedResponse{}, fmt.Errorf("unable to tombstone message row: %w", err)
+	}
+
+	// every version of the text and its translations are left to the caller to purge from caches
+	deleted.DeletedContents = []string{content}
+	deleted.DeletedLangCodes = []string{langCode}
+
+	translationRows, err := tx.Query(ctx, `SELECT content, lang_code FROM translation WHERE message_id=$1`, messageID)
+	if err != nil {
+		return models.MessageDeletedResponse{}, fmt.Errorf("unable to query translation rows: %w", err)
+	}
+	for translationRows.Next() {
+		var translationContent, translationLangCode string
+		if err := translationRows.Scan(&translationContent, &translationLangCode); err != nil {
+			translationRows.Close()
+			return models.MessageDeletedResponse{}, fmt.Errorf("unable to scan translation row: %w", err)
+		}
+		deleted.DeletedTranslations = append(deleted.DeletedTranslations, translationContent)
+		deleted.DeletedLangCodes = append(deleted.DeletedLangCodes, translationLangCode)
+	}
+	translationRows.Close()
+	if err := translationRows.Err(); err != nil {
+		return models.MessageDeletedResponse{}, fmt.Errorf("unable to query translation rows: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE translation SET content='' WHERE message_id=$1`, messageID)
+	if err != nil {
+		return models.MessageDeletedResponse{}, fmt.Errorf("unable to tombstone translation rows: %w", err)
+	}
+
+	editRows, err := tx.Query(ctx, `DELETE FROM message_edit WHERE message_id=$1 RETURNING content`, messageID)
+	if err != nil {
+		return models.MessageDeletedResponse{}, fmt.Errorf("unable to delete message_edit rows: %w", err)
+	}
+	for editRows.Next() {
+		var editContent string
+		if err := editRows.Scan(&editContent); err != nil {
+			editRows.Close()
+			return models.MessageDeletedResponse{}, fmt.Errorf("unable to scan deleted message_edit row: %w", err)
+		}
+		deleted.DeletedContents = append(deleted.DeletedContents, editContent)
+	}
+	editRows.Close()
+	if err := editRows.Err(); err != nil {
+		return models.MessageDeletedResponse{}, fmt.Errorf("unable to delete message_edit rows: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM message_reaction WHERE message_id=$1`, messageID)
+	if err != nil {
+		return models.MessageDeletedResponse{}, fmt.Errorf("unable to delete message_reaction rows: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM message_correction WHERE message_id=$1`, messageID)
+	if err != nil {
+		return models.MessageDeletedResponse{}, fmt.Errorf("unable to delete message_correction rows: %w", err)
+	}
+
+	// the recording is no longer served, its blob is left to the caller to remove
+	err = tx.QueryRow(ctx, `DELETE FROM voice_message WHERE message_id=$1 RETURNING blob_key`, messageID).Scan(&deleted.VoiceBlobKey)
+	if err != nil && err != pgx.ErrNoRows {
+		return models.MessageDeletedResponse{}, fmt.Errorf("unable to delete voice_message row: %w", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return models.MessageDeletedResponse{}, fmt.Errorf("unable to commit delete transaction: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// HideMessageForUser deletes a message of chatID for userID only
+func (pg *postgres) HideMessageForUser(ctx context.Context, chatID string, messageID string, userID string) error {
+
+	hideMessageQuery := `INSERT INTO message_hidden (message_id, user_id, hidden_at)
+		SELECT m.id, $3, $4 FROM message m
+		WHERE m.id = $1 AND m.chat_id = $2
+		ON CONFLICT (message_id, user_id) DO NOTHING`
+
+	tag, err := pg.db.Exec(ctx, hideMessageQuery, messageID, chatID, userID, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("unable to insert message_hidden row: %w", err)
+	}
+
+	// no row inserted either means the message does not exist or it is already hidden
+	if tag.RowsAffected() == 0 {
+		var exists bool
+		err = pg.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM message WHERE id=$1 AND chat_id=$2)`, messageID, chatID).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("unable to check message exists: %w", err)
+		}
+		if !exists {
+			return pgx.ErrNoRows
+		}
+	}
+
+	return nil
+}
+
+// maxSnippetLength is the number of characters of a quoted message kept in a reply
+const maxSnippetLength = 120
+
+// replyColumns holds the nullable columns of the message a reply refers to
+type replyColumns struct {
+	id             uuid.NullUUID
+	senderUsername *string
+	content        *string
+	deleted        *bool
+}
+
+// apply sets ReplyToID and ReplyTo of msg when the row is a reply
+func (r replyColumns) apply(msg *models.MessageResponse) {
+	if !r.id.Valid {
+		return
+	}
+
+	replyToID := r.id.UUID
+	msg.ReplyToID = &replyToID
+
+	// the replied message may have been removed entirely
+	if r.senderUsername == nil || r.content == nil {
+		return
+	}
+
+	msg.ReplyTo = &models.MessageSnippet{
+		ID:             replyToID,
+		SenderUsername: *r.senderUsername,
+		Content:        truncateSnippet(*r.content),
+		Deleted:        r.deleted != nil && *r.deleted,
+	}
+}
+
+// translatedColumns holds the nullable translation joined for the viewer
+type translatedColumns struct {
+	content  *string
+	langCode *string
+}
+
+// apply sets the translated half of pair when a translation for the viewer exists
+func (t translatedColumns) apply(pair *models.TranslationPair) {
+	if t.content == nil || t.langCode == nil {
+		return
+	}
+	pair.TranslatedContent = *t.content
+	pair.TranslatedLangCode = *t.langCode
+	pair.HasTranslation = true
+}
+
+func truncateSnippet(content string) string {
+	runes := []rune(content)
+	if len(runes) <= maxSnippetLength {
+		return content
+	}
+	return string(runes[:maxSnippetLength]) + "…"
+}
+
+// GetReplySnippet returns the quoted form of a message of chatID in its original language,
+// along with the quoted form of each of its translations for resolving it per viewer
+func (pg *postgres) GetReplySnippet(ctx context.Context, chatID string, messageID string) (models.MessageSnippet, error) {
+
+	snippetQuery := `SELECT m.id, u.username, m.content, m.lang_code, m.deleted_at IS NOT NULL
+		FROM message m
+		JOIN user_account u ON m.sender_id = u.id
+		WHERE m.id = $1 AND m.chat_id = $2`
+
+	var snippet models.MessageSnippet
+	err := pg.db.QueryRow(ctx, snippetQuery, messageID, chatID).Scan(&snippet.ID, &snippet.SenderUsername, &snippet.Content, &snippet.LangCode, &snippet.Deleted)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return models.MessageSnippet{}, err
+		}
+		return models.MessageSnippet{}, fmt.Errorf("unable to scan message row: %w", err)
+	}
+
+	snippet.Content = truncateSnippet(snippet.Content)
+
+	snippet.Translations, err = pg.GetMessageTranslations(ctx, messageID)
+	if err != nil {
+		return models.MessageSnippet{}, err
+	}
+	for langCode, content := range snippet.Translations {
+		snippet.Translations[langCode] = truncateSnippet(content)
+	}
+
+	return snippet, nil
+}
+
+// GetMessageThread returns messageID followed by every direct and nested reply to it,
+// oldest first and translated into langCode where a translation exists
+func (pg *postgres) GetMessageThread(ctx context.Context, userID string, langCode string, chatID string, messageID string) ([]models.MessageResponse, error) {
+
+	threadQuery := `WITH RECURSIVE thread AS (
+			SELECT id FROM message WHERE id = $2 AND chat_id = $3
+			UNION ALL
+			SELECT m.id FROM message m JOIN thread th ON m.reply_to_id = th.id
+		)
+		SELECT m.id, m.chat_id, u.username AS sender_username, m.sender_id,
+		CASE
+			WHEN m.lang_code != $1 AND t.content IS NOT NULL THEN t.content
+			ELSE m.content
+		END AS content,
+		m.created_at,
+		CASE
+			WHEN m.lang_code != $1 AND t.content IS NOT NULL THEN t.lang_code
+			ELSE m.lang_code
+		END AS lang_code,
+		m.edited_at,
+		m.deleted_at,
+		m.reply_to_id,
+		ru.username AS reply_sender_username,
+		CASE
+			WHEN r.lang_code != $1 AND rt.content IS NOT NULL THEN rt.content
+			ELSE r.content
+		END AS reply_content,
+		r.deleted_at IS NOT NULL AS reply_deleted,
+		m.content AS original_content,
+		m.lang_code AS original_lang_code,
+		t.content AS translated_content,
+		t.lang_code AS translated_lang_code
+		FROM thread
+		JOIN message m ON m.id = thread.id
+		JOIN user_account u ON m.sender_id = u.id
+		LEFT JOIN translation t ON m.id = t.message_id AND t.lang_code = $5
+		LEFT JOIN message r ON m.reply_to_id = r.id
+		LEFT JOIN user_account ru ON r.sender_id = ru.id
+		LEFT JOIN translation rt ON r.id = rt.message_id AND rt.lang_code = $5
+		WHERE NOT EXISTS (SELECT 1 FROM message_hidden h WHERE h.message_id = m.id AND h.user_id = $4)
+		ORDER BY m.created_at ASC`
+
+	// langCode is passed twice as message and translation lang_code are compared separately
+	rows, err := pg.db.Query(ctx, threadQuery, langCode, messageID, chatID, userID, langCode)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query message thread: %w", err)
+	}
+	defer rows.Close()
+
+	thread := []models.MessageResponse{}
+	for rows.Next() {
+
+		var msg models.MessageResponse
+		var reply replyColumns
+		var translated translatedColumns
+
+		err = rows.Scan(&msg.ID, &msg.ChatID, &msg.SenderUsername, &msg.SenderID, &msg.Content, &msg.CreatedAt, &msg.LangCode, &msg.EditedAt, &msg.DeletedAt,
+			&reply.id, &reply.senderUsername, &reply.content, &reply.deleted,
+			&msg.OriginalContent, &msg.OriginalLangCode, &translated.content, &translated.langCode)
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan row of message thread: %w", err)
+		}
+		reply.apply(&msg)
+		translated.apply(&msg.TranslationPair)
+
+		thread = append(thread, msg)
+	}
+
+	err = pg.attachReactions(ctx, userID, thread)
+	if err != nil {
+		return nil, err
+	}
+
+	err = pg.attachCorrections(ctx, thread)
+	if err != nil {
+		return nil, err
+	}
+
+	err = pg.attachVoice(ctx, thread)
+	if err != nil {
+		return nil, err
+	}
+
+	return thread, nil
+}
@@ -0,0 +1,13 @@
+-- chat_participant.role governs what a participant may do in a chat
+UPDATE chat_participant SET role = 'member'
+	WHERE role IS NULL OR role NOT IN ('admin', 'moderator', 'member', 'read-only');
+
+ALTER TABLE chat_participant
+	ALTER COLUMN role SET NOT NULL,
+	ADD CONSTRAINT chat_participant_role_check
+		CHECK (role IN ('admin', 'moderator', 'member', 'read-only'));
+
+-- pinning is restricted to admins and moderators
+ALTER TABLE message
+	ADD COLUMN pinned_at TIMESTAMPTZ,
+	ADD COLUMN pinned_by TEXT REFERENCES user_account (id);
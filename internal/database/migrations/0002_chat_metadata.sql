@@ -0,0 +1,5 @@
+-- optional chat details, chats without a title are named after their participants
+ALTER TABLE chat
+	ADD COLUMN title TEXT CHECK (char_length(title) <= 100),
+	ADD COLUMN description TEXT CHECK (char_length(description) <= 500),
+	ADD COLUMN avatar_url TEXT;
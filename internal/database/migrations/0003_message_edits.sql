@@ -0,0 +1,17 @@
+-- messages can be edited by their sender, previous versions are kept
+ALTER TABLE message
+	ADD COLUMN edited_at TIMESTAMPTZ;
+
+CREATE TABLE message_edit (
+	id UUID PRIMARY KEY,
+	message_id UUID NOT NULL REFERENCES message (id) ON DELETE CASCADE,
+	content TEXT NOT NULL,
+	lang_code TEXT NOT NULL,
+	edited_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX message_edit_message_id_idx ON message_edit (message_id, edited_at);
+
+-- translations record the provider that produced them
+ALTER TABLE translation
+	ADD COLUMN provider TEXT;
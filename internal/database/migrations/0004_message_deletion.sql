@@ -0,0 +1,12 @@
+-- "delete for everyone" keeps the row as a tombstone with its content cleared
+ALTER TABLE message
+	ADD COLUMN deleted_at TIMESTAMPTZ,
+	ADD COLUMN deleted_by TEXT REFERENCES user_account (id);
+
+-- "delete for me" hides a message from a single participant
+CREATE TABLE message_hidden (
+	message_id UUID NOT NULL REFERENCES message (id) ON DELETE CASCADE,
+	user_id TEXT NOT NULL REFERENCES user_account (id) ON DELETE CASCADE,
+	hidden_at TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY (message_id, user_id)
+);
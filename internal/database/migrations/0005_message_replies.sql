@@ -0,0 +1,5 @@
+-- a message may quote an earlier message of the same chat
+ALTER TABLE message
+	ADD COLUMN reply_to_id UUID REFERENCES message (id) ON DELETE SET NULL;
+
+CREATE INDEX message_reply_to_id_idx ON message (reply_to_id);
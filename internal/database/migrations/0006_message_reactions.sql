@@ -0,0 +1,8 @@
+-- one row per user and emoji on a message
+CREATE TABLE message_reaction (
+	message_id UUID NOT NULL REFERENCES message (id) ON DELETE CASCADE,
+	user_id TEXT NOT NULL REFERENCES user_account (id) ON DELETE CASCADE,
+	emoji TEXT NOT NULL CHECK (char_length(emoji) BETWEEN 1 AND 8),
+	created_at TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY (message_id, user_id, emoji)
+);
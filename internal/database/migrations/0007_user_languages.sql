@@ -0,0 +1,16 @@
+-- users speak one native language and learn any number of target languages
+CREATE TABLE user_language (
+	user_id TEXT NOT NULL REFERENCES user_account (id) ON DELETE CASCADE,
+	lang_code TEXT NOT NULL,
+	kind TEXT NOT NULL CHECK (kind IN ('native', 'target')),
+	level TEXT CHECK (level IN ('A1', 'A2', 'B1', 'B2', 'C1', 'C2')),
+	created_at TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY (user_id, lang_code),
+	CHECK ((kind = 'native') = (level IS NULL))
+);
+
+CREATE UNIQUE INDEX user_language_one_native_idx ON user_language (user_id) WHERE kind = 'native';
+
+-- existing users become native speakers of their lang_code
+INSERT INTO user_language (user_id, lang_code, kind, level, created_at)
+	SELECT id, lang_code, 'native', NULL, now() FROM user_account WHERE lang_code IS NOT NULL;
@@ -0,0 +1,3 @@
+-- learning mode is chosen per participant and chat
+ALTER TABLE chat_participant
+	ADD COLUMN learning_mode BOOLEAN NOT NULL DEFAULT false;
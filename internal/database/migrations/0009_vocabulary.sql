@@ -0,0 +1,17 @@
+-- words and phrases learners save from their chats
+CREATE TABLE vocabulary_item (
+	id UUID PRIMARY KEY,
+	user_id TEXT NOT NULL REFERENCES user_account (id) ON DELETE CASCADE,
+	lang_code TEXT NOT NULL,
+	term TEXT NOT NULL CHECK (char_length(term) BETWEEN 1 AND 100),
+	translation TEXT NOT NULL DEFAULT '',
+	translation_lang_code TEXT NOT NULL DEFAULT '',
+	context TEXT NOT NULL DEFAULT '',
+	message_id UUID REFERENCES message (id) ON DELETE SET NULL,
+	tags TEXT[] NOT NULL DEFAULT '{}',
+	created_at TIMESTAMPTZ NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL
+);
+
+-- a term is saved once per user and language regardless of case
+CREATE UNIQUE INDEX vocabulary_item_term_idx ON vocabulary_item (user_id, lang_code, lower(term));
@@ -0,0 +1,26 @@
+-- spaced repetition state of a vocabulary item, items without a card are new and due
+CREATE TABLE review_card (
+	item_id UUID PRIMARY KEY REFERENCES vocabulary_item (id) ON DELETE CASCADE,
+	user_id TEXT NOT NULL REFERENCES user_account (id) ON DELETE CASCADE,
+	ease_factor DOUBLE PRECISION NOT NULL DEFAULT 2.5,
+	interval_days INTEGER NOT NULL DEFAULT 0,
+	repetitions INTEGER NOT NULL DEFAULT 0,
+	lapses INTEGER NOT NULL DEFAULT 0,
+	due_at TIMESTAMPTZ NOT NULL,
+	last_reviewed_at TIMESTAMPTZ
+);
+
+CREATE INDEX review_card_due_idx ON review_card (user_id, due_at);
+
+-- one row per submitted grade
+CREATE TABLE review_log (
+	id UUID PRIMARY KEY,
+	item_id UUID NOT NULL REFERENCES vocabulary_item (id) ON DELETE CASCADE,
+	user_id TEXT NOT NULL REFERENCES user_account (id) ON DELETE CASCADE,
+	grade SMALLINT NOT NULL CHECK (grade BETWEEN 0 AND 5),
+	interval_days INTEGER NOT NULL,
+	ease_factor DOUBLE PRECISION NOT NULL,
+	reviewed_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX review_log_user_idx ON review_log (user_id, reviewed_at);
@@ -0,0 +1,12 @@
+-- corrections other participants suggest for a message, one per corrector
+CREATE TABLE message_correction (
+	id UUID PRIMARY KEY,
+	message_id UUID NOT NULL REFERENCES message (id) ON DELETE CASCADE,
+	corrector_id TEXT NOT NULL REFERENCES user_account (id) ON DELETE CASCADE,
+	original_content TEXT NOT NULL,
+	content TEXT NOT NULL,
+	diff JSONB NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL,
+	accepted_at TIMESTAMPTZ,
+	UNIQUE (message_id, corrector_id)
+);
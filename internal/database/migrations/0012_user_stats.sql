@@ -0,0 +1,29 @@
+-- learning statistics are aggregated from message rows incrementally,
+-- the cursor is the last message counted and the timezone days are bucketed in
+CREATE TABLE user_stats_cursor (
+	user_id TEXT PRIMARY KEY REFERENCES user_account (id) ON DELETE CASCADE,
+	timezone TEXT NOT NULL DEFAULT 'UTC',
+	last_created_at TIMESTAMPTZ,
+	last_message_id UUID
+);
+
+CREATE TABLE user_daily_stat (
+	user_id TEXT NOT NULL REFERENCES user_account (id) ON DELETE CASCADE,
+	day DATE NOT NULL,
+	lang_code TEXT NOT NULL,
+	messages_sent INTEGER NOT NULL DEFAULT 0,
+	words_written INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (user_id, day, lang_code)
+);
+
+-- distinct words a user has written per language
+CREATE TABLE user_word_use (
+	user_id TEXT NOT NULL REFERENCES user_account (id) ON DELETE CASCADE,
+	lang_code TEXT NOT NULL,
+	word TEXT NOT NULL,
+	uses INTEGER NOT NULL DEFAULT 0,
+	first_used_at TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY (user_id, lang_code, word)
+);
+
+CREATE INDEX message_sender_created_idx ON message (sender_id, created_at, id);
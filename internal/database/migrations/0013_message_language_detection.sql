@@ -0,0 +1,4 @@
+-- language the server detected for a message and how sure it was, lang_code holds the language used
+ALTER TABLE message
+	ADD COLUMN detected_lang_code TEXT,
+	ADD COLUMN lang_confidence REAL;
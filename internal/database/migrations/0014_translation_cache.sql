@@ -0,0 +1,16 @@
+-- content-addressed translations shared across chats, key is the sha256 of
+-- normalized text, source and target language, provider and scope
+CREATE TABLE translation_cache (
+	key TEXT PRIMARY KEY,
+	source_text TEXT NOT NULL,
+	source_lang TEXT NOT NULL,
+	target_lang TEXT NOT NULL,
+	provider TEXT NOT NULL,
+	scope TEXT NOT NULL DEFAULT '',
+	content TEXT NOT NULL,
+	hits BIGINT NOT NULL DEFAULT 0,
+	created_at TIMESTAMPTZ NOT NULL,
+	last_used_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX translation_cache_provider_idx ON translation_cache (provider, scope);
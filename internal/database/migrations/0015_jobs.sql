@@ -0,0 +1,22 @@
+-- durable background jobs claimed by workers with FOR UPDATE SKIP LOCKED,
+-- jobs that keep failing stay behind as status 'dead'
+CREATE TABLE job (
+	id UUID PRIMARY KEY,
+	kind TEXT NOT NULL,
+	payload JSONB NOT NULL,
+	dedupe_key TEXT,
+	status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'done', 'dead')),
+	attempts INTEGER NOT NULL DEFAULT 0,
+	max_attempts INTEGER NOT NULL,
+	run_at TIMESTAMPTZ NOT NULL,
+	last_error TEXT,
+	locked_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX job_pending_idx ON job (run_at) WHERE status = 'pending';
+CREATE INDEX job_running_idx ON job (locked_at) WHERE status = 'running';
+
+-- a job waiting to run is not queued twice, one may be queued while another runs
+CREATE UNIQUE INDEX job_dedupe_idx ON job (kind, dedupe_key) WHERE status = 'pending';
@@ -0,0 +1,14 @@
+-- terms of a chat the translation pipeline keeps or translates a fixed way,
+-- an empty target_lang_code applies to every language and a NULL translation keeps the term as written
+CREATE TABLE chat_glossary_term (
+	id UUID PRIMARY KEY,
+	chat_id UUID NOT NULL REFERENCES chat (id) ON DELETE CASCADE,
+	term TEXT NOT NULL,
+	target_lang_code TEXT NOT NULL DEFAULT '',
+	translation TEXT,
+	created_by TEXT NOT NULL REFERENCES user_account (id) ON DELETE CASCADE,
+	created_at TIMESTAMPTZ NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE UNIQUE INDEX chat_glossary_term_unique_idx ON chat_glossary_term (chat_id, lower(term), target_lang_code);
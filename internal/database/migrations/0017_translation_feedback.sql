@@ -0,0 +1,17 @@
+-- thumbs up/down of readers on a translation, provider and language pair are copied so
+-- the feedback still counts towards the provider after the message is retranslated
+CREATE TABLE translation_feedback (
+	id UUID PRIMARY KEY,
+	translation_id UUID REFERENCES translation (id) ON DELETE SET NULL,
+	message_id UUID NOT NULL REFERENCES message (id) ON DELETE CASCADE,
+	user_id TEXT NOT NULL REFERENCES user_account (id) ON DELETE CASCADE,
+	provider TEXT NOT NULL,
+	source_lang_code TEXT NOT NULL,
+	target_lang_code TEXT NOT NULL,
+	rating SMALLINT NOT NULL CHECK (rating IN (-1, 1)),
+	created_at TIMESTAMPTZ NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE UNIQUE INDEX translation_feedback_user_idx ON translation_feedback (translation_id, user_id);
+CREATE INDEX translation_feedback_provider_idx ON translation_feedback (provider, source_lang_code, target_lang_code, created_at);
@@ -0,0 +1,2 @@
+-- users opt in to receiving romanized text of messages written in a non-Latin script
+ALTER TABLE user_account ADD COLUMN show_romanization BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,13 @@
+-- voice recordings of messages, the audio lives in the blob store and the transcript
+-- becomes the content of the message once transcription finished
+CREATE TABLE voice_message (
+	message_id UUID PRIMARY KEY REFERENCES message (id) ON DELETE CASCADE,
+	blob_key TEXT NOT NULL,
+	content_type TEXT NOT NULL,
+	size_bytes BIGINT NOT NULL,
+	duration_ms INTEGER,
+	transcript_status TEXT NOT NULL DEFAULT 'pending' CHECK (transcript_status IN ('pending', 'done', 'failed')),
+	transcript_error TEXT,
+	created_at TIMESTAMPTZ NOT NULL,
+	transcribed_at TIMESTAMPTZ
+);
@@ -0,0 +1,9 @@
+-- cached translations keep the sha256 of their source text instead of the text itself,
+-- so that translations of messages deleted for everyone can be found and purged.
+-- existing rows can not be hashed without their text and are dropped, they are only a cache
+DELETE FROM translation_cache;
+
+ALTER TABLE translation_cache DROP COLUMN source_text;
+ALTER TABLE translation_cache ADD COLUMN source_hash TEXT NOT NULL;
+
+CREATE INDEX translation_cache_source_hash_idx ON translation_cache (source_hash);
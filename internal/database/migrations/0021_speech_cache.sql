@@ -0,0 +1,10 @@
+-- speech synthesized for message audio is cached in the blob store under tts/ keys,
+-- tracked here so audio nobody listened to for a while can be evicted
+CREATE TABLE speech_cache (
+	blob_key TEXT PRIMARY KEY,
+	size_bytes BIGINT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL,
+	last_used_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX speech_cache_last_used_at_idx ON speech_cache (last_used_at);
@@ -0,0 +1,2 @@
+-- finished jobs are deleted once they are past their retention
+CREATE INDEX job_finished_idx ON job (status, updated_at) WHERE status IN ('done', 'dead');
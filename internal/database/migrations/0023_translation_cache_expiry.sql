@@ -0,0 +1,2 @@
+-- cached translations expire once they were not looked up for a while
+CREATE INDEX translation_cache_last_used_idx ON translation_cache (last_used_at);
@@ -0,0 +1,32 @@
+-- messages are numbered in insertion order so the stats cursor can not skip a message that
+-- committed after a later one was counted, existing messages are numbered by (created_at, id)
+ALTER TABLE message ADD COLUMN seq BIGINT;
+
+UPDATE message
+SET seq = numbered.seq
+FROM (SELECT id, row_number() OVER (ORDER BY created_at, id) AS seq FROM message) numbered
+WHERE message.id = numbered.id;
+
+CREATE SEQUENCE message_seq_seq OWNED BY message.seq;
+SELECT setval('message_seq_seq', COALESCE(MAX(seq), 0) + 1, false) FROM message;
+
+ALTER TABLE message ALTER COLUMN seq SET DEFAULT nextval('message_seq_seq');
+ALTER TABLE message ALTER COLUMN seq SET NOT NULL;
+
+CREATE INDEX message_sender_seq_idx ON message (sender_id, seq);
+DROP INDEX message_sender_created_idx;
+
+-- the cursor becomes the seq of the last message counted
+ALTER TABLE user_stats_cursor ADD COLUMN last_seq BIGINT;
+
+UPDATE user_stats_cursor
+SET last_seq = (
+	SELECT MAX(message.seq)
+	FROM message
+	WHERE message.sender_id = user_stats_cursor.user_id
+		AND (message.created_at, message.id) <= (user_stats_cursor.last_created_at, user_stats_cursor.last_message_id)
+)
+WHERE last_created_at IS NOT NULL;
+
+ALTER TABLE user_stats_cursor DROP COLUMN last_created_at;
+ALTER TABLE user_stats_cursor DROP COLUMN last_message_id;
@@ -0,0 +1,11 @@
+-- reactions are stored without a trailing emoji variation selector so "❤" and "❤️" are one reaction,
+-- a user who reacted with both keeps one
+DELETE FROM message_reaction selected
+WHERE right(selected.emoji, 1) = U&'\FE0F'
+	AND EXISTS (
+		SELECT 1 FROM message_reaction plain
+		WHERE plain.message_id = selected.message_id AND plain.user_id = selected.user_id
+			AND plain.emoji = left(selected.emoji, -1)
+	);
+
+UPDATE message_reaction SET emoji = left(emoji, -1) WHERE right(emoji, 1) = U&'\FE0F';
@@ -0,0 +1,145 @@
+-- lang_code values written before the language registry, such as "es-MX", "Spanish" or an unwrapped
+-- "es" in message and translation rows, are rewritten to the registry code. user_account stores the
+-- bare code, message and translation wrap it as "{es}". Values naming no registered language are
+-- left as they are and listed in unresolved_lang_code
+CREATE TEMPORARY TABLE lang_code_alias (
+	alias TEXT PRIMARY KEY,
+	code TEXT NOT NULL
+);
+
+-- registry codes, English names and deprecated codes, mirroring internal/languages
+INSERT INTO lang_code_alias (alias, code) VALUES
+	('ar', 'ar'),
+	('bg', 'bg'),
+	('bn', 'bn'),
+	('ca', 'ca'),
+	('cs', 'cs'),
+	('da', 'da'),
+	('de', 'de'),
+	('el', 'el'),
+	('en', 'en'),
+	('eo', 'eo'),
+	('es', 'es'),
+	('fa', 'fa'),
+	('fi', 'fi'),
+	('fr', 'fr'),
+	('ga', 'ga'),
+	('he', 'he'),
+	('hi', 'hi'),
+	('hu', 'hu'),
+	('id', 'id'),
+	('it', 'it'),
+	('ja', 'ja'),
+	('ko', 'ko'),
+	('la', 'la'),
+	('mr', 'mr'),
+	('ms', 'ms'),
+	('nb', 'nb'),
+	('ne', 'ne'),
+	('nl', 'nl'),
+	('pl', 'pl'),
+	('pt', 'pt'),
+	('ro', 'ro'),
+	('ru', 'ru'),
+	('sk', 'sk'),
+	('sv', 'sv'),
+	('sw', 'sw'),
+	('th', 'th'),
+	('tl', 'tl'),
+	('tr', 'tr'),
+	('uk', 'uk'),
+	('ur', 'ur'),
+	('vi', 'vi'),
+	('zh', 'zh'),
+	('arabic', 'ar'),
+	('bulgarian', 'bg'),
+	('bengali', 'bn'),
+	('catalan', 'ca'),
+	('czech', 'cs'),
+	('danish', 'da'),
+	('german', 'de'),
+	('greek', 'el'),
+	('english', 'en'),
+	('esperanto', 'eo'),
+	('spanish', 'es'),
+	('persian', 'fa'),
+	('finnish', 'fi'),
+	('french', 'fr'),
+	('irish', 'ga'),
+	('hebrew', 'he'),
+	('hindi', 'hi'),
+	('hungarian', 'hu'),
+	('indonesian', 'id'),
+	('italian', 'it'),
+	('japanese', 'ja'),
+	('korean', 'ko'),
+	('latin', 'la'),
+	('marathi', 'mr'),
+	('malay', 'ms'),
+	('norwegian bokmål', 'nb'),
+	('nepali', 'ne'),
+	('dutch', 'nl'),
+	('polish', 'pl'),
+	('portuguese', 'pt'),
+	('romanian', 'ro'),
+	('russian', 'ru'),
+	('slovak', 'sk'),
+	('swedish', 'sv'),
+	('swahili', 'sw'),
+	('thai', 'th'),
+	('tagalog', 'tl'),
+	('turkish', 'tr'),
+	('ukrainian', 'uk'),
+	('urdu', 'ur'),
+	('vietnamese', 'vi'),
+	('chinese', 'zh'),
+	('iw', 'he'),
+	('in', 'id'),
+	('no', 'nb'),
+	('fil', 'tl'),
+	('zho', 'zh'),
+	('cmn', 'zh');
+
+-- a value resolves by its whole text, so names match, or else by its primary subtag
+CREATE FUNCTION pg_temp.resolve_lang_code(value TEXT) RETURNS TEXT AS $$
+	SELECT COALESCE(
+		(SELECT code FROM lang_code_alias WHERE alias = lower(btrim(value, '{} '))),
+		(SELECT code FROM lang_code_alias
+			WHERE alias = lower(split_part(replace(btrim(value, '{} '), '_', '-'), '-', 1)))
+	)
+$$ LANGUAGE SQL STABLE;
+
+CREATE TABLE unresolved_lang_code (
+	table_name TEXT NOT NULL,
+	row_id TEXT NOT NULL,
+	lang_code TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY (table_name, row_id)
+);
+
+INSERT INTO unresolved_lang_code (table_name, row_id, lang_code, created_at)
+	SELECT 'user_account', id, lang_code, now() FROM user_account
+	WHERE lang_code IS NOT NULL AND pg_temp.resolve_lang_code(lang_code) IS NULL;
+
+INSERT INTO unresolved_lang_code (table_name, row_id, lang_code, created_at)
+	SELECT 'message', id::text, lang_code, now() FROM message
+	WHERE lang_code IS NOT NULL AND pg_temp.resolve_lang_code(lang_code) IS NULL;
+
+INSERT INTO unresolved_lang_code (table_name, row_id, lang_code, created_at)
+	SELECT 'translation', id::text, lang_code, now() FROM translation
+	WHERE lang_code IS NOT NULL AND pg_temp.resolve_lang_code(lang_code) IS NULL;
+
+UPDATE user_account SET lang_code = pg_temp.resolve_lang_code(lang_code)
+WHERE pg_temp.resolve_lang_code(lang_code) IS DISTINCT FROM lang_code
+	AND pg_temp.resolve_lang_code(lang_code) IS NOT NULL;
+
+UPDATE message SET lang_code = '{' || pg_temp.resolve_lang_code(lang_code) || '}'
+WHERE '{' || pg_temp.resolve_lang_code(lang_code) || '}' IS DISTINCT FROM lang_code
+	AND pg_temp.resolve_lang_code(lang_code) IS NOT NULL;
+
+UPDATE translation SET lang_code = '{' || pg_temp.resolve_lang_code(lang_code) || '}'
+WHERE '{' || pg_temp.resolve_lang_code(lang_code) || '}' IS DISTINCT FROM lang_code
+	AND pg_temp.resolve_lang_code(lang_code) IS NOT NULL;
+
+DROP FUNCTION pg_temp.resolve_lang_code(TEXT);
+DROP TABLE lang_code_alias;
@@ -0,0 +1,120 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ToggleReaction adds the reaction of userID to a message of chatID,
+// or removes it when the user already reacted with emoji
+func (pg *postgres) ToggleReaction(ctx context.Context, chatID string, messageID string, userID string, emoji string) (models.ReactionResponse, error) {
+
+	tx, err := pg.db.Begin(ctx)
+	if err != nil {
+		return models.ReactionResponse{}, fmt.Errorf("unable to begin reaction transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// reactions on tombstoned messages are rejected the same as missing messages
+	messageQuery := `SELECT id, chat_id FROM message
+		WHERE id = $1 AND chat_id = $2 AND deleted_at IS NULL`
+
+	reaction := models.ReactionResponse{UserID: userID, Emoji: emoji}
+	err = tx.QueryRow(ctx, messageQuery, messageID, chatID).Scan(&reaction.MessageID, &reaction.ChatID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return models.ReactionResponse{}, err
+		}
+		return models.ReactionResponse{}, fmt.Errorf("unable to scan message row: %w", err)
+	}
+
+	deleteReactionQuery := `DELETE FROM message_reaction
+		WHERE message_id = $1 AND user_id = $2 AND emoji = $3`
+
+	tag, err := tx.Exec(ctx, deleteReactionQuery, messageID, userID, emoji)
+	if err != nil {
+		return models.ReactionResponse{}, fmt.Errorf("unable to delete message_reaction row: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		insertReactionQuery := `INSERT INTO message_reaction (message_id, user_id, emoji, created_at)
+			VALUES ($1::UUID, $2, $3, $4)`
+
+		_, err = tx.Exec(ctx, insertReactionQuery, messageID, userID, emoji, time.Now().UTC())
+		if err != nil {
+			return models.ReactionResponse{}, fmt.Errorf("unable to insert message_reaction row: %w", err)
+		}
+		reaction.Added = true
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return models.ReactionResponse{}, fmt.Errorf("unable to commit reaction transaction: %w", err)
+	}
+
+	return reaction, nil
+}
+
+// GetReactionCounts aggregates the reactions of messageIDs per emoji,
+// ReactedByMe is set for the emojis userID reacted with
+func (pg *postgres) GetReactionCounts(ctx context.Context, userID string, messageIDs []uuid.UUID) (map[uuid.UUID][]models.ReactionCount, error) {
+
+	reactionCounts := make(map[uuid.UUID][]models.ReactionCount)
+	if len(messageIDs) == 0 {
+		return reactionCounts, nil
+	}
+
+	messageIDStrs := make([]string, 0, len(messageIDs))
+	for _, messageID := range messageIDs {
+		messageIDStrs = append(messageIDStrs, messageID.String())
+	}
+
+	reactionCountsQuery := `SELECT message_id, emoji, COUNT(*), BOOL_OR(user_id = $2)
+		FROM message_reaction
+		WHERE message_id = ANY($1::UUID[])
+		GROUP BY message_id, emoji
+		ORDER BY message_id, MIN(created_at)`
+
+	rows, err := pg.db.Query(ctx, reactionCountsQuery, messageIDStrs, userID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query reaction counts: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var messageID uuid.UUID
+		var reactionCount models.ReactionCount
+		err := rows.Scan(&messageID, &reactionCount.Emoji, &reactionCount.Count, &reactionCount.ReactedByMe)
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan row of reaction counts: %w", err)
+		}
+		reactionCounts[messageID] = append(reactionCounts[messageID], reactionCount)
+	}
+
+	return reactionCounts, nil
+}
+
+// attachReactions sets the aggregated Reactions of every message in messages
+func (pg *postgres) attachReactions(ctx context.Context, userID string, messages []models.MessageResponse) error {
+
+	messageIDs := make([]uuid.UUID, 0, len(messages))
+	for _, msg := range messages {
+		messageIDs = append(messageIDs, msg.ID)
+	}
+
+	reactionCounts, err := pg.GetReactionCounts(ctx, userID, messageIDs)
+	if err != nil {
+		return err
+	}
+
+	for i := range messages {
+		messages[i].Reactions = reactionCounts[messages[i].ID]
+	}
+
+	return nil
+}
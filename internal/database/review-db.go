@@ -0,0 +1,155 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// columns of a vocabulary_item joined with its review_card, cards of new items are filled with defaults
+const dueCardColumns = `vi.id, vi.user_id, vi.lang_code, vi.term, vi.translation, vi.translation_lang_code, vi.context,
+	vi.message_id, vi.tags, vi.created_at, vi.updated_at,
+	rc.item_id IS NULL, COALESCE(rc.ease_factor, 2.5), COALESCE(rc.interval_days, 0), COALESCE(rc.repetitions, 0),
+	COALESCE(rc.lapses, 0), COALESCE(rc.due_at, vi.created_at), rc.last_reviewed_at`
+
+// scanDueCard scans a row selected with dueCardColumns
+func scanDueCard(row pgx.Row) (models.DueCardResponse, error) {
+	var dueCard models.DueCardResponse
+	item := &dueCard.Item
+	card := &dueCard.Card
+	err := row.Scan(&item.ID, &item.UserID, &item.LangCode, &item.Term, &item.Translation, &item.TranslationLangCode, &item.Context,
+		&item.MessageID, &item.Tags, &item.CreatedAt, &item.UpdatedAt,
+		&dueCard.New, &card.EaseFactor, &card.IntervalDays, &card.Repetitions,
+		&card.Lapses, &card.DueAt, &card.LastReviewedAt)
+	card.ItemID = item.ID
+	card.UserID = item.UserID
+	return dueCard, err
+}
+
+// GetDueCards returns up to limit vocabulary items of userID due at now, overdue cards first
+// and new items after them. langCode narrows the cards down when it is not empty
+func (pg *postgres) GetDueCards(ctx context.Context, userID string, langCode string, now time.Time, limit int) ([]models.DueCardResponse, error) {
+
+	dueCardsQuery := `SELECT ` + dueCardColumns + `
+		FROM vocabulary_item vi
+		LEFT JOIN review_card rc ON rc.item_id = vi.id
+		WHERE vi.user_id = $1
+			AND ($2 = '' OR vi.lang_code = $2)
+			AND (rc.item_id IS NULL OR rc.due_at <= $3)
+		ORDER BY rc.item_id IS NULL, rc.due_at, vi.created_at
+		LIMIT $4`
+
+	rows, err := pg.db.Query(ctx, dueCardsQuery, userID, langCode, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query due cards: %w", err)
+	}
+	defer rows.Close()
+
+	dueCards := []models.DueCardResponse{}
+	for rows.Next() {
+		dueCard, err := scanDueCard(rows)
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan row of due cards: %w", err)
+		}
+		dueCards = append(dueCards, dueCard)
+	}
+
+	return dueCards, nil
+}
+
+// LockReviewCard returns the card of a vocabulary item of userID with the item locked for the rest
+// of tx, so reviews of the same item are applied one after the other. pgx.ErrNoRows is returned
+// when the item does not exist or belongs to another user
+func (pg *postgres) LockReviewCard(ctx context.Context, tx pgx.Tx, userID string, itemID string) (models.DueCardResponse, error) {
+
+	// items without a card yet have no review_card row to lock, the item row stands in for it
+	cardQuery := `SELECT ` + dueCardColumns + `
+		FROM vocabulary_item vi
+		LEFT JOIN review_card rc ON rc.item_id = vi.id
+		WHERE vi.id = $1 AND vi.user_id = $2
+		FOR UPDATE OF vi`
+
+	dueCard, err := scanDueCard(tx.QueryRow(ctx, cardQuery, itemID, userID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return models.DueCardResponse{}, err
+		}
+		return models.DueCardResponse{}, fmt.Errorf("unable to scan review card row: %w", err)
+	}
+
+	return dueCard, nil
+}
+
+// SaveReview stores the rescheduled card and logs the grade it was reviewed with
+func (pg *postgres) SaveReview(ctx context.Context, tx pgx.Tx, card *models.ReviewCard, grade int) error {
+
+	upsertCardQuery := `INSERT INTO review_card (item_id, user_id, ease_factor, interval_days, repetitions, lapses, due_at, last_reviewed_at)
+		VALUES ($1::UUID, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (item_id) DO UPDATE
+		SET ease_factor=EXCLUDED.ease_factor, interval_days=EXCLUDED.interval_days, repetitions=EXCLUDED.repetitions,
+			lapses=EXCLUDED.lapses, due_at=EXCLUDED.due_at, last_reviewed_at=EXCLUDED.last_reviewed_at`
+
+	_, err := tx.Exec(ctx, upsertCardQuery, card.ItemID.String(), card.UserID, card.EaseFactor, card.IntervalDays,
+		card.Repetitions, card.Lapses, card.DueAt, card.LastReviewedAt)
+	if err != nil {
+		return fmt.Errorf("unable to upsert review_card row: %w", err)
+	}
+
+	logUUID, err := uuid.NewV4()
+	if err != nil {
+		return fmt.Errorf("unable to generate uuid %w", err)
+	}
+
+	insertLogQuery := `INSERT INTO review_log (id, item_id, user_id, grade, interval_days, ease_factor, reviewed_at)
+		VALUES ($1::UUID, $2::UUID, $3, $4, $5, $6, $7)`
+
+	_, err = tx.Exec(ctx, insertLogQuery, logUUID.String(), card.ItemID.String(), card.UserID, grade,
+		card.IntervalDays, card.EaseFactor, card.LastReviewedAt)
+	if err != nil {
+		return fmt.Errorf("unable to insert review_log row: %w", err)
+	}
+
+	return nil
+}
+
+// GetReviewStats counts the cards and reviews of userID, dayStart is the start of
+// the user's current day. langCode narrows the stats down when it is not empty
+func (pg *postgres) GetReviewStats(ctx context.Context, userID string, langCode string, now time.Time, dayStart time.Time) (models.ReviewStatsResponse, error) {
+
+	stats := models.ReviewStatsResponse{LangCode: langCode}
+
+	cardStatsQuery := `SELECT COUNT(*),
+			COUNT(*) FILTER (WHERE rc.item_id IS NULL),
+			COUNT(*) FILTER (WHERE rc.item_id IS NULL OR rc.due_at <= $3),
+			COUNT(*) FILTER (WHERE rc.interval_days >= 21)
+		FROM vocabulary_item vi
+		LEFT JOIN review_card rc ON rc.item_id = vi.id
+		WHERE vi.user_id = $1 AND ($2 = '' OR vi.lang_code = $2)`
+
+	err := pg.db.QueryRow(ctx, cardStatsQuery, userID, langCode, now).Scan(
+		&stats.TotalCards, &stats.NewCards, &stats.DueCards, &stats.MatureCards)
+	if err != nil {
+		return models.ReviewStatsResponse{}, fmt.Errorf("unable to scan card stats: %w", err)
+	}
+
+	// retention is the share of passing grades over the last 30 days
+	reviewStatsQuery := `SELECT COUNT(*) FILTER (WHERE rl.reviewed_at >= $3),
+			COUNT(*) FILTER (WHERE rl.reviewed_at >= $4 - INTERVAL '7 days'),
+			COALESCE(AVG(CASE WHEN rl.grade >= 3 THEN 1.0 ELSE 0.0 END), 0)
+		FROM review_log rl
+		JOIN vocabulary_item vi ON vi.id = rl.item_id
+		WHERE rl.user_id = $1 AND ($2 = '' OR vi.lang_code = $2)
+			AND rl.reviewed_at >= $4 - INTERVAL '30 days'`
+
+	err = pg.db.QueryRow(ctx, reviewStatsQuery, userID, langCode, dayStart, now).Scan(
+		&stats.ReviewsToday, &stats.ReviewsWeek, &stats.RetentionRate)
+	if err != nil {
+		return models.ReviewStatsResponse{}, fmt.Errorf("unable to scan review stats: %w", err)
+	}
+
+	return stats, nil
+}
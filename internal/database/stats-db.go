@@ -0,0 +1,260 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// LockStatsCursor returns the stats cursor of userID locked for the rest of tx,
+// users without one start with an empty cursor in timezone
+func (pg *postgres) LockStatsCursor(ctx context.Context, tx pgx.Tx, userID string, timezone string) (models.StatsCursor, error) {
+
+	_, err := tx.Exec(ctx, `INSERT INTO user_stats_cursor (user_id, timezone) VALUES ($1, $2) ON CONFLICT DO NOTHING`, userID, timezone)
+	if err != nil {
+		return models.StatsCursor{}, fmt.Errorf("unable to insert user_stats_cursor row: %w", err)
+	}
+
+	cursorQuery := `SELECT user_id, timezone, last_seq
+		FROM user_stats_cursor
+		WHERE user_id = $1
+		FOR UPDATE`
+
+	var cursor models.StatsCursor
+	err = tx.QueryRow(ctx, cursorQuery, userID).Scan(&cursor.UserID, &cursor.Timezone, &cursor.LastSeq)
+	if err != nil {
+		return models.StatsCursor{}, fmt.Errorf("unable to scan user_stats_cursor row: %w", err)
+	}
+
+	return cursor, nil
+}
+
+// ResetUserStats drops the aggregated stats of userID so they are counted again in timezone
+func (pg *postgres) ResetUserStats(ctx context.Context, tx pgx.Tx, userID string, timezone string) error {
+
+	_, err := tx.Exec(ctx, `DELETE FROM user_daily_stat WHERE user_id=$1`, userID)
+	if err != nil {
+		return fmt.Errorf("unable to delete user_daily_stat rows: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM user_word_use WHERE user_id=$1`, userID)
+	if err != nil {
+		return fmt.Errorf("unable to delete user_word_use rows: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE user_stats_cursor SET timezone=$1, last_seq=NULL WHERE user_id=$2`, timezone, userID)
+	if err != nil {
+		return fmt.Errorf("unable to reset user_stats_cursor row: %w", err)
+	}
+
+	return nil
+}
+
+// GetMessagesAfterCursor returns up to limit messages sent by the cursor's user after it in seq
+// order, stopping before the first one created at or after settledBefore
+func (pg *postgres) GetMessagesAfterCursor(ctx context.Context, tx pgx.Tx, cursor models.StatsCursor, settledBefore time.Time, limit int) ([]models.Message, error) {
+
+	// a message with a lower seq may still be uncommitted while a later one is visible, so
+	// recent messages and everything after them wait for the next refresh
+	messagesQuery := `SELECT id, chat_id, sender_id, content, created_at, lang_code, deleted_at, seq
+		FROM message
+		WHERE sender_id = $1
+			AND seq > COALESCE($2, 0)
+			AND NOT EXISTS (
+				SELECT 1 FROM message recent
+				WHERE recent.sender_id = $1 AND recent.seq > COALESCE($2, 0) AND recent.seq <= message.seq
+					AND recent.created_at >= $3
+			)
+		ORDER BY seq
+		LIMIT $4`
+
+	rows, err := tx.Query(ctx, messagesQuery, cursor.UserID, cursor.LastSeq, settledBefore, limit)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query messages after stats cursor: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []models.Message
+	for rows.Next() {
+		var msg models.Message
+		err := rows.Scan(&msg.ID, &msg.ChatID, &msg.SenderID, &msg.Content, &msg.CreatedAt, &msg.LangCode, &msg.DeletedAt, &msg.Seq)
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan row of messages after stats cursor: %w", err)
+		}
+		messages = append(messages, msg)
+	}
+
+	return messages, nil
+}
+
+// AddDailyStats adds the counts of dailyStats onto the stored days
+func (pg *postgres) AddDailyStats(ctx context.Context, tx pgx.Tx, dailyStats []models.DailyStat) error {
+
+	addDailyStatQuery := `INSERT INTO user_daily_stat (user_id, day, lang_code, messages_sent, words_written)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, day, lang_code) DO UPDATE
+		SET messages_sent = user_daily_stat.messages_sent + EXCLUDED.messages_sent,
+			words_written = user_daily_stat.words_written + EXCLUDED.words_written`
+
+	for _, dailyStat := range dailyStats {
+		_, err := tx.Exec(ctx, addDailyStatQuery, dailyStat.UserID, dailyStat.Day, dailyStat.LangCode, dailyStat.MessagesSent, dailyStat.WordsWritten)
+		if err != nil {
+			return fmt.Errorf("unable to upsert user_daily_stat row: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// AddWordUses adds the uses of words, keyed by word, written by userID in langCode
+func (pg *postgres) AddWordUses(ctx context.Context, tx pgx.Tx, userID string, langCode string, words map[string]int, usedAt time.Time) error {
+
+	if len(words) == 0 {
+		return nil
+	}
+
+	wordList := make([]string, 0, len(words))
+	uses := make([]int32, 0, len(words))
+	for word, count := range words {
+		wordList = append(wordList, word)
+		uses = append(uses, int32(count))
+	}
+
+	addWordUsesQuery := `INSERT INTO user_word_use (user_id, lang_code, word, uses, first_used_at)
+		SELECT $1, $2, w.word, w.uses, $5
+		FROM unnest($3::TEXT[], $4::INTEGER[]) AS w(word, uses)
+		ON CONFLICT (user_id, lang_code, word) DO UPDATE
+		SET uses = user_word_use.uses + EXCLUDED.uses`
+
+	_, err := tx.Exec(ctx, addWordUsesQuery, userID, langCode, wordList, uses, usedAt)
+	if err != nil {
+		return fmt.Errorf("unable to upsert user_word_use rows: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteEmptyStats drops the days and words of userID whose counts were taken back to nothing
+func (pg *postgres) DeleteEmptyStats(ctx context.Context, tx pgx.Tx, userID string) error {
+
+	_, err := tx.Exec(ctx, `DELETE FROM user_daily_stat WHERE user_id=$1 AND messages_sent <= 0`, userID)
+	if err != nil {
+		return fmt.Errorf("unable to delete user_daily_stat rows: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM user_word_use WHERE user_id=$1 AND uses <= 0`, userID)
+	if err != nil {
+		return fmt.Errorf("unable to delete user_word_use rows: %w", err)
+	}
+
+	return nil
+}
+
+// AdvanceStatsCursor moves the stats cursor of userID past the last counted message
+func (pg *postgres) AdvanceStatsCursor(ctx context.Context, tx pgx.Tx, userID string, last models.Message) error {
+
+	_, err := tx.Exec(ctx, `UPDATE user_stats_cursor SET last_seq=$1 WHERE user_id=$2`, last.Seq, userID)
+	if err != nil {
+		return fmt.Errorf("unable to update user_stats_cursor row: %w", err)
+	}
+
+	return nil
+}
+
+// IsMessageCounted reports whether the stats cursor passed messageID
+func (pg *postgres) IsMessageCounted(ctx context.Context, tx pgx.Tx, cursor models.StatsCursor, messageID string) (bool, error) {
+
+	if cursor.LastSeq == nil {
+		return false, nil
+	}
+
+	var counted bool
+	err := tx.QueryRow(ctx, `SELECT seq <= $1 FROM message WHERE id=$2`, *cursor.LastSeq, messageID).Scan(&counted)
+	if err != nil {
+		return false, fmt.Errorf("unable to scan message seq: %w", err)
+	}
+
+	return counted, nil
+}
+
+// GetStatsTimezone returns the timezone the stats of userID were last counted in, empty when never counted
+func (pg *postgres) GetStatsTimezone(ctx context.Context, userID string) (string, error) {
+
+	var timezone string
+	err := pg.db.QueryRow(ctx, `SELECT timezone FROM user_stats_cursor WHERE user_id=$1`, userID).Scan(&timezone)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", nil
+		}
+		return "", fmt.Errorf("unable to scan user_stats_cursor timezone: %w", err)
+	}
+
+	return timezone, nil
+}
+
+// GetDailyStats returns every counted day of userID, newest first
+func (pg *postgres) GetDailyStats(ctx context.Context, userID string) ([]models.DailyStat, error) {
+
+	dailyStatsQuery := `SELECT user_id, day, lang_code, messages_sent, words_written
+		FROM user_daily_stat
+		WHERE user_id = $1
+		ORDER BY day DESC, lang_code`
+
+	rows, err := pg.db.Query(ctx, dailyStatsQuery, userID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query daily stats: %w", err)
+	}
+	defer rows.Close()
+
+	var dailyStats []models.DailyStat
+	for rows.Next() {
+		var dailyStat models.DailyStat
+		err := rows.Scan(&dailyStat.UserID, &dailyStat.Day, &dailyStat.LangCode, &dailyStat.MessagesSent, &dailyStat.WordsWritten)
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan row of daily stats: %w", err)
+		}
+		dailyStats = append(dailyStats, dailyStat)
+	}
+
+	return dailyStats, nil
+}
+
+// GetWordsUsed counts the distinct words userID has written per lang_code
+func (pg *postgres) GetWordsUsed(ctx context.Context, userID string) (map[string]int, error) {
+	return pg.countByLangCode(ctx, `SELECT lang_code, COUNT(*) FROM user_word_use WHERE user_id = $1 GROUP BY lang_code`, userID)
+}
+
+// GetCorrectionsReceived counts the corrections other participants made to messages of userID per lang_code
+func (pg *postgres) GetCorrectionsReceived(ctx context.Context, userID string) (map[string]int, error) {
+	return pg.countByLangCode(ctx, `SELECT btrim(m.lang_code, '{}'), COUNT(*)
+		FROM message_correction mc
+		JOIN message m ON mc.message_id = m.id
+		WHERE m.sender_id = $1
+		GROUP BY btrim(m.lang_code, '{}')`, userID)
+}
+
+// countByLangCode scans lang_code and count rows of query into a map
+func (pg *postgres) countByLangCode(ctx context.Context, query string, args ...interface{}) (map[string]int, error) {
+
+	rows, err := pg.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query counts by lang code: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var langCode string
+		var count int
+		err := rows.Scan(&langCode, &count)
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan row of counts by lang code: %w", err)
+		}
+		counts[langCode] = count
+	}
+
+	return counts, nil
+}
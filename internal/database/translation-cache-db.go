@@ -0,0 +1,114 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// GetCachedTranslation returns the cached translation stored under key
+func (pg *postgres) GetCachedTranslation(ctx context.Context, key string) (models.TranslationCacheEntry, error) {
+
+	cachedTranslationQuery := `SELECT key, source_hash, source_lang, target_lang, provider, scope, content, hits, created_at, last_used_at
+		FROM translation_cache
+		WHERE key = $1`
+
+	var entry models.TranslationCacheEntry
+	err := pg.db.QueryRow(ctx, cachedTranslationQuery, key).Scan(&entry.Key, &entry.SourceHash, &entry.SourceLang,
+		&entry.TargetLang, &entry.Provider, &entry.Scope, &entry.Content, &entry.Hits, &entry.CreatedAt, &entry.LastUsedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return models.TranslationCacheEntry{}, err
+		}
+		return models.TranslationCacheEntry{}, fmt.Errorf("unable to scan translation_cache row: %w", err)
+	}
+
+	return entry, nil
+}
+
+// AddCachedTranslationHits counts hits[key] more hits of every cached translation in hits, used last at usedAt
+func (pg *postgres) AddCachedTranslationHits(ctx context.Context, hits map[string]int64, usedAt time.Time) error {
+
+	if len(hits) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(hits))
+	counts := make([]int64, 0, len(hits))
+	for key, count := range hits {
+		keys = append(keys, key)
+		counts = append(counts, count)
+	}
+
+	addHitsQuery := `UPDATE translation_cache c
+		SET hits = c.hits + h.hits, last_used_at = GREATEST(c.last_used_at, $3)
+		FROM unnest($1::TEXT[], $2::BIGINT[]) AS h(key, hits)
+		WHERE c.key = h.key`
+
+	_, err := pg.db.Exec(ctx, addHitsQuery, keys, counts, usedAt)
+	if err != nil {
+		return fmt.Errorf("unable to update translation_cache hits: %w", err)
+	}
+
+	return nil
+}
+
+// PutCachedTranslation stores a translation under entry.Key, replacing what was cached there
+func (pg *postgres) PutCachedTranslation(ctx context.Context, entry *models.TranslationCacheEntry) error {
+
+	entry.CreatedAt = time.Now().UTC()
+	entry.LastUsedAt = entry.CreatedAt
+
+	putCachedTranslationQuery := `INSERT INTO translation_cache (key, source_hash, source_lang, target_lang, provider, scope, content, hits, created_at, last_used_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, 0, $8, $9)
+		ON CONFLICT (key) DO UPDATE
+		SET content=EXCLUDED.content, created_at=EXCLUDED.created_at, last_used_at=EXCLUDED.last_used_at`
+
+	_, err := pg.db.Exec(ctx, putCachedTranslationQuery, entry.Key, entry.SourceHash, entry.SourceLang, entry.TargetLang,
+		entry.Provider, entry.Scope, entry.Content, entry.CreatedAt, entry.LastUsedAt)
+	if err != nil {
+		return fmt.Errorf("unable to upsert translation_cache row: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteCachedTranslations drops the cached translations of provider in scope,
+// an empty provider matches every provider
+func (pg *postgres) DeleteCachedTranslations(ctx context.Context, provider string, scope string) (int64, error) {
+
+	deleteCachedTranslationsQuery := `DELETE FROM translation_cache
+		WHERE ($1 = '' OR provider = $1) AND scope = $2`
+
+	tag, err := pg.db.Exec(ctx, deleteCachedTranslationsQuery, provider, scope)
+	if err != nil {
+		return 0, fmt.Errorf("unable to delete translation_cache rows: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// DeleteCachedTranslationsOfSources drops the cached translations of the source texts hashed in sourceHashes
+func (pg *postgres) DeleteCachedTranslationsOfSources(ctx context.Context, sourceHashes []string) (int64, error) {
+
+	tag, err := pg.db.Exec(ctx, `DELETE FROM translation_cache WHERE source_hash = ANY($1::TEXT[])`, sourceHashes)
+	if err != nil {
+		return 0, fmt.Errorf("unable to delete translation_cache rows: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// DeleteStaleCachedTranslations drops the cached translations not used since unusedSince
+func (pg *postgres) DeleteStaleCachedTranslations(ctx context.Context, unusedSince time.Time) (int64, error) {
+
+	tag, err := pg.db.Exec(ctx, `DELETE FROM translation_cache WHERE last_used_at < $1`, unusedSince)
+	if err != nil {
+		return 0, fmt.Errorf("unable to delete translation_cache rows: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
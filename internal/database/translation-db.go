@@ -0,0 +1,181 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// GetTranslation returns the translation of messageID into the bare langCode
+func (pg *postgres) GetTranslation(ctx context.Context, messageID string, langCode string) (models.Translation, error) {
+
+	translationQuery := `SELECT id, message_id, content, btrim(lang_code, '{}'), provider, created_at
+		FROM translation
+		WHERE message_id = $1 AND lang_code = '{' || $2::text || '}'`
+
+	var translation models.Translation
+	err := pg.db.QueryRow(ctx, translationQuery, messageID, langCode).Scan(&translation.ID, &translation.MessageID,
+		&translation.Content, &translation.LangCode, &translation.Provider, &translation.CreatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return models.Translation{}, err
+		}
+		return models.Translation{}, fmt.Errorf("unable to scan translation row: %w", err)
+	}
+
+	return translation, nil
+}
+
+// GetMessageTranslations returns the translations of messageID keyed by bare lang_code
+func (pg *postgres) GetMessageTranslations(ctx context.Context, messageID string) (map[string]string, error) {
+
+	rows, err := pg.db.Query(ctx, `SELECT btrim(lang_code, '{}'), content FROM translation WHERE message_id = $1`, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query message translations: %w", err)
+	}
+	defer rows.Close()
+
+	translations := make(map[string]string)
+	for rows.Next() {
+		var langCode, content string
+		err := rows.Scan(&langCode, &content)
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan row of message translations: %w", err)
+		}
+		translations[langCode] = content
+	}
+
+	return translations, nil
+}
+
+// SaveTranslation replaces the translation of a message into translation.LangCode,
+// translations into other languages are kept
+func (pg *postgres) SaveTranslation(ctx context.Context, translation *models.Translation) (models.Translation, error) {
+
+	tx, err := pg.db.Begin(ctx)
+	if err != nil {
+		return models.Translation{}, fmt.Errorf("unable to begin translation transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `DELETE FROM translation WHERE message_id=$1 AND lang_code='{' || $2::text || '}'`, translation.MessageID.String(), translation.LangCode)
+	if err != nil {
+		return models.Translation{}, fmt.Errorf("unable to delete translation row: %w", err)
+	}
+
+	translationUUID, err := uuid.NewV4()
+	if err != nil {
+		return models.Translation{}, fmt.Errorf("unable to generate uuid %w", err)
+	}
+
+	translation.ID = translationUUID
+	translation.CreatedAt = time.Now().UTC()
+
+	insertTranslationQuery := `INSERT INTO translation (id, message_id, content, lang_code, provider, created_at)
+		VALUES ($1::UUID, $2::UUID, $3, '{' || $4::text || '}', $5, $6)`
+
+	_, err = tx.Exec(ctx, insertTranslationQuery, translation.ID.String(), translation.MessageID.String(), translation.Content,
+		translation.LangCode, translation.Provider, translation.CreatedAt)
+	if err != nil {
+		return models.Translation{}, fmt.Errorf("unable to insert translation row: %w", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return models.Translation{}, fmt.Errorf("unable to commit translation transaction: %w", err)
+	}
+
+	return *translation, nil
+}
+
+// SaveTranslationFeedback records the rating of userID for the current translation of a message
+// of chatID into the bare langCode, a later rating of the same translation replaces it.
+// pgx.ErrNoRows is returned when the message has no such translation
+func (pg *postgres) SaveTranslationFeedback(ctx context.Context, chatID string, messageID string, langCode string, userID string, rating int) (models.TranslationFeedback, error) {
+
+	feedbackUUID, err := uuid.NewV4()
+	if err != nil {
+		return models.TranslationFeedback{}, fmt.Errorf("unable to generate uuid %w", err)
+	}
+
+	now := time.Now().UTC()
+
+	saveFeedbackQuery := `INSERT INTO translation_feedback (id, translation_id, message_id, user_id, provider, source_lang_code, target_lang_code, rating, created_at, updated_at)
+		SELECT $1::UUID, t.id, m.id, $2, t.provider, btrim(m.lang_code, '{}'), btrim(t.lang_code, '{}'), $3, $4, $4
+		FROM translation t
+		JOIN message m ON t.message_id = m.id
+		WHERE m.id = $5 AND m.chat_id = $6 AND m.deleted_at IS NULL AND t.lang_code = '{' || $7::text || '}'
+		ON CONFLICT (translation_id, user_id) DO UPDATE SET rating = EXCLUDED.rating, updated_at = EXCLUDED.updated_at
+		RETURNING id, translation_id, message_id, user_id, provider, source_lang_code, target_lang_code, rating, created_at, updated_at`
+
+	var feedback models.TranslationFeedback
+	err = pg.db.QueryRow(ctx, saveFeedbackQuery, feedbackUUID.String(), userID, rating, now, messageID, chatID, langCode).Scan(
+		&feedback.ID, &feedback.TranslationID, &feedback.MessageID, &feedback.UserID, &feedback.Provider,
+		&feedback.SourceLangCode, &feedback.TargetLangCode, &feedback.Rating, &feedback.CreatedAt, &feedback.UpdatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return models.TranslationFeedback{}, err
+		}
+		return models.TranslationFeedback{}, fmt.Errorf("unable to upsert translation_feedback row: %w", err)
+	}
+
+	return feedback, nil
+}
+
+// DeleteTranslationFeedback withdraws the rating of userID for the current translation of a message into langCode
+func (pg *postgres) DeleteTranslationFeedback(ctx context.Context, chatID string, messageID string, langCode string, userID string) error {
+
+	deleteFeedbackQuery := `DELETE FROM translation_feedback f
+		USING translation t, message m
+		WHERE f.translation_id = t.id AND t.message_id = m.id
+			AND m.id = $1 AND m.chat_id = $2 AND t.lang_code = '{' || $3::text || '}' AND f.user_id = $4`
+
+	tag, err := pg.db.Exec(ctx, deleteFeedbackQuery, messageID, chatID, langCode, userID)
+	if err != nil {
+		return fmt.Errorf("unable to delete translation_feedback row: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// GetTranslationFeedbackStats returns the ratings given since since grouped by provider and
+// language pair, the worst approval first
+func (pg *postgres) GetTranslationFeedbackStats(ctx context.Context, since time.Time) ([]models.TranslationFeedbackStats, error) {
+
+	feedbackStatsQuery := `SELECT provider, source_lang_code, target_lang_code,
+		COUNT(*) FILTER (WHERE rating > 0) AS up,
+		COUNT(*) FILTER (WHERE rating < 0) AS down
+		FROM translation_feedback
+		WHERE updated_at >= $1
+		GROUP BY provider, source_lang_code, target_lang_code
+		ORDER BY COUNT(*) FILTER (WHERE rating > 0)::float / COUNT(*), provider, source_lang_code, target_lang_code`
+
+	rows, err := pg.db.Query(ctx, feedbackStatsQuery, since)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query translation feedback stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := []models.TranslationFeedbackStats{}
+	for rows.Next() {
+		var stat models.TranslationFeedbackStats
+		err := rows.Scan(&stat.Provider, &stat.SourceLangCode, &stat.TargetLangCode, &stat.Up, &stat.Down)
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan row of translation feedback stats: %w", err)
+		}
+		if total := stat.Up + stat.Down; total > 0 {
+			stat.Approval = float64(stat.Up) / float64(total)
+		}
+		stats = append(stats, stat)
+	}
+
+	return stats, nil
+}
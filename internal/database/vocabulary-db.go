@@ -0,0 +1,143 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrVocabularyExists = errors.New("term already saved in vocabulary")
+
+const vocabularyColumns = `id, user_id, lang_code, term, translation, translation_lang_code, context, message_id, tags, created_at, updated_at`
+
+// scanVocabularyItem scans a row selected with vocabularyColumns
+func scanVocabularyItem(row pgx.Row) (models.VocabularyItem, error) {
+	var item models.VocabularyItem
+	err := row.Scan(&item.ID, &item.UserID, &item.LangCode, &item.Term, &item.Translation, &item.TranslationLangCode,
+		&item.Context, &item.MessageID, &item.Tags, &item.CreatedAt, &item.UpdatedAt)
+	return item, err
+}
+
+// CreateVocabularyItem saves a new vocabulary item, ErrVocabularyExists is returned
+// when the user already saved the term in the same language
+func (pg *postgres) CreateVocabularyItem(ctx context.Context, item *models.VocabularyItem) (models.VocabularyItem, error) {
+
+	itemUUID, err := uuid.NewV4()
+	if err != nil {
+		return models.VocabularyItem{}, fmt.Errorf("unable to generate uuid %w", err)
+	}
+
+	item.ID = itemUUID
+	item.CreatedAt = time.Now().UTC()
+	item.UpdatedAt = item.CreatedAt
+
+	var messageID *string
+	if item.MessageID.Valid {
+		messageIDStr := item.MessageID.UUID.String()
+		messageID = &messageIDStr
+	}
+
+	insertItemQuery := `INSERT INTO vocabulary_item (` + vocabularyColumns + `)
+		VALUES ($1::UUID, $2, $3, $4, $5, $6, $7, $8::UUID, $9, $10, $11)
+		ON CONFLICT DO NOTHING
+		RETURNING ` + vocabularyColumns
+
+	created, err := scanVocabularyItem(pg.db.QueryRow(ctx, insertItemQuery,
+		item.ID.String(), item.UserID, item.LangCode, item.Term, item.Translation, item.TranslationLangCode,
+		item.Context, messageID, item.Tags, item.CreatedAt, item.UpdatedAt))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return models.VocabularyItem{}, ErrVocabularyExists
+		}
+		return models.VocabularyItem{}, fmt.Errorf("unable to insert vocabulary_item row: %w", err)
+	}
+
+	return created, nil
+}
+
+// GetVocabulary returns the vocabulary of userID newest first, langCode and tag
+// narrow the list down when they are not empty
+func (pg *postgres) GetVocabulary(ctx context.Context, userID string, langCode string, tag string) ([]models.VocabularyItem, error) {
+
+	vocabularyQuery := `SELECT ` + vocabularyColumns + `
+		FROM vocabulary_item
+		WHERE user_id = $1
+			AND ($2 = '' OR lang_code = $2)
+			AND ($3 = '' OR $3 = ANY(tags))
+		ORDER BY created_at DESC`
+
+	rows, err := pg.db.Query(ctx, vocabularyQuery, userID, langCode, tag)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query vocabulary: %w", err)
+	}
+	defer rows.Close()
+
+	items := []models.VocabularyItem{}
+	for rows.Next() {
+		item, err := scanVocabularyItem(rows)
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan row of vocabulary: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// GetVocabularyItem returns a single vocabulary item of userID
+func (pg *postgres) GetVocabularyItem(ctx context.Context, userID string, itemID string) (models.VocabularyItem, error) {
+
+	itemQuery := `SELECT ` + vocabularyColumns + `
+		FROM vocabulary_item
+		WHERE id = $1 AND user_id = $2`
+
+	item, err := scanVocabularyItem(pg.db.QueryRow(ctx, itemQuery, itemID, userID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return models.VocabularyItem{}, err
+		}
+		return models.VocabularyItem{}, fmt.Errorf("unable to scan vocabulary_item row: %w", err)
+	}
+
+	return item, nil
+}
+
+// UpdateVocabularyItem replaces the translation, context and tags of a vocabulary item of userID
+func (pg *postgres) UpdateVocabularyItem(ctx context.Context, item *models.VocabularyItem) (models.VocabularyItem, error) {
+
+	updateItemQuery := `UPDATE vocabulary_item
+		SET translation=$1, context=$2, tags=$3, updated_at=$4
+		WHERE id=$5 AND user_id=$6
+		RETURNING ` + vocabularyColumns
+
+	updated, err := scanVocabularyItem(pg.db.QueryRow(ctx, updateItemQuery,
+		item.Translation, item.Context, item.Tags, time.Now().UTC(), item.ID.String(), item.UserID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return models.VocabularyItem{}, err
+		}
+		return models.VocabularyItem{}, fmt.Errorf("unable to update vocabulary_item row: %w", err)
+	}
+
+	return updated, nil
+}
+
+// DeleteVocabularyItem removes a vocabulary item of userID
+func (pg *postgres) DeleteVocabularyItem(ctx context.Context, userID string, itemID string) error {
+
+	tag, err := pg.db.Exec(ctx, `DELETE FROM vocabulary_item WHERE id=$1 AND user_id=$2`, itemID, userID)
+	if err != nil {
+		return fmt.Errorf("unable to delete vocabulary_item row: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
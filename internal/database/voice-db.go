@@ -0,0 +1,238 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const voiceColumns = `v.message_id, v.blob_key, v.content_type, v.size_bytes, v.duration_ms, v.transcript_status, v.transcribed_at`
+
+// scanVoice scans a row selected with voiceColumns
+func scanVoice(row pgx.Row) (uuid.UUID, models.VoiceAttachment, error) {
+	var messageID uuid.UUID
+	var voice models.VoiceAttachment
+	err := row.Scan(&messageID, &voice.BlobKey, &voice.ContentType, &voice.SizeBytes, &voice.DurationMs, &voice.TranscriptStatus, &voice.TranscribedAt)
+	return messageID, voice, err
+}
+
+// insertVoiceMessage stores the recording of a message created in tx
+func insertVoiceMessage(ctx context.Context, tx pgx.Tx, messageID string, voice *models.VoiceAttachment, createdAt time.Time) error {
+
+	voice.TranscriptStatus = models.TranscriptPending
+
+	insertVoiceQuery := `INSERT INTO voice_message (message_id, blob_key, content_type, size_bytes, duration_ms, transcript_status, created_at)
+		VALUES ($1::UUID, $2, $3, $4, $5, $6, $7)`
+
+	_, err := tx.Exec(ctx, insertVoiceQuery, messageID, voice.BlobKey, voice.ContentType, voice.SizeBytes, voice.DurationMs, voice.TranscriptStatus, createdAt)
+	if err != nil {
+		return fmt.Errorf("unable to insert voice_message row: %w", err)
+	}
+
+	return nil
+}
+
+// GetVoiceAttachment returns the recording of a voice message of chatID,
+// ErrMessageDeleted once the message has been deleted for everyone
+func (pg *postgres) GetVoiceAttachment(ctx context.Context, chatID string, messageID string) (models.VoiceAttachment, error) {
+
+	voiceQuery := `SELECT v.blob_key, v.content_type, v.size_bytes, v.duration_ms, v.transcript_status, v.transcribed_at,
+		m.deleted_at IS NOT NULL
+		FROM voice_message v
+		JOIN message m ON v.message_id = m.id
+		WHERE v.message_id = $1 AND m.chat_id = $2`
+
+	var voice models.VoiceAttachment
+	var deleted bool
+	err := pg.db.QueryRow(ctx, voiceQuery, messageID, chatID).Scan(&voice.BlobKey, &voice.ContentType, &voice.SizeBytes, &voice.DurationMs,
+		&voice.TranscriptStatus, &voice.TranscribedAt, &deleted)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return models.VoiceAttachment{}, err
+		}
+		return models.VoiceAttachment{}, fmt.Errorf("unable to scan voice_message row: %w", err)
+	}
+
+	if deleted {
+		return models.VoiceAttachment{}, ErrMessageDeleted
+	}
+
+	return voice, nil
+}
+
+// SetVoiceTranscript makes the transcript in msg the content of its voice message. A message
+// the sender edited before transcription finished keeps the edited content
+func (pg *postgres) SetVoiceTranscript(ctx context.Context, msg *models.MessageResponse) error {
+
+	tx, err := pg.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to begin transcript transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var edited bool
+	err = tx.QueryRow(ctx, `SELECT m.edited_at IS NOT NULL
+		FROM message m
+		JOIN voice_message v ON v.message_id = m.id
+		WHERE m.id = $1
+		FOR UPDATE OF m, v`, msg.ID.String()).Scan(&edited)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return err
+		}
+		return fmt.Errorf("unable to scan voice message row: %w", err)
+	}
+
+	if !edited {
+		detectedLangCode, langConfidence := detectionValues(msg)
+
+		updateMessageQuery := `UPDATE message SET content=$1, lang_code=$2, detected_lang_code=$3, lang_confidence=$4 WHERE id=$5`
+
+		_, err = tx.Exec(ctx, updateMessageQuery, msg.Content, msg.LangCode, detectedLangCode, langConfidence, msg.ID.String())
+		if err != nil {
+			return fmt.Errorf("unable to update message row: %w", err)
+		}
+	}
+
+	transcribedAt := time.Now().UTC()
+
+	_, err = tx.Exec(ctx, `UPDATE voice_message SET transcript_status=$1, transcript_error=NULL, transcribed_at=$2 WHERE message_id=$3`,
+		models.TranscriptDone, transcribedAt, msg.ID.String())
+	if err != nil {
+		return fmt.Errorf("unable to update voice_message row: %w", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to commit transcript transaction: %w", err)
+	}
+
+	if msg.Voice != nil {
+		msg.Voice.TranscriptStatus = models.TranscriptDone
+		msg.Voice.TranscribedAt = &transcribedAt
+	}
+
+	return nil
+}
+
+// FailVoiceTranscript records that the recording of messageID could not be transcribed,
+// the message stays a voice message without text
+func (pg *postgres) FailVoiceTranscript(ctx context.Context, messageID string, errStr string) error {
+
+	_, err := pg.db.Exec(ctx, `UPDATE voice_message SET transcript_status=$1, transcript_error=$2 WHERE message_id=$3`,
+		models.TranscriptFailed, errStr, messageID)
+	if err != nil {
+		return fmt.Errorf("unable to update voice_message row: %w", err)
+	}
+
+	return nil
+}
+
+// attachVoice sets the Voice of every voice message in messages
+func (pg *postgres) attachVoice(ctx context.Context, messages []models.MessageResponse) error {
+
+	if len(messages) == 0 {
+		return nil
+	}
+
+	messageIDStrs := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		messageIDStrs = append(messageIDStrs, msg.ID.String())
+	}
+
+	voiceQuery := `SELECT ` + voiceColumns + `
+		FROM voice_message v
+		WHERE v.message_id = ANY($1::UUID[])`
+
+	rows, err := pg.db.Query(ctx, voiceQuery, messageIDStrs)
+	if err != nil {
+		return fmt.Errorf("unable to query voice messages: %w", err)
+	}
+	defer rows.Close()
+
+	voices := make(map[uuid.UUID]*models.VoiceAttachment)
+	for rows.Next() {
+		messageID, voice, err := scanVoice(rows)
+		if err != nil {
+			return fmt.Errorf("unable to scan row of voice messages: %w", err)
+		}
+		voices[messageID] = &voice
+	}
+
+	for i := range messages {
+		messages[i].Voice = voices[messages[i].ID]
+	}
+
+	return nil
+}
+
+// TouchCachedSpeech records that the speech cached under blobKey was used at usedAt,
+// last_used_at is only moved forward once an hour so repeated plays do not write every time
+func (pg *postgres) TouchCachedSpeech(ctx context.Context, blobKey string, sizeBytes int64, usedAt time.Time) error {
+
+	touchSpeechQuery := `INSERT INTO speech_cache (blob_key, size_bytes, created_at, last_used_at)
+		VALUES ($1, $2, $3, $3)
+		ON CONFLICT (blob_key) DO UPDATE
+		SET size_bytes=EXCLUDED.size_bytes, last_used_at=EXCLUDED.last_used_at
+		WHERE speech_cache.last_used_at < EXCLUDED.last_used_at - INTERVAL '1 hour'`
+
+	_, err := pg.db.Exec(ctx, touchSpeechQuery, blobKey, sizeBytes, usedAt)
+	if err != nil {
+		return fmt.Errorf("unable to upsert speech_cache row: %w", err)
+	}
+
+	return nil
+}
+
+// GetStaleCachedSpeech returns the blob keys of at most limit cached speech unused since unusedSince,
+// least recently used first
+func (pg *postgres) GetStaleCachedSpeech(ctx context.Context, unusedSince time.Time, limit int) ([]string, error) {
+
+	staleSpeechQuery := `SELECT blob_key FROM speech_cache
+		WHERE last_used_at < $1
+		ORDER BY last_used_at
+		LIMIT $2`
+
+	rows, err := pg.db.Query(ctx, staleSpeechQuery, unusedSince, limit)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query stale speech: %w", err)
+	}
+	defer rows.Close()
+
+	var blobKeys []string
+	for rows.Next() {
+		var blobKey string
+		if err := rows.Scan(&blobKey); err != nil {
+			return nil, fmt.Errorf("unable to scan row of stale speech: %w", err)
+		}
+		blobKeys = append(blobKeys, blobKey)
+	}
+
+	return blobKeys, rows.Err()
+}
+
+// DeleteCachedSpeech forgets the speech cached under blobKey unless it was used again since unusedSince
+func (pg *postgres) DeleteCachedSpeech(ctx context.Context, blobKey string, unusedSince time.Time) (bool, error) {
+
+	tag, err := pg.db.Exec(ctx, `DELETE FROM speech_cache WHERE blob_key = $1 AND last_used_at < $2`, blobKey, unusedSince)
+	if err != nil {
+		return false, fmt.Errorf("unable to delete speech_cache row: %w", err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// DeleteCachedSpeechKeys drops the speech_cache rows of blobKeys however recently they were used
+func (pg *postgres) DeleteCachedSpeechKeys(ctx context.Context, blobKeys []string) (int64, error) {
+
+	tag, err := pg.db.Exec(ctx, `DELETE FROM speech_cache WHERE blob_key = ANY($1::TEXT[])`, blobKeys)
+	if err != nil {
+		return 0, fmt.Errorf("unable to delete speech_cache rows: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,260 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/export/sqlite"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+)
+
+// the note type is shared by every exported deck so repeated imports reuse it
+const (
+	ankiModelID   = 1718300000000
+	ankiModelName = "LinguaChat"
+	ankiSchemaVer = 11
+
+	// separates the fields of a note
+	ankiFieldSeparator = "\x1f"
+)
+
+// schema of an Anki collection.anki2 file, version 11
+var ankiTables = []sqlite.Table{
+	{Name: "col", SQL: `CREATE TABLE col (id integer primary key, crt integer not null, mod integer not null, scm integer not null, ver integer not null, dty integer not null, usn integer not null, ls integer not null, conf text not null, models text not null, decks text not null, dconf text not null, tags text not null)`},
+	{Name: "notes", SQL: `CREATE TABLE notes (id integer primary key, guid text not null, mid integer not null, mod integer not null, usn integer not null, tags text not null, flds text not null, sfld integer not null, csum integer not null, flags integer not null, data text not null)`},
+	{Name: "cards", SQL: `CREATE TABLE cards (id integer primary key, nid integer not null, did integer not null, ord integer not null, mod integer not null, usn integer not null, type integer not null, queue integer not null, due integer not null, ivl integer not null, factor integer not null, reps integer not null, lapses integer not null, left integer not null, odue integer not null, odid integer not null, flags integer not null, data text not null)`},
+	{Name: "revlog", SQL: `CREATE TABLE revlog (id integer primary key, cid integer not null, usn integer not null, ease integer not null, ivl integer not null, lastIvl integer not null, factor integer not null, time integer not null, type integer not null)`},
+	{Name: "graves", SQL: `CREATE TABLE graves (usn integer not null, oid integer not null, type integer not null)`},
+}
+
+// WriteAnkiPackage writes cards as an Anki .apkg package holding a single deck named deckName
+func WriteAnkiPackage(w io.Writer, deckName string, cards []Card) error {
+
+	now := time.Now().UTC()
+	deckID := ankiDeckID(deckName)
+
+	colJSON, err := ankiCollectionJSON(deckID, deckName, now)
+	if err != nil {
+		return err
+	}
+
+	tables := make([]sqlite.Table, len(ankiTables))
+	copy(tables, ankiTables)
+
+	tables[0].Rows = []sqlite.Row{{RowID: 1, Values: []interface{}{
+		nil, now.Unix(), now.UnixMilli(), now.UnixMilli(), ankiSchemaVer, 0, 0, 0,
+		colJSON.conf, colJSON.models, colJSON.decks, colJSON.dconf, "{}",
+	}}}
+
+	// note and card ids are millisecond timestamps in Anki, kept unique by counting up
+	usedIDs := make(map[int64]bool)
+	uniqueID := func(t time.Time) int64 {
+		id := t.UnixMilli()
+		for usedIDs[id] {
+			id++
+		}
+		usedIDs[id] = true
+		return id
+	}
+
+	for i, card := range cards {
+		noteID := uniqueID(card.CreatedAt)
+		cardID := uniqueID(card.CreatedAt)
+
+		fields := []string{ankiText(card.Front), ankiText(card.Back), ankiContext(card)}
+
+		tables[1].Rows = append(tables[1].Rows, sqlite.Row{RowID: noteID, Values: []interface{}{
+			nil, ankiGUID(card.Key), int64(ankiModelID), now.Unix(), -1, ankiTags(card.Tags),
+			strings.Join(fields, ankiFieldSeparator), card.Front, ankiChecksum(card.Front), 0, "",
+		}})
+
+		// new cards, due in export order
+		tables[2].Rows = append(tables[2].Rows, sqlite.Row{RowID: cardID, Values: []interface{}{
+			nil, noteID, deckID, 0, now.Unix(), -1, 0, 0, int64(i + 1), 0, 0, 0, 0, 0, 0, 0, 0, "",
+		}})
+	}
+
+	var collection bytes.Buffer
+	err = sqlite.Write(&collection, tables)
+	if err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(w)
+
+	collectionFile, err := zw.Create("collection.anki2")
+	if err != nil {
+		return err
+	}
+	_, err = collectionFile.Write(collection.Bytes())
+	if err != nil {
+		return err
+	}
+
+	mediaFile, err := zw.Create("media")
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(mediaFile, "{}")
+	if err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+type ankiCollection struct {
+	conf, models, decks, dconf string
+}
+
+// ankiCollectionJSON builds the JSON columns of the col row
+func ankiCollectionJSON(deckID int64, deckName string, now time.Time) (ankiCollection, error) {
+
+	modelIDStr := strconv.FormatInt(ankiModelID, 10)
+	deckIDStr := strconv.FormatInt(deckID, 10)
+
+	conf := map[string]interface{}{
+		"nextPos": 1, "estTimes": true, "activeDecks": []int64{deckID}, "sortType": "noteFld",
+		"timeLim": 0, "sortBackwards": false, "addToCur": true, "curDeck": deckID,
+		"newSpread": 0, "dueCounts": true, "curModel": modelIDStr, "collapseTime": 1200,
+	}
+
+	field := func(name string, ord int) map[string]interface{} {
+		return map[string]interface{}{
+			"name": name, "ord": ord, "sticky": false, "rtl": false, "font": "Arial", "size": 20, "media": []string{},
+		}
+	}
+
+	model := map[string]interface{}{
+		"id": ankiModelID, "name": ankiModelName, "type": 0, "mod": now.Unix(), "usn": -1,
+		"sortf": 0, "did": deckID, "tags": []string{}, "vers": []int{},
+		"flds": []interface{}{field("Front", 0), field("Back", 1), field("Context", 2)},
+		"tmpls": []interface{}{map[string]interface{}{
+			"name": "Card 1", "ord": 0, "did": nil, "bqfmt": "", "bafmt": "",
+			"qfmt": "{{Front}}",
+			"afmt": "{{FrontSide}}<hr id=answer>{{Back}}{{#Context}}<br><br><small>{{Context}}</small>{{/Context}}",
+		}},
+		"css":       ".card { font-family: arial; font-size: 20px; text-align: center; color: black; background-color: white; } del { color: #c0392b; } ins { color: #27ae60; }",
+		"latexPre":  "\\documentclass[12pt]{article}\n\\special{papersize=3in,5in}\n\\usepackage[utf8]{inputenc}\n\\usepackage{amssymb,amsmath}\n\\pagestyle{empty}\n\\setlength{\\parindent}{0in}\n\\begin{document}\n",
+		"latexPost": "\\end{document}",
+		"req":       []interface{}{[]interface{}{0, "any", []int{0}}},
+	}
+
+	deck := func(id int64, name string) map[string]interface{} {
+		return map[string]interface{}{
+			"id": id, "name": name, "mod": now.Unix(), "usn": -1, "desc": "", "dyn": 0, "conf": 1,
+			"collapsed": false, "extendNew": 10, "extendRev": 50,
+			"newToday": []int{0, 0}, "revToday": []int{0, 0}, "lrnToday": []int{0, 0}, "timeToday": []int{0, 0},
+		}
+	}
+
+	decks := map[string]interface{}{
+		"1":       deck(1, "Default"),
+		deckIDStr: deck(deckID, deckName),
+	}
+
+	dconf := map[string]interface{}{
+		"1": map[string]interface{}{
+			"id": 1, "name": "Default", "mod": 0, "usn": 0, "maxTaken": 60, "autoplay": true,
+			"timer": 0, "replayq": true, "dyn": false,
+			"new": map[string]interface{}{
+				"delays": []float64{1, 10}, "ints": []int{1, 4, 7}, "initialFactor": 2500,
+				"order": 1, "perDay": 20, "bury": true, "separate": true,
+			},
+			"rev": map[string]interface{}{
+				"perDay": 200, "ease4": 1.3, "fuzz": 0.05, "ivlFct": 1, "maxIvl": 36500,
+				"minSpace": 1, "bury": true,
+			},
+			"lapse": map[string]interface{}{
+				"delays": []float64{10}, "mult": 0, "minInt": 1, "leechFails": 8, "leechAction": 0,
+			},
+		},
+	}
+
+	var col ankiCollection
+	for _, column := range []struct {
+		value interface{}
+		dst   *string
+	}{
+		{conf, &col.conf},
+		{map[string]interface{}{modelIDStr: model}, &col.models},
+		{decks, &col.decks},
+		{dconf, &col.dconf},
+	} {
+		data, err := json.Marshal(column.value)
+		if err != nil {
+			return ankiCollection{}, err
+		}
+		*column.dst = string(data)
+	}
+
+	return col, nil
+}
+
+// ankiDeckID derives a stable deck id from its name so re-imports land in the same deck
+func ankiDeckID(deckName string) int64 {
+	sum := sha1.Sum([]byte("deck:" + deckName))
+	// ids must stay within the safe integer range of the JavaScript parts of Anki
+	return int64(binary.BigEndian.Uint64(sum[:8]) >> 12)
+}
+
+// ankiGUID derives the note guid from the card's source, Anki updates notes with a known guid on import
+func ankiGUID(key string) string {
+	sum := sha1.Sum([]byte(key))
+	return base64.RawStdEncoding.EncodeToString(sum[:])[:10]
+}
+
+// ankiChecksum is the first 8 hex digits of the sha1 of the sort field, used by Anki to find duplicates
+func ankiChecksum(field string) int64 {
+	sum := sha1.Sum([]byte(field))
+	checksum, _ := strconv.ParseInt(hex.EncodeToString(sum[:4]), 16, 64)
+	return checksum
+}
+
+// ankiTags joins tags the way notes store them, space separated and padded
+func ankiTags(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	escaped := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		escaped = append(escaped, strings.ReplaceAll(tag, " ", "_"))
+	}
+	return " " + strings.Join(escaped, " ") + " "
+}
+
+// ankiText escapes plain text for an Anki field, fields are HTML
+func ankiText(text string) string {
+	return strings.ReplaceAll(html.EscapeString(text), "\n", "<br>")
+}
+
+// ankiContext renders the context field, corrections show their diff with <del> and <ins>
+func ankiContext(card Card) string {
+
+	if len(card.Diff) == 0 {
+		return ankiText(card.Context)
+	}
+
+	var sb strings.Builder
+	for _, segment := range card.Diff {
+		switch segment.Op {
+		case models.DiffDelete:
+			sb.WriteString("<del>" + ankiText(segment.Text) + "</del>")
+		case models.DiffInsert:
+			sb.WriteString("<ins>" + ankiText(segment.Text) + "</ins>")
+		default:
+			sb.WriteString(ankiText(segment.Text))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,101 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+)
+
+// export formats of a vocabulary deck
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+	FormatTSV  = "tsv"
+	FormatAnki = "apkg"
+)
+
+// Card is a single flashcard of an export, built from a vocabulary item or an accepted correction
+type Card struct {
+	// Key identifies the source of the card so that re-imports update instead of duplicate it
+	Key       string
+	Front     string
+	Back      string
+	Context   string
+	Diff      []models.DiffSegment
+	Tags      []string
+	CreatedAt time.Time
+}
+
+// BuildCards turns saved vocabulary and accepted corrections into cards. Vocabulary cards ask
+// for the translation of a term, correction cards show what was written and the corrected text
+func BuildCards(items []models.VocabularyItem, corrections []models.CorrectionResponse) []Card {
+
+	cards := make([]Card, 0, len(items)+len(corrections))
+
+	for _, item := range items {
+		cards = append(cards, Card{
+			Key:       "vocabulary:" + item.ID.String(),
+			Front:     item.Term,
+			Back:      item.Translation,
+			Context:   item.Context,
+			Tags:      append([]string{"vocabulary"}, item.Tags...),
+			CreatedAt: item.CreatedAt,
+		})
+	}
+
+	for _, correction := range corrections {
+		cards = append(cards, Card{
+			Key:       "correction:" + correction.ID.String(),
+			Front:     correction.OriginalContent,
+			Back:      correction.Content,
+			Context:   DiffText(correction.Diff),
+			Diff:      correction.Diff,
+			Tags:      []string{"correction"},
+			CreatedAt: correction.CreatedAt,
+		})
+	}
+
+	return cards
+}
+
+// DiffText renders diff segments in wdiff notation, [-removed-]{+added+}
+func DiffText(diff []models.DiffSegment) string {
+	var sb strings.Builder
+	for _, segment := range diff {
+		switch segment.Op {
+		case models.DiffDelete:
+			sb.WriteString("[-" + segment.Text + "-]")
+		case models.DiffInsert:
+			sb.WriteString("{+" + segment.Text + "+}")
+		default:
+			sb.WriteString(segment.Text)
+		}
+	}
+	return sb.String()
+}
+
+// WriteDelimited writes cards as front, back, context and tags columns behind a header row,
+// comma is ',' for CSV and '\t' for TSV
+func WriteDelimited(w io.Writer, cards []Card, comma rune) error {
+
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+
+	err := cw.Write([]string{"front", "back", "context", "tags"})
+	if err != nil {
+		return err
+	}
+
+	for _, card := range cards {
+		err := cw.Write([]string{card.Front, card.Back, card.Context, strings.Join(card.Tags, " ")})
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,353 @@
+package sqlite
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// Writer only supports what exports need: tables written once, no indexes and no freelist
+const (
+	pageSize = 4096
+
+	// payload kept on a table leaf page before spilling to overflow pages
+	maxLocalPayload = pageSize - 35
+	minLocalPayload = (pageSize-12)*32/255 - 23
+
+	leafTablePage     = 0x0d
+	interiorTablePage = 0x05
+
+	// interior cells are a page number and a rowid varint
+	maxInteriorCellSize = 4 + 9
+)
+
+// Table is a table of the database file, SQL is its CREATE TABLE statement.
+// Values of an INTEGER PRIMARY KEY column must be nil, the RowID holds them
+type Table struct {
+	Name string
+	SQL  string
+	Rows []Row
+}
+
+// Row is a single record of a table, Values may hold nil, int, int64, float64, string and []byte
+type Row struct {
+	RowID  int64
+	Values []interface{}
+}
+
+type writer struct {
+	pages [][]byte
+}
+
+// child is a page of a b-tree level together with the largest rowid below it
+type child struct {
+	page   uint32
+	maxKey int64
+}
+
+// Write writes a SQLite 3 database file holding tables to w
+func Write(w io.Writer, tables []Table) error {
+
+	wr := &writer{}
+
+	// page 1 holds the file header and the sqlite_master table
+	_, masterPage := wr.allocate()
+
+	masterCells := make([][]byte, 0, len(tables))
+	for i, table := range tables {
+		rootPage, err := wr.writeTable(table.Rows)
+		if err != nil {
+			return fmt.Errorf("unable to write table %s: %w", table.Name, err)
+		}
+
+		cell, err := wr.leafCell(Row{
+			RowID:  int64(i + 1),
+			Values: []interface{}{"table", table.Name, table.Name, int64(rootPage), table.SQL},
+		})
+		if err != nil {
+			return err
+		}
+		masterCells = append(masterCells, cell)
+	}
+
+	if !fits(100+8, masterCells) {
+		return fmt.Errorf("schema of %d tables does not fit the first page", len(tables))
+	}
+	fillPage(masterPage, 100, leafTablePage, masterCells, 0)
+	writeHeader(masterPage, uint32(len(wr.pages)))
+
+	for _, page := range wr.pages {
+		if _, err := w.Write(page); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (wr *writer) allocate() (uint32, []byte) {
+	page := make([]byte, pageSize)
+	wr.pages = append(wr.pages, page)
+	return uint32(len(wr.pages)), page
+}
+
+// writeTable writes the b-tree of rows and returns its root page
+func (wr *writer) writeTable(rows []Row) (uint32, error) {
+
+	rows = append([]Row(nil), rows...)
+	sort.Slice(rows, func(i, j int) bool { return rows[i].RowID < rows[j].RowID })
+	for i := 1; i < len(rows); i++ {
+		if rows[i].RowID == rows[i-1].RowID {
+			return 0, fmt.Errorf("duplicate rowid %d", rows[i].RowID)
+		}
+	}
+
+	var leaves []child
+	var cells [][]byte
+	flush := func(maxKey int64) {
+		pageNum, page := wr.allocate()
+		fillPage(page, 0, leafTablePage, cells, 0)
+		leaves = append(leaves, child{page: pageNum, maxKey: maxKey})
+		cells = nil
+	}
+
+	for i, row := range rows {
+		cell, err := wr.leafCell(row)
+		if err != nil {
+			return 0, err
+		}
+		if len(cells) > 0 && !fits(8, append(cells, cell)) {
+			flush(rows[i-1].RowID)
+		}
+		cells = append(cells, cell)
+	}
+	if len(cells) > 0 || len(leaves) == 0 {
+		var maxKey int64
+		if len(rows) > 0 {
+			maxKey = rows[len(rows)-1].RowID
+		}
+		flush(maxKey)
+	}
+
+	level := leaves
+	for len(level) > 1 {
+		level = wr.writeInteriorLevel(level)
+	}
+
+	return level[0].page, nil
+}
+
+// writeInteriorLevel writes interior pages pointing at children, split evenly
+// so that every page has at least one cell besides its right-most pointer
+func (wr *writer) writeInteriorLevel(children []child) []child {
+
+	perPage := (pageSize - 12) / (2 + maxInteriorCellSize)
+	numPages := (len(children) + perPage - 1) / perPage
+
+	parents := make([]child, 0, numPages)
+	for p := 0; p < numPages; p++ {
+		group := children[p*len(children)/numPages : (p+1)*len(children)/numPages]
+
+		cells := make([][]byte, 0, len(group)-1)
+		for _, c := range group[:len(group)-1] {
+			cell := binary.BigEndian.AppendUint32(nil, c.page)
+			cell = append(cell, putVarint(uint64(c.maxKey))...)
+			cells = append(cells, cell)
+		}
+
+		last := group[len(group)-1]
+		pageNum, page := wr.allocate()
+		fillPage(page, 0, interiorTablePage, cells, last.page)
+		parents = append(parents, child{page: pageNum, maxKey: last.maxKey})
+	}
+
+	return parents
+}
+
+// leafCell encodes row as a table leaf cell, spilling payload that does not fit onto overflow pages
+func (wr *writer) leafCell(row Row) ([]byte, error) {
+
+	payload, err := encodeRecord(row.Values)
+	if err != nil {
+		return nil, err
+	}
+
+	local := len(payload)
+	if local > maxLocalPayload {
+		local = minLocalPayload + (len(payload)-minLocalPayload)%(pageSize-4)
+		if local > maxLocalPayload {
+			local = minLocalPayload
+		}
+	}
+
+	cell := putVarint(uint64(len(payload)))
+	cell = append(cell, putVarint(uint64(row.RowID))...)
+	cell = append(cell, payload[:local]...)
+
+	if local < len(payload) {
+		rest := payload[local:]
+		firstPage, page := wr.allocate()
+		for {
+			n := copy(page[4:], rest)
+			rest = rest[n:]
+			if len(rest) == 0 {
+				break
+			}
+			next, nextPage := wr.allocate()
+			binary.BigEndian.PutUint32(page, next)
+			page = nextPage
+		}
+		cell = binary.BigEndian.AppendUint32(cell, firstPage)
+	}
+
+	return cell, nil
+}
+
+// fits reports whether cells fit a page behind a header of headerSize bytes
+func fits(headerSize int, cells [][]byte) bool {
+	used := headerSize
+	for _, cell := range cells {
+		used += 2 + len(cell)
+	}
+	return used <= pageSize
+}
+
+// fillPage writes the b-tree page header at offset, the cell pointer array behind it
+// and the cells from the end of the page backwards
+func fillPage(page []byte, offset int, pageType byte, cells [][]byte, rightMost uint32) {
+
+	headerSize := 8
+	if pageType == interiorTablePage {
+		headerSize = 12
+	}
+
+	contentStart := pageSize
+	for i, cell := range cells {
+		contentStart -= len(cell)
+		copy(page[contentStart:], cell)
+		binary.BigEndian.PutUint16(page[offset+headerSize+2*i:], uint16(contentStart))
+	}
+
+	page[offset] = pageType
+	binary.BigEndian.PutUint16(page[offset+3:], uint16(len(cells)))
+	binary.BigEndian.PutUint16(page[offset+5:], uint16(contentStart))
+	if pageType == interiorTablePage {
+		binary.BigEndian.PutUint32(page[offset+8:], rightMost)
+	}
+}
+
+// writeHeader writes the 100 byte database file header into page 1
+func writeHeader(page []byte, numPages uint32) {
+	copy(page, "SQLite format 3\x00")
+	binary.BigEndian.PutUint16(page[16:], pageSize)
+	page[18] = 1  // legacy write version
+	page[19] = 1  // legacy read version
+	page[21] = 64 // max embedded payload fraction
+	page[22] = 32 // min embedded payload fraction
+	page[23] = 32 // leaf payload fraction
+	binary.BigEndian.PutUint32(page[24:], 1) // file change counter
+	binary.BigEndian.PutUint32(page[28:], numPages)
+	binary.BigEndian.PutUint32(page[40:], 1) // schema cookie
+	binary.BigEndian.PutUint32(page[44:], 4) // schema format
+	binary.BigEndian.PutUint32(page[56:], 1) // UTF-8
+	binary.BigEndian.PutUint32(page[92:], 1) // version valid for
+	binary.BigEndian.PutUint32(page[96:], 3045000)
+}
+
+// encodeRecord encodes values in the SQLite record format
+func encodeRecord(values []interface{}) ([]byte, error) {
+
+	var header, body []byte
+	for _, value := range values {
+		switch v := value.(type) {
+		case nil:
+			header = append(header, 0)
+		case int:
+			serialType, data := encodeInt(int64(v))
+			header = append(header, putVarint(serialType)...)
+			body = append(body, data...)
+		case int64:
+			serialType, data := encodeInt(v)
+			header = append(header, putVarint(serialType)...)
+			body = append(body, data...)
+		case float64:
+			header = append(header, 7)
+			body = binary.BigEndian.AppendUint64(body, math.Float64bits(v))
+		case string:
+			header = append(header, putVarint(uint64(len(v))*2+13)...)
+			body = append(body, v...)
+		case []byte:
+			header = append(header, putVarint(uint64(len(v))*2+12)...)
+			body = append(body, v...)
+		default:
+			return nil, fmt.Errorf("unsupported value type %T", value)
+		}
+	}
+
+	// the header size counts its own varint
+	headerSize := len(header) + 1
+	for len(putVarint(uint64(headerSize)))+len(header) != headerSize {
+		headerSize = len(header) + len(putVarint(uint64(headerSize)))
+	}
+
+	record := putVarint(uint64(headerSize))
+	record = append(record, header...)
+	return append(record, body...), nil
+}
+
+// encodeInt returns the serial type and big-endian bytes of the smallest encoding of v
+func encodeInt(v int64) (uint64, []byte) {
+
+	switch {
+	case v == 0:
+		return 8, nil
+	case v == 1:
+		return 9, nil
+	}
+
+	sizes := []struct {
+		serialType uint64
+		bytes      int
+	}{{1, 1}, {2, 2}, {3, 3}, {4, 4}, {5, 6}, {6, 8}}
+
+	for _, size := range sizes {
+		bits := uint(size.bytes * 8)
+		if size.bytes == 8 || (v >= -(1<<(bits-1)) && v < 1<<(bits-1)) {
+			data := binary.BigEndian.AppendUint64(nil, uint64(v))
+			return size.serialType, data[8-size.bytes:]
+		}
+	}
+
+	return 6, binary.BigEndian.AppendUint64(nil, uint64(v))
+}
+
+// putVarint encodes v as a SQLite varint, big-endian with 7 bits per byte
+// and all 8 bits used in the ninth byte
+func putVarint(v uint64) []byte {
+
+	if v <= 0x7f {
+		return []byte{byte(v)}
+	}
+
+	if v > 0x00ffffffffffffff {
+		buf := make([]byte, 9)
+		buf[8] = byte(v)
+		v >>= 8
+		for i := 7; i >= 0; i-- {
+			buf[i] = byte(v&0x7f) | 0x80
+			v >>= 7
+		}
+		return buf
+	}
+
+	var buf []byte
+	for v != 0 {
+		buf = append([]byte{byte(v&0x7f) | 0x80}, buf...)
+		v >>= 7
+	}
+	buf[len(buf)-1] &= 0x7f
+
+	return buf
+}
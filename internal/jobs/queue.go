@@ -0,0 +1,243 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/database"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// defaultMaxAttempts is how often a job runs before it is dead-lettered
+	defaultMaxAttempts = 5
+
+	// pollInterval is how long an idle worker waits before looking for due jobs again,
+	// jobs enqueued by this process wake a worker right away
+	pollInterval = 5 * time.Second
+
+	// jobTimeout bounds a single run of a job
+	jobTimeout = time.Minute
+
+	// leaseTimeout is how long a job may stay running before it is handed to another worker
+	leaseTimeout = 5 * time.Minute
+
+	baseBackoff = 2 * time.Second
+	maxBackoff  = 5 * time.Minute
+)
+
+// Publisher broadcasts events to the clients of a chat, implemented by the websocket hub
+type Publisher interface {
+	Publish(event models.ChatEvent)
+}
+
+// Handler runs a job, a returned error schedules a retry unless it is Permanent
+type Handler func(ctx context.Context, job models.Job) error
+
+// permanentError marks a failure that retrying can not fix
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so the job is dead-lettered without further attempts
+func Permanent(err error) error {
+	return permanentError{err: err}
+}
+
+var (
+	handlersMu sync.RWMutex
+	handlers   = make(map[string]Handler)
+
+	publisher Publisher
+
+	// wake lets Enqueue signal an idle worker without waiting for the next poll
+	wake = make(chan struct{}, 1)
+)
+
+// Register sets the handler of jobs of kind
+func Register(kind string, handler Handler) {
+	handlersMu.Lock()
+	defer handlersMu.Unlock()
+	handlers[kind] = handler
+}
+
+func handlerFor(kind string) (Handler, bool) {
+	handlersMu.RLock()
+	defer handlersMu.RUnlock()
+	handler, ok := handlers[kind]
+	return handler, ok
+}
+
+// Enqueue stores a job of kind with payload marshalled to JSON, a job of the same kind
+// and dedupeKey that has not started yet absorbs it. An empty dedupeKey never dedupes
+func Enqueue(ctx context.Context, kind string, payload interface{}, dedupeKey string) error {
+
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("unable to marshal %s job payload: %w", kind, err)
+	}
+
+	job := models.Job{
+		Kind:        kind,
+		Payload:     payloadBytes,
+		MaxAttempts: defaultMaxAttempts,
+	}
+	job.DedupeKey.String = dedupeKey
+	job.DedupeKey.Valid = dedupeKey != ""
+
+	db := database.GetPostgresConn()
+
+	_, err = db.EnqueueJob(ctx, &job)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Start runs workers goroutines claiming due jobs until ctx is done,
+// events produced by jobs are broadcast through pub
+func Start(ctx context.Context, pub Publisher, workers int) {
+
+	publisher = pub
+
+	for i := 0; i < workers; i++ {
+		go work(ctx)
+	}
+	go requeueStale(ctx)
+	go sweep(ctx)
+}
+
+func work(ctx context.Context) {
+
+	db := database.GetPostgresConn()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		job, err := db.ClaimJob(ctx, time.Now().UTC())
+		if err == nil {
+			runJob(ctx, job)
+			continue
+		}
+		if err != pgx.ErrNoRows {
+			log.Printf("Failed to claim job: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// runJob runs a claimed job and records its outcome
+func runJob(ctx context.Context, job models.Job) {
+
+	db := database.GetPostgresConn()
+
+	err := callHandler(ctx, job)
+	if err == nil {
+		if err := db.CompleteJob(ctx, job.ID.String()); err != nil {
+			log.Printf("Failed to complete job %s: %v", job.ID, err)
+		}
+		return
+	}
+
+	var retryAt *time.Time
+	var permanent permanentError
+	if job.Attempts < job.MaxAttempts && !errors.As(err, &permanent) {
+		next := time.Now().UTC().Add(backoff(job.Attempts))
+		retryAt = &next
+		log.Printf("Job %s (%s) failed on attempt %d, retrying at %s: %v", job.ID, job.Kind, job.Attempts, next.Format(time.RFC3339), err)
+	} else {
+		log.Printf("Job %s (%s) dead-lettered after %d attempts: %v", job.ID, job.Kind, job.Attempts, err)
+	}
+
+	if err := db.FailJob(ctx, job.ID.String(), err.Error(), retryAt); err != nil {
+		log.Printf("Failed to record failure of job %s: %v", job.ID, err)
+	}
+}
+
+// callHandler runs the handler of job with a timeout, a panic fails the job instead of the worker
+func callHandler(ctx context.Context, job models.Job) (err error) {
+
+	handler, ok := handlerFor(job.Kind)
+	if !ok {
+		return Permanent(fmt.Errorf("no handler registered for job kind %s", job.Kind))
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job handler panicked: %v", r)
+		}
+	}()
+
+	jobCtx, cancel := context.WithTimeout(ctx, jobTimeout)
+	defer cancel()
+
+	return handler(jobCtx, job)
+}
+
+// backoff returns the delay before the next attempt, doubling per attempt
+// from baseBackoff up to maxBackoff with up to a fifth added as jitter
+func backoff(attempts int) time.Duration {
+
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	delay := maxBackoff
+	if attempts < 20 {
+		delay = baseBackoff << (attempts - 1)
+		if delay > maxBackoff {
+			delay = maxBackoff
+		}
+	}
+
+	return delay + time.Duration(rand.Int63n(int64(delay)/5+1))
+}
+
+// requeueStale hands jobs of crashed workers back to the queue
+func requeueStale(ctx context.Context) {
+
+	db := database.GetPostgresConn()
+
+	ticker := time.NewTicker(leaseTimeout / 5)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		requeued, err := db.RequeueStaleJobs(ctx, time.Now().UTC().Add(-leaseTimeout))
+		if err != nil {
+			log.Printf("Failed to requeue stale jobs: %v", err)
+			continue
+		}
+		if requeued > 0 {
+			log.Printf("Requeued %d stale jobs", requeued)
+		}
+	}
+}
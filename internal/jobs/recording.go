@@ -0,0 +1,42 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/voice"
+)
+
+// KindDeleteRecording removes the recording of a voice message deleted for everyone from the blob store
+const KindDeleteRecording = "delete_recording"
+
+type deleteRecordingPayload struct {
+	BlobKey string `json:"blob_key"`
+}
+
+func init() {
+	Register(KindDeleteRecording, deleteRecording)
+}
+
+// EnqueueRecordingDeletion queues the removal of the recording stored under blobKey
+func EnqueueRecordingDeletion(ctx context.Context, blobKey string) error {
+	return Enqueue(ctx, KindDeleteRecording, deleteRecordingPayload{BlobKey: blobKey}, blobKey)
+}
+
+func deleteRecording(ctx context.Context, job models.Job) error {
+
+	var payload deleteRecordingPayload
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return Permanent(fmt.Errorf("invalid delete recording payload: %w", err))
+	}
+
+	err := voice.DeleteRecording(ctx, models.VoiceAttachment{BlobKey: payload.BlobKey})
+	if errors.Is(err, voice.ErrNoStore) {
+		return Permanent(err)
+	}
+
+	return err
+}
@@ -0,0 +1,69 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/database"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/translation"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/voice"
+)
+
+const (
+	// sweepInterval is how often data that is kept for a limited time is cleaned up
+	sweepInterval = time.Hour
+
+	// doneJobRetention is how long finished jobs are kept, dead jobs are kept longer
+	// so they can still be inspected once their failure is noticed
+	doneJobRetention = 7 * 24 * time.Hour
+	deadJobRetention = 30 * 24 * time.Hour
+)
+
+// sweep periodically evicts what outlived its retention until ctx is done
+func sweep(ctx context.Context) {
+
+	db := database.GetPostgresConn()
+
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now().UTC()
+
+		for status, retention := range map[models.JobStatus]time.Duration{models.JobDone: doneJobRetention, models.JobDead: deadJobRetention} {
+			deleted, err := db.DeleteFinishedJobs(ctx, status, now.Add(-retention))
+			if err != nil {
+				log.Printf("Failed to delete %s jobs: %v", status, err)
+				continue
+			}
+			if deleted > 0 {
+				log.Printf("Deleted %d %s jobs older than %v", deleted, status, retention)
+			}
+		}
+
+		expired, err := translation.ExpireCache(ctx, now.Add(-translation.CacheTTL))
+		if err != nil {
+			log.Printf("Failed to expire cached translations: %v", err)
+		}
+		if expired > 0 {
+			log.Printf("Expired %d cached translations unused for %v", expired, translation.CacheTTL)
+		}
+
+		evicted, err := voice.PruneSpeech(ctx, now.Add(-voice.SpeechCacheTTL))
+		if err != nil && !errors.Is(err, voice.ErrNoStore) {
+			log.Printf("Failed to evict cached speech: %v", err)
+		}
+		if evicted > 0 {
+			log.Printf("Evicted %d cached speech unused for %v", evicted, voice.SpeechCacheTTL)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/blobstore"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/database"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/stats"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/transliteration"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/voice"
+	"github.com/jackc/pgx/v5"
+)
+
+// KindTranscribeVoice turns the recording of a voice message into its text
+const KindTranscribeVoice = "transcribe_voice"
+
+type transcribePayload struct {
+	MessageID string `json:"message_id"`
+}
+
+func init() {
+	Register(KindTranscribeVoice, transcribeVoice)
+}
+
+// EnqueueTranscription queues the transcription of the voice message messageID,
+// message.transcribed is broadcast once it is done and its translation queued
+func EnqueueTranscription(ctx context.Context, messageID string) error {
+	return Enqueue(ctx, KindTranscribeVoice, transcribePayload{MessageID: messageID}, messageID)
+}
+
+func transcribeVoice(ctx context.Context, job models.Job) error {
+
+	var payload transcribePayload
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return Permanent(fmt.Errorf("invalid transcribe payload: %w", err))
+	}
+
+	db := database.GetPostgresConn()
+
+	msg, err := db.GetLiveMessage(ctx, payload.MessageID)
+	if err != nil {
+		// removed before its turn came, nothing left to transcribe
+		if err == pgx.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+	if msg.DeletedAt != nil {
+		return nil
+	}
+
+	// the stats of the sender counted the voice message without text
+	before := statsMessage(msg)
+
+	err = voice.TranscribeMessage(ctx, &msg)
+	if err != nil {
+		// deleted for everyone while transcribing, the recording and its row are gone
+		if errors.Is(err, pgx.ErrNoRows) || deletedMeanwhile(ctx, payload.MessageID) {
+			return nil
+		}
+
+		permanent := errors.Is(err, voice.ErrNoTranscriber) || errors.Is(err, voice.ErrNoStore) ||
+			errors.Is(err, voice.ErrNotVoiceMessage) || errors.Is(err, blobstore.ErrNotFound)
+
+		// the last attempt leaves the message as a voice message without text
+		if permanent || job.Attempts >= job.MaxAttempts {
+			failTranscription(ctx, msg, err)
+		}
+		if permanent {
+			return Permanent(err)
+		}
+		return err
+	}
+
+	// the sender may have edited the message meanwhile, broadcast what was stored
+	msg, err = db.GetLiveMessage(ctx, payload.MessageID)
+	if err != nil {
+		return Permanent(fmt.Errorf("unable to reload transcribed message: %w", err))
+	}
+
+	after := statsMessage(msg)
+	err = stats.Recount(ctx, before, &after)
+	if err != nil {
+		log.Printf("Failed to recount stats of transcribed message %s: %v", msg.ID, err)
+	}
+
+	transliteration.AttachRomanization(ctx, &msg)
+
+	if publisher != nil {
+		publisher.Publish(models.ChatEvent{Type: models.EventMessageTranscribed, ChatID: msg.ChatID, Payload: msg})
+	}
+
+	if msg.Content == "" {
+		return nil
+	}
+
+	return EnqueueTranslation(ctx, msg.ID.String())
+}
+
+// failTranscription marks the transcript of msg failed and tells the chat
+func failTranscription(ctx context.Context, msg models.MessageResponse, cause error) {
+
+	db := database.GetPostgresConn()
+
+	err := db.FailVoiceTranscript(ctx, msg.ID.String(), cause.Error())
+	if err != nil {
+		log.Printf("Failed to mark transcript of message %s failed: %v", msg.ID, err)
+		return
+	}
+
+	if msg.Voice != nil {
+		msg.Voice.TranscriptStatus = models.TranscriptFailed
+	}
+	if publisher != nil {
+		publisher.Publish(models.ChatEvent{Type: models.EventMessageTranscribed, ChatID: msg.ChatID, Payload: msg})
+	}
+}
+
+// deletedMeanwhile reports whether messageID was deleted for everyone since its job started
+func deletedMeanwhile(ctx context.Context, messageID string) bool {
+	msg, err := database.GetPostgresConn().GetLiveMessage(ctx, messageID)
+	return err == pgx.ErrNoRows || (err == nil && msg.DeletedAt != nil)
+}
+
+// statsMessage is the part of msg the stats of its sender are counted from
+func statsMessage(msg models.MessageResponse) models.Message {
+	return models.Message{
+		ID:        msg.ID,
+		ChatID:    msg.ChatID,
+		SenderID:  msg.SenderID,
+		Content:   msg.Content,
+		CreatedAt: msg.CreatedAt,
+		LangCode:  msg.LangCode,
+		DeletedAt: msg.DeletedAt,
+	}
+}
@@ -0,0 +1,78 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/database"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/learning"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/translation"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/transliteration"
+	"github.com/jackc/pgx/v5"
+)
+
+// KindTranslateMessage translates a message into the languages of its chat
+const KindTranslateMessage = "translate_message"
+
+type translatePayload struct {
+	MessageID string `json:"message_id"`
+}
+
+func init() {
+	Register(KindTranslateMessage, translateMessage)
+}
+
+// EnqueueTranslation queues the translation of messageID, message.translated is
+// broadcast once it is done. A queued translation that has not started yet is
+// reused as it reads the content of the message when it runs
+func EnqueueTranslation(ctx context.Context, messageID string) error {
+	return Enqueue(ctx, KindTranslateMessage, translatePayload{MessageID: messageID}, messageID)
+}
+
+func translateMessage(ctx context.Context, job models.Job) error {
+
+	var payload translatePayload
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return Permanent(fmt.Errorf("invalid translate payload: %w", err))
+	}
+
+	db := database.GetPostgresConn()
+
+	msg, err := db.GetLiveMessage(ctx, payload.MessageID)
+	if err != nil {
+		// removed before its turn came, nothing left to translate
+		if err == pgx.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+	if msg.DeletedAt != nil {
+		return nil
+	}
+
+	err = translation.TranslateMessage(ctx, &msg)
+	if err != nil {
+		// the edit queued a translation of its own, a delete leaves nothing to translate
+		if errors.Is(err, database.ErrMessageChanged) || errors.Is(err, database.ErrMessageDeleted) || errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	err = learning.AttachLearningViews(ctx, &msg)
+	if err != nil {
+		log.Printf("Failed to build learning views of message %s: %v", msg.ID, err)
+	}
+
+	transliteration.AttachRomanization(ctx, &msg)
+
+	if publisher != nil {
+		publisher.Publish(models.ChatEvent{Type: models.EventMessageTranslated, ChatID: msg.ChatID, Payload: msg})
+	}
+
+	return nil
+}
@@ -0,0 +1,279 @@
+package langdetect
+
+import (
+	"embed"
+	"fmt"
+	"math"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Result is the language detected for a text and how sure the detector is of it, from 0 to 1
+type Result struct {
+	LangCode   string
+	Confidence float64
+}
+
+// Detector guesses the language a text is written in
+type Detector interface {
+	// Detect returns the most likely language of text, ok is false when the
+	// text has no letters or is written in a language the detector does not know
+	Detect(text string) (result Result, ok bool)
+	// Languages returns the lang_codes the detector tells apart by their n-gram profiles
+	Languages() []string
+}
+
+//go:embed profiles/*.txt
+var profiles embed.FS
+
+// n-gram sizes scored by the detector, longer n-grams separate close languages
+var ngramSizes = []int{1, 2, 3}
+
+const (
+	// added to every n-gram count so unseen n-grams do not rule a language out
+	smoothing = 0.5
+
+	// scripts used by a single language in the bundled set are detected with this confidence
+	scriptConfidence = 0.99
+
+	// confidence is the product of two terms that reach 1-1/e at these scales, the average
+	// log-likelihood margin per n-gram between the two best languages and the number of
+	// n-grams, so a few distinct letters never make the detector sure
+	marginScale   = 0.06
+	evidenceScale = 30.0
+)
+
+// profile is the n-gram model of one language
+type profile struct {
+	langCode string
+	counts   map[string]float64
+	totals   map[int]float64
+	vocab    map[int]float64
+}
+
+// NGramDetector is the bundled naive Bayes detector over character n-grams. Latin-script
+// languages are told apart by n-gram profiles built from bundled sample texts, other
+// scripts by the script itself
+type NGramDetector struct {
+	profiles []profile
+}
+
+// NewNGramDetector builds the profile of every bundled sample text
+func NewNGramDetector() (*NGramDetector, error) {
+
+	files, err := profiles.ReadDir("profiles")
+	if err != nil {
+		return nil, fmt.Errorf("unable to list language profiles: %w", err)
+	}
+
+	detector := &NGramDetector{}
+	for _, file := range files {
+		langCode := strings.TrimSuffix(file.Name(), path.Ext(file.Name()))
+
+		data, err := profiles.ReadFile(path.Join("profiles", file.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("unable to read language profile %s: %w", file.Name(), err)
+		}
+
+		p := profile{
+			langCode: langCode,
+			counts:   make(map[string]float64),
+			totals:   make(map[int]float64),
+			vocab:    make(map[int]float64),
+		}
+		for _, gram := range ngrams(letterWords(string(data))) {
+			if p.counts[gram] == 0 {
+				p.vocab[len([]rune(gram))]++
+			}
+			p.counts[gram]++
+			p.totals[len([]rune(gram))]++
+		}
+		detector.profiles = append(detector.profiles, p)
+	}
+
+	if len(detector.profiles) == 0 {
+		return nil, fmt.Errorf("no language profiles bundled")
+	}
+
+	return detector, nil
+}
+
+// Languages returns the lang_codes the detector can tell apart by their profiles
+func (d *NGramDetector) Languages() []string {
+	langCodes := make([]string, 0, len(d.profiles))
+	for _, p := range d.profiles {
+		langCodes = append(langCodes, p.langCode)
+	}
+	return langCodes
+}
+
+func (d *NGramDetector) Detect(text string) (Result, bool) {
+
+	if langCode, ok := detectScript(text); ok {
+		return Result{LangCode: langCode, Confidence: scriptConfidence}, true
+	}
+
+	grams := ngrams(Words(text))
+	if len(grams) == 0 {
+		return Result{}, false
+	}
+
+	scores := make([]float64, len(d.profiles))
+	for i, p := range d.profiles {
+		for _, gram := range grams {
+			n := len([]rune(gram))
+			scores[i] += math.Log((p.counts[gram] + smoothing) / (p.totals[n] + smoothing*(p.vocab[n]+1)))
+		}
+	}
+
+	// every character takes part in one n-gram per size, dividing by the number of sizes
+	// keeps the correlated n-grams from making short texts look certain
+	for i := range scores {
+		scores[i] /= float64(len(ngramSizes))
+	}
+
+	best, second := 0, -1
+	for i := range scores {
+		if scores[i] > scores[best] {
+			second, best = best, i
+		} else if i != best && (second == -1 || scores[i] > scores[second]) {
+			second = i
+		}
+	}
+	if second == -1 {
+		return Result{LangCode: d.profiles[best].langCode, Confidence: 1}, true
+	}
+
+	// the posterior of naive Bayes is near 1 for any text of a few words, confidence instead
+	// grows with how much better the best language explains each n-gram than the runner-up
+	margin := (scores[best] - scores[second]) / float64(len(grams))
+	confidence := (1 - math.Exp(-margin/marginScale)) * (1 - math.Exp(-float64(len(grams))/evidenceScale))
+
+	return Result{LangCode: d.profiles[best].langCode, Confidence: confidence}, true
+}
+
+// Words returns the distinct lowercased words of text that detection looks at, leaving out
+// links, numbers and repeated words since they say little about the language
+func Words(text string) []string {
+
+	var kept []string
+	for _, field := range strings.Fields(text) {
+		lower := strings.ToLower(field)
+		if strings.Contains(lower, "://") || strings.HasPrefix(lower, "www.") {
+			continue
+		}
+		kept = append(kept, lower)
+	}
+
+	seen := make(map[string]bool)
+	var words []string
+	for _, word := range letterWords(strings.Join(kept, " ")) {
+		if !seen[word] {
+			seen[word] = true
+			words = append(words, word)
+		}
+	}
+
+	return words
+}
+
+// letterWords returns the lowercased runs of letters of text, apostrophes inside words are kept
+func letterWords(text string) []string {
+
+	var words []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	}) {
+		if word = strings.Trim(word, "'"); len(word) != 0 {
+			words = append(words, word)
+		}
+	}
+
+	return words
+}
+
+// ngrams returns the character n-grams of words, each word is padded with spaces
+func ngrams(words []string) []string {
+
+	var grams []string
+	for _, word := range words {
+		runes := []rune(" " + word + " ")
+		for _, n := range ngramSizes {
+			for i := 0; i+n <= len(runes); i++ {
+				gram := string(runes[i : i+n])
+				if gram != " " {
+					grams = append(grams, gram)
+				}
+			}
+		}
+	}
+
+	return grams
+}
+
+// scripts that identify a language on their own, checked in order
+var scriptLanguages = []struct {
+	langCode string
+	table    *unicode.RangeTable
+}{
+	{"ja", unicode.Hiragana},
+	{"ja", unicode.Katakana},
+	{"ko", unicode.Hangul},
+	{"zh", unicode.Han},
+	{"ru", unicode.Cyrillic},
+	{"ar", unicode.Arabic},
+	{"he", unicode.Hebrew},
+	{"el", unicode.Greek},
+	{"hi", unicode.Devanagari},
+	{"th", unicode.Thai},
+}
+
+// detectScript returns the language of text when most of its letters are written in a
+// script only one language uses, Japanese mixes kana into Han so kana wins over Han
+func detectScript(text string) (string, bool) {
+
+	letters := 0
+	counts := make(map[string]int)
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		for _, script := range scriptLanguages {
+			if unicode.Is(script.table, r) {
+				counts[script.langCode]++
+				break
+			}
+		}
+	}
+
+	if letters == 0 {
+		return "", false
+	}
+
+	// any kana makes Han text Japanese
+	if counts["ja"] > 0 {
+		counts["ja"] += counts["zh"]
+		delete(counts, "zh")
+	}
+
+	// Ukrainian letters set Ukrainian apart from Russian
+	if counts["ru"] > 0 && strings.ContainsAny(strings.ToLower(text), "іїєґ") {
+		counts["uk"] = counts["ru"]
+		delete(counts, "ru")
+	}
+
+	langCodes := make([]string, 0, len(counts))
+	for langCode := range counts {
+		langCodes = append(langCodes, langCode)
+	}
+	sort.Slice(langCodes, func(i, j int) bool { return counts[langCodes[i]] > counts[langCodes[j]] })
+
+	if len(langCodes) == 0 || counts[langCodes[0]]*2 <= letters {
+		return "", false
+	}
+
+	return langCodes[0], true
+}
@@ -0,0 +1,176 @@
+package languages
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+)
+
+// writing directions
+const (
+	DirectionLTR = "ltr"
+	DirectionRTL = "rtl"
+)
+
+// ISO 15924 codes of the scripts used by registered languages
+const (
+	ScriptLatin      = "Latn"
+	ScriptCyrillic   = "Cyrl"
+	ScriptGreek      = "Grek"
+	ScriptArabic     = "Arab"
+	ScriptHebrew     = "Hebr"
+	ScriptDevanagari = "Deva"
+	ScriptBengali    = "Beng"
+	ScriptThai       = "Thai"
+	ScriptHangul     = "Kore"
+	ScriptJapanese   = "Jpan"
+	ScriptHan        = "Hani"
+)
+
+var ErrUnsupportedLanguage = errors.New("unsupported language")
+
+// registry lists every language users can pick, message and translation rows store Code.
+// Translation is false for languages no configured provider is expected to translate
+var registry = []models.LanguageResponse{
+	{Code: "ar", Name: "Arabic", NativeName: "العربية", Script: ScriptArabic, Direction: DirectionRTL, Translation: true},
+	{Code: "bg", Name: "Bulgarian", NativeName: "Български", Script: ScriptCyrillic, Direction: DirectionLTR, Translation: true},
+	{Code: "bn", Name: "Bengali", NativeName: "বাংলা", Script: ScriptBengali, Direction: DirectionLTR, Translation: true},
+	{Code: "ca", Name: "Catalan", NativeName: "Català", Script: ScriptLatin, Direction: DirectionLTR, Translation: true},
+	{Code: "cs", Name: "Czech", NativeName: "Čeština", Script: ScriptLatin, Direction: DirectionLTR, Translation: true},
+	{Code: "da", Name: "Danish", NativeName: "Dansk", Script: ScriptLatin, Direction: DirectionLTR, Translation: true},
+	{Code: "de", Name: "German", NativeName: "Deutsch", Script: ScriptLatin, Direction: DirectionLTR, Translation: true},
+	{Code: "el", Name: "Greek", NativeName: "Ελληνικά", Script: ScriptGreek, Direction: DirectionLTR, Translation: true},
+	{Code: "en", Name: "English", NativeName: "English", Script: ScriptLatin, Direction: DirectionLTR, Translation: true},
+	{Code: "eo", Name: "Esperanto", NativeName: "Esperanto", Script: ScriptLatin, Direction: DirectionLTR, Translation: true},
+	{Code: "es", Name: "Spanish", NativeName: "Español", Script: ScriptLatin, Direction: DirectionLTR, Translation: true},
+	{Code: "fa", Name: "Persian", NativeName: "فارسی", Script: ScriptArabic, Direction: DirectionRTL, Translation: true},
+	{Code: "fi", Name: "Finnish", NativeName: "Suomi", Script: ScriptLatin, Direction: DirectionLTR, Translation: true},
+	{Code: "fr", Name: "French", NativeName: "Français", Script: ScriptLatin, Direction: DirectionLTR, Translation: true},
+	{Code: "ga", Name: "Irish", NativeName: "Gaeilge", Script: ScriptLatin, Direction: DirectionLTR, Translation: true},
+	{Code: "he", Name: "Hebrew", NativeName: "עברית", Script: ScriptHebrew, Direction: DirectionRTL, Translation: true},
+	{Code: "hi", Name: "Hindi", NativeName: "हिन्दी", Script: ScriptDevanagari, Direction: DirectionLTR, Translation: true},
+	{Code: "hu", Name: "Hungarian", NativeName: "Magyar", Script: ScriptLatin, Direction: DirectionLTR, Translation: true},
+	{Code: "id", Name: "Indonesian", NativeName: "Bahasa Indonesia", Script: ScriptLatin, Direction: DirectionLTR, Translation: true},
+	{Code: "it", Name: "Italian", NativeName: "Italiano", Script: ScriptLatin, Direction: DirectionLTR, Translation: true},
+	{Code: "ja", Name: "Japanese", NativeName: "日本語", Script: ScriptJapanese, Direction: DirectionLTR, Translation: true},
+	{Code: "ko", Name: "Korean", NativeName: "한국어", Script: ScriptHangul, Direction: DirectionLTR, Translation: true},
+	{Code: "la", Name: "Latin", NativeName: "Latina", Script: ScriptLatin, Direction: DirectionLTR, Translation: false},
+	{Code: "mr", Name: "Marathi", NativeName: "मराठी", Script: ScriptDevanagari, Direction: DirectionLTR, Translation: false},
+	{Code: "ms", Name: "Malay", NativeName: "Bahasa Melayu", Script: ScriptLatin, Direction: DirectionLTR, Translation: true},
+	{Code: "nb", Name: "Norwegian Bokmål", NativeName: "Norsk bokmål", Script: ScriptLatin, Direction: DirectionLTR, Translation: true},
+	{Code: "ne", Name: "Nepali", NativeName: "नेपाली", Script: ScriptDevanagari, Direction: DirectionLTR, Translation: false},
+	{Code: "nl", Name: "Dutch", NativeName: "Nederlands", Script: ScriptLatin, Direction: DirectionLTR, Translation: true},
+	{Code: "pl", Name: "Polish", NativeName: "Polski", Script: ScriptLatin, Direction: DirectionLTR, Translation: true},
+	{Code: "pt", Name: "Portuguese", NativeName: "Português", Script: ScriptLatin, Direction: DirectionLTR, Translation: true},
+	{Code: "ro", Name: "Romanian", NativeName: "Română", Script: ScriptLatin, Direction: DirectionLTR, Translation: true},
+	{Code: "ru", Name: "Russian", NativeName: "Русский", Script: ScriptCyrillic, Direction: DirectionLTR, Translation: true},
+	{Code: "sk", Name: "Slovak", NativeName: "Slovenčina", Script: ScriptLatin, Direction: DirectionLTR, Translation: true},
+	{Code: "sv", Name: "Swedish", NativeName: "Svenska", Script: ScriptLatin, Direction: DirectionLTR, Translation: true},
+	{Code: "sw", Name: "Swahili", NativeName: "Kiswahili", Script: ScriptLatin, Direction: DirectionLTR, Translation: false},
+	{Code: "th", Name: "Thai", NativeName: "ไทย", Script: ScriptThai, Direction: DirectionLTR, Translation: true},
+	{Code: "tl", Name: "Tagalog", NativeName: "Tagalog", Script: ScriptLatin, Direction: DirectionLTR, Translation: true},
+	{Code: "tr", Name: "Turkish", NativeName: "Türkçe", Script: ScriptLatin, Direction: DirectionLTR, Translation: true},
+	{Code: "uk", Name: "Ukrainian", NativeName: "Українська", Script: ScriptCyrillic, Direction: DirectionLTR, Translation: true},
+	{Code: "ur", Name: "Urdu", NativeName: "اردو", Script: ScriptArabic, Direction: DirectionRTL, Translation: true},
+	{Code: "vi", Name: "Vietnamese", NativeName: "Tiếng Việt", Script: ScriptLatin, Direction: DirectionLTR, Translation: true},
+	{Code: "zh", Name: "Chinese", NativeName: "中文", Script: ScriptHan, Direction: DirectionLTR, Translation: true},
+}
+
+var byCode = func() map[string]models.LanguageResponse {
+	languages := make(map[string]models.LanguageResponse, len(registry))
+	for _, language := range registry {
+		languages[language.Code] = language
+	}
+	return languages
+}()
+
+// deprecatedCodes are replaced by the code the registry uses for the same language
+var deprecatedCodes = map[string]string{
+	"iw": "he", "in": "id", "no": "nb", "fil": "tl", "zho": "zh", "cmn": "zh",
+}
+
+// All returns every registered language ordered by code
+func All() []models.LanguageResponse {
+	languages := make([]models.LanguageResponse, len(registry))
+	copy(languages, registry)
+	return languages
+}
+
+// Lookup returns the registered language of a normalized code
+func Lookup(code string) (models.LanguageResponse, bool) {
+	language, ok := byCode[code]
+	return language, ok
+}
+
+// Normalize checks that tag is a well-formed BCP-47 tag of a registered language and returns
+// the registry code for it. Region, script and other subtags are dropped so "pt-BR", "pt_br"
+// and "{pt}", as message rows store it, all normalize to "pt"
+func Normalize(tag string) (string, error) {
+
+	tag = strings.Trim(strings.TrimSpace(tag), "{}")
+	if tag == "" {
+		return "", fmt.Errorf("%w: empty language tag", ErrUnsupportedLanguage)
+	}
+
+	subtags := strings.Split(strings.ReplaceAll(tag, "_", "-"), "-")
+	for _, subtag := range subtags {
+		if len(subtag) == 0 || len(subtag) > 8 || strings.ContainsFunc(subtag, func(r rune) bool {
+			return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+		}) {
+			return "", fmt.Errorf("%w: malformed language tag %q", ErrUnsupportedLanguage, tag)
+		}
+	}
+
+	primary := strings.ToLower(subtags[0])
+	if len(primary) < 2 || len(primary) > 3 || strings.ContainsAny(primary, "0123456789") {
+		return "", fmt.Errorf("%w: malformed language tag %q", ErrUnsupportedLanguage, tag)
+	}
+	if code, ok := deprecatedCodes[primary]; ok {
+		primary = code
+	}
+
+	if _, ok := byCode[primary]; !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedLanguage, tag)
+	}
+
+	return primary, nil
+}
+
+// ByName returns the registry code of the language with the English name, ignoring case
+func ByName(name string) (string, bool) {
+	for _, language := range registry {
+		if strings.EqualFold(language.Name, strings.TrimSpace(name)) {
+			return language.Code, true
+		}
+	}
+	return "", false
+}
+
+// NonLatin reports whether the registered language code is written in a non-Latin script
+func NonLatin(code string) bool {
+	language, ok := byCode[code]
+	return ok && language.Script != ScriptLatin
+}
+
+// UniqueScript reports whether code is the only registered language written in its script,
+// so that text in the script alone shows it is written in code. Japanese also writes Han
+func UniqueScript(code string) bool {
+
+	language, ok := byCode[code]
+	if !ok {
+		return false
+	}
+
+	for _, other := range registry {
+		if other.Code == code {
+			continue
+		}
+		if other.Script == language.Script || (other.Script == ScriptJapanese && language.Script == ScriptHan) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,104 @@
+package dictionary
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Entry is what a dictionary knows about a single word form
+type Entry struct {
+	Lemma        string
+	PartOfSpeech string
+	Translation  string
+}
+
+// Dictionary looks up word forms of one language and translates them into another
+type Dictionary interface {
+	// Lookup returns the entry of word written in langCode translated into targetLang,
+	// ok is false when the dictionary has no entry for the word
+	Lookup(ctx context.Context, langCode string, word string, targetLang string) (entry Entry, ok bool, err error)
+}
+
+//go:embed *.tsv
+var datasets embed.FS
+
+// OfflineDictionary is the bundled dictionary, one tsv file per language pair named source-target
+type OfflineDictionary struct {
+	entries map[string]map[string]Entry
+}
+
+// NewOfflineDictionary parses every bundled language pair
+func NewOfflineDictionary() (*OfflineDictionary, error) {
+
+	files, err := datasets.ReadDir(".")
+	if err != nil {
+		return nil, fmt.Errorf("unable to list dictionary datasets: %w", err)
+	}
+
+	dict := &OfflineDictionary{entries: make(map[string]map[string]Entry)}
+	for _, file := range files {
+		pair := strings.TrimSuffix(file.Name(), path.Ext(file.Name()))
+
+		data, err := datasets.ReadFile(file.Name())
+		if err != nil {
+			return nil, fmt.Errorf("unable to read dictionary dataset %s: %w", file.Name(), err)
+		}
+
+		entries, err := parseDataset(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dictionary dataset %s: %w", file.Name(), err)
+		}
+		dict.entries[pair] = entries
+	}
+
+	return dict, nil
+}
+
+// parseDataset reads form<TAB>lemma<TAB>pos<TAB>translation lines, lines starting with # are comments
+func parseDataset(data []byte) (map[string]Entry, error) {
+
+	entries := make(map[string]Entry)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("line %d: expected form<TAB>lemma<TAB>pos<TAB>translation", lineNum)
+		}
+
+		entries[strings.ToLower(fields[0])] = Entry{
+			Lemma:        fields[1],
+			PartOfSpeech: fields[2],
+			Translation:  fields[3],
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (d *OfflineDictionary) Lookup(ctx context.Context, langCode string, word string, targetLang string) (Entry, bool, error) {
+
+	entries, ok := d.entries[langCode+"-"+targetLang]
+	if !ok {
+		return Entry{}, false, nil
+	}
+
+	entry, ok := entries[strings.ToLower(word)]
+	return entry, ok, nil
+}
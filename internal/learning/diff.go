@@ -0,0 +1,73 @@
+package learning
+
+import (
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+)
+
+// maxDiffCells bounds the table DiffWords compares tokens in, two texts of
+// a websocket frame worth of single byte tokens still fit
+const maxDiffCells = 512 * 512
+
+// DiffWords compares original and corrected token by token and returns the
+// segments that turn original into corrected, runs of the same op are merged
+func DiffWords(original string, corrected string) []models.DiffSegment {
+
+	a := Tokenize(original)
+	b := Tokenize(corrected)
+
+	// the table grows with both texts, long ones are shown as replaced whole
+	if len(a)*len(b) > maxDiffCells {
+		return []models.DiffSegment{
+			{Op: models.DiffDelete, Text: original},
+			{Op: models.DiffInsert, Text: corrected},
+		}
+	}
+
+	// lcs[i][j] is the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i].Text == b[j].Text {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	segments := []models.DiffSegment{}
+	add := func(op string, text string) {
+		if last := len(segments) - 1; last >= 0 && segments[last].Op == op {
+			segments[last].Text += text
+			return
+		}
+		segments = append(segments, models.DiffSegment{Op: op, Text: text})
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i].Text == b[j].Text:
+			add(models.DiffEqual, a[i].Text)
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			add(models.DiffDelete, a[i].Text)
+			i++
+		default:
+			add(models.DiffInsert, b[j].Text)
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		add(models.DiffDelete, a[i].Text)
+	}
+	for ; j < len(b); j++ {
+		add(models.DiffInsert, b[j].Text)
+	}
+
+	return segments
+}
@@ -0,0 +1,84 @@
+package learning
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/learning/dictionary"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/translation"
+)
+
+// sources of a word gloss
+const (
+	GlossSourceDictionary = "dictionary"
+	GlossSourceTranslator = "translator"
+)
+
+var ErrTokenOutOfRange = errors.New("message has no word at token index")
+
+var dict dictionary.Dictionary
+
+// SetDictionary sets the backend used by GlossWord, glosses fall back to the translator without one
+func SetDictionary(d dictionary.Dictionary) {
+	dict = d
+}
+
+// GlossWord explains the word at tokenIndex of content, counting word tokens only from 0,
+// in targetLang
+func GlossWord(ctx context.Context, content string, langCode string, tokenIndex int, targetLang string) (models.WordGlossResponse, error) {
+
+	words := Words(content)
+	if tokenIndex < 0 || tokenIndex >= len(words) {
+		return models.WordGlossResponse{}, ErrTokenOutOfRange
+	}
+
+	gloss, err := GlossTerm(ctx, words[tokenIndex], langCode, targetLang)
+	if err != nil {
+		return models.WordGlossResponse{}, err
+	}
+	gloss.Token = tokenIndex
+
+	return gloss, nil
+}
+
+// GlossTerm explains a word or phrase written in langCode in targetLang. The dictionary
+// is asked first and the translator fills in terms it does not know
+func GlossTerm(ctx context.Context, term string, langCode string, targetLang string) (models.WordGlossResponse, error) {
+
+	sourceLang := translation.BareLangCode(langCode)
+
+	gloss := models.WordGlossResponse{
+		Word:           term,
+		LangCode:       sourceLang,
+		TargetLangCode: targetLang,
+	}
+	gloss.Level, _ = WordLevel(sourceLang, term)
+
+	if dict != nil {
+		entry, ok, err := dict.Lookup(ctx, sourceLang, term, targetLang)
+		if err != nil {
+			return models.WordGlossResponse{}, fmt.Errorf("unable to look up %q: %w", term, err)
+		}
+		if ok {
+			gloss.Lemma = entry.Lemma
+			gloss.PartOfSpeech = entry.PartOfSpeech
+			gloss.Translation = entry.Translation
+			gloss.Source = GlossSourceDictionary
+			return gloss, nil
+		}
+	}
+
+	translated, err := translation.TranslateText(ctx, term, sourceLang, targetLang)
+	if err != nil {
+		return models.WordGlossResponse{}, fmt.Errorf("unable to translate %q: %w", term, err)
+	}
+
+	gloss.Lemma = strings.ToLower(term)
+	gloss.Translation = translated
+	gloss.Source = GlossSourceTranslator
+
+	return gloss, nil
+}
@@ -0,0 +1,160 @@
+package learning
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"unicode"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/database"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/learning/lexicon"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/translation"
+)
+
+// unknownWordLevel is assumed for words the lexicon has no rating for,
+// they tend to be rarer than the words it does know
+const unknownWordLevel = models.LevelB2
+
+var lex lexicon.Lexicon
+
+// SetLexicon sets the word difficulty source, learning mode is off without one
+func SetLexicon(l lexicon.Lexicon) {
+	lex = l
+}
+
+// WordLevel rates word in langCode, unknown words get unknownWordLevel
+func WordLevel(langCode string, word string) (models.CEFRLevel, bool) {
+	if lex != nil {
+		if level, ok := lex.Level(translation.BareLangCode(langCode), word); ok {
+			return level, true
+		}
+	}
+	return unknownWordLevel, false
+}
+
+// BuildView tokenizes content written in langCode and glosses every word above
+// level in glossLangCode, words repeated in the message are translated once
+func BuildView(ctx context.Context, content string, langCode string, level models.CEFRLevel, glossLangCode string) (*models.LearningView, error) {
+
+	if lex == nil {
+		return nil, fmt.Errorf("no lexicon configured")
+	}
+
+	view := &models.LearningView{
+		LangCode:      translation.BareLangCode(langCode),
+		Level:         level,
+		GlossLangCode: glossLangCode,
+		Tokens:        []models.GlossToken{},
+	}
+
+	glosses := make(map[string]string)
+	for _, token := range Tokenize(content) {
+
+		glossToken := models.GlossToken{Text: token.Text, Word: token.Word}
+
+		if token.Word && !strings.ContainsFunc(token.Text, unicode.IsDigit) {
+			wordLevel, _ := WordLevel(view.LangCode, token.Text)
+			glossToken.Level = wordLevel
+
+			if wordLevel.Rank() > level.Rank() {
+				key := strings.ToLower(token.Text)
+				gloss, ok := glosses[key]
+				if !ok {
+					var err error
+					gloss, err = translation.TranslateText(ctx, token.Text, view.LangCode, glossLangCode)
+					if err != nil {
+						log.Printf("Failed to gloss %q from %s into %s: %v", token.Text, view.LangCode, glossLangCode, err)
+					}
+					glosses[key] = gloss
+				}
+				glossToken.Gloss = gloss
+			}
+		}
+
+		view.Tokens = append(view.Tokens, glossToken)
+	}
+
+	return view, nil
+}
+
+// viewFor builds the learning view of msg for profile, nil when msg is not in one
+// of the profile's target languages or the profile belongs to the sender
+func viewFor(ctx context.Context, profile models.LearningProfile, msg *models.MessageResponse) *models.LearningView {
+
+	if msg.SenderID == profile.UserID || msg.DeletedAt != nil || profile.Native == "" {
+		return nil
+	}
+
+	level, learning := profile.Targets[translation.BareLangCode(msg.OriginalLangCode)]
+	if !learning {
+		return nil
+	}
+
+	view, err := BuildView(ctx, msg.OriginalContent, msg.OriginalLangCode, level, profile.Native)
+	if err != nil {
+		log.Printf("Failed to build learning view of message %s for user %s: %v", msg.ID, profile.UserID, err)
+		return nil
+	}
+
+	return view
+}
+
+// AttachLearningViews builds the learning views of a live message for every participant
+// of its chat with learning mode on, ForViewer picks the right one per client
+func AttachLearningViews(ctx context.Context, msg *models.MessageResponse) error {
+
+	if lex == nil {
+		return nil
+	}
+
+	db := database.GetPostgresConn()
+
+	profiles, err := db.GetLearningProfiles(ctx, msg.ChatID.String())
+	if err != nil {
+		return fmt.Errorf("unable to retrieve learning profiles: %w", err)
+	}
+
+	// live messages have not been resolved for a viewer yet
+	msg.OriginalContent = msg.Content
+	msg.OriginalLangCode = msg.LangCode
+
+	msg.LearningViews = make(map[string]*models.LearningView)
+	for _, profile := range profiles {
+		if view := viewFor(ctx, profile, msg); view != nil {
+			msg.LearningViews[profile.UserID] = view
+		}
+	}
+
+	return nil
+}
+
+// ApplyLearningMode sets the learning view of history messages for userID
+// when learning mode is on for them in chatID
+func ApplyLearningMode(ctx context.Context, chatID string, userID string, messages []models.MessageResponse) error {
+
+	if lex == nil || len(messages) == 0 {
+		return nil
+	}
+
+	db := database.GetPostgresConn()
+
+	profile, enabled, err := db.GetLearningProfile(ctx, chatID, userID)
+	if err != nil {
+		return fmt.Errorf("unable to retrieve learning profile: %w", err)
+	}
+	if !enabled {
+		return nil
+	}
+
+	for i := range messages {
+		if view := viewFor(ctx, profile, &messages[i]); view != nil {
+			messages[i].Learning = view
+			messages[i].Content = messages[i].OriginalContent
+			messages[i].LangCode = messages[i].OriginalLangCode
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package lexicon
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+)
+
+// Lexicon rates how difficult a word is for learners of a language
+type Lexicon interface {
+	// Level returns the CEFR level a word is usually learned at,
+	// ok is false when the word is not known to the lexicon
+	Level(langCode string, word string) (level models.CEFRLevel, ok bool)
+}
+
+//go:embed *.tsv
+var datasets embed.FS
+
+// EmbeddedLexicon is the bundled offline lexicon, one tsv file per language
+type EmbeddedLexicon struct {
+	words map[string]map[string]models.CEFRLevel
+}
+
+// NewEmbeddedLexicon parses every bundled dataset
+func NewEmbeddedLexicon() (*EmbeddedLexicon, error) {
+
+	files, err := datasets.ReadDir(".")
+	if err != nil {
+		return nil, fmt.Errorf("unable to list lexicon datasets: %w", err)
+	}
+
+	lex := &EmbeddedLexicon{words: make(map[string]map[string]models.CEFRLevel)}
+	for _, file := range files {
+		langCode := strings.TrimSuffix(file.Name(), path.Ext(file.Name()))
+
+		data, err := datasets.ReadFile(file.Name())
+		if err != nil {
+			return nil, fmt.Errorf("unable to read lexicon dataset %s: %w", file.Name(), err)
+		}
+
+		words, err := parseDataset(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid lexicon dataset %s: %w", file.Name(), err)
+		}
+		lex.words[langCode] = words
+	}
+
+	return lex, nil
+}
+
+// parseDataset reads word<TAB>level lines, lines starting with # are comments
+func parseDataset(data []byte) (map[string]models.CEFRLevel, error) {
+
+	words := make(map[string]models.CEFRLevel)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		word, levelStr, found := strings.Cut(line, "\t")
+		level := models.CEFRLevel(strings.TrimSpace(levelStr))
+		if !found || !level.Valid() {
+			return nil, fmt.Errorf("line %d: expected word<TAB>level", lineNum)
+		}
+		words[strings.ToLower(strings.TrimSpace(word))] = level
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return words, nil
+}
+
+// Languages returns the lang_codes the lexicon has a dataset for
+func (l *EmbeddedLexicon) Languages() []string {
+	langCodes := make([]string, 0, len(l.words))
+	for langCode := range l.words {
+		langCodes = append(langCodes, langCode)
+	}
+	return langCodes
+}
+
+func (l *EmbeddedLexicon) Level(langCode string, word string) (models.CEFRLevel, bool) {
+
+	words, ok := l.words[langCode]
+	if !ok {
+		return "", false
+	}
+
+	word = strings.ToLower(word)
+	if level, ok := words[word]; ok {
+		return level, true
+	}
+
+	// plural and third person forms are rated like the word they come from
+	for _, suffix := range []string{"es", "s"} {
+		if stem, found := strings.CutSuffix(word, suffix); found && stem != "" {
+			if level, ok := words[stem]; ok {
+				return level, true
+			}
+		}
+	}
+
+	return "", false
+}
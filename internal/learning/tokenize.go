@@ -0,0 +1,65 @@
+package learning
+
+import (
+	"unicode"
+)
+
+// Token is a run of either word or non-word characters of a message,
+// concatenating the Text of every token gives back the message
+type Token struct {
+	Text string
+	Word bool
+}
+
+// Tokenize splits text into words and the whitespace and punctuation between them,
+// apostrophes and hyphens inside a word are kept as part of it. Scripts written
+// without spaces (Chinese, Japanese) get one token per character
+func Tokenize(text string) []Token {
+
+	runes := []rune(text)
+
+	var tokens []Token
+	for i := 0; i < len(runes); {
+		word := isWordRune(runes, i)
+
+		j := i + 1
+		for j < len(runes) && isWordRune(runes, j) == word && !isIdeograph(runes[i]) && !isIdeograph(runes[j]) {
+			j++
+		}
+
+		tokens = append(tokens, Token{Text: string(runes[i:j]), Word: word})
+		i = j
+	}
+
+	return tokens
+}
+
+// Words returns only the word tokens of text, in order
+func Words(text string) []string {
+	var words []string
+	for _, token := range Tokenize(text) {
+		if token.Word {
+			words = append(words, token.Text)
+		}
+	}
+	return words
+}
+
+func isWordRune(runes []rune, i int) bool {
+	r := runes[i]
+	if unicode.IsLetter(r) || unicode.IsMark(r) || unicode.IsDigit(r) {
+		return true
+	}
+
+	// l'homme, don't, peut-être
+	if r == '\'' || r == '’' || r == '-' {
+		return i > 0 && i < len(runes)-1 && unicode.IsLetter(runes[i-1]) && unicode.IsLetter(runes[i+1])
+	}
+
+	return false
+}
+
+// isIdeograph reports whether r belongs to a script that does not separate words with spaces
+func isIdeograph(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana)
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"github.com/gofrs/uuid"
+)
+
+// websocket event types sent to clients of a chat
+const (
+	EventMessageCreated	= "message.created"
+	EventMessageEdited	= "message.edited"
+	EventMessageDeleted	= "message.deleted"
+	EventMessageTranslated	= "message.translated"
+	EventMessageTranscribed	= "message.transcribed"
+	EventReactionAdded	= "reaction.added"
+	EventReactionRemoved	= "reaction.removed"
+	EventCorrectionCreated	= "correction.created"
+	EventCorrectionAccepted	= "correction.accepted"
+	EventChatUpdated	= "chat.updated"
+	EventError		= "error"
+)
+
+// EventProtocolVersion is the ?protocol clients connect with to receive every event in a
+// ChatEvent envelope. Clients connecting without it keep the original protocol, new messages
+// sent as bare message objects and no other events
+const EventProtocolVersion = "2"
+
+// ChatEvent is the envelope for every frame the hub sends to clients of EventProtocolVersion
+type ChatEvent struct {
+	Type		string		`json:"type"`
+	ChatID		uuid.UUID	`json:"chat_id"`
+	Payload		interface{}	`json:"payload"`
+}
+
+// ForViewer resolves message payloads for viewer,
+// other payloads are the same for every viewer
+func (e ChatEvent) ForViewer(viewer Viewer) ChatEvent {
+	if msg, ok := e.Payload.(MessageResponse); ok {
+		e.Payload = msg.ForViewer(viewer)
+	}
+	return e
+}
+
+// ViewerKey groups viewers that receive the same bytes for this event
+func (e ChatEvent) ViewerKey(viewer Viewer) string {
+	msg, ok := e.Payload.(MessageResponse)
+	if ok && msg.LearningViews[viewer.UserID] != nil {
+		return "user:" + viewer.UserID
+	}
+	if ok && viewer.Romanize && msg.Romanization != "" {
+		return "lang:" + viewer.LangCode + ":romanized"
+	}
+	return "lang:" + viewer.LangCode
+}
+
+// Legacy reports whether clients of the original protocol receive this event,
+// as the bare payload resolved for them
+func (e ChatEvent) Legacy() bool {
+	return e.Type == EventMessageCreated
+}
+
+// type EventErrorResponse for telling a client a frame it sent was rejected
+type EventErrorResponse struct {
+	Frame	string	`json:"frame"`
+	Error	string	`json:"error"`
+}
@@ -16,15 +16,75 @@ type User struct {
 	CreatedAt		time.Time		`json:"created_at"`
 }
 
+// CEFRLevel is a learner's proficiency in a target language
+type CEFRLevel string
+
+const (
+	LevelA1	CEFRLevel = "A1"
+	LevelA2	CEFRLevel = "A2"
+	LevelB1	CEFRLevel = "B1"
+	LevelB2	CEFRLevel = "B2"
+	LevelC1	CEFRLevel = "C1"
+	LevelC2	CEFRLevel = "C2"
+)
+
+// cefrRanks orders CEFR levels from beginner to mastery
+var cefrRanks = map[CEFRLevel]int{
+	LevelA1: 1,
+	LevelA2: 2,
+	LevelB1: 3,
+	LevelB2: 4,
+	LevelC1: 5,
+	LevelC2: 6,
+}
+
+// Rank returns the position of the level from 1 (A1) to 6 (C2), 0 when unknown
+func (l CEFRLevel) Rank() int {
+	return cefrRanks[l]
+}
+
+// Valid reports whether l is one of the six CEFR levels
+func (l CEFRLevel) Valid() bool {
+	return l.Rank() > 0
+}
+
+// kinds of user_language rows
+const (
+	LanguageNative	= "native"
+	LanguageTarget	= "target"
+)
+
+// user_language table, the languages a user speaks natively or is learning
+type UserLanguage struct {
+	UserID		string			`json:"user_id"`
+	LangCode	string			`json:"lang_code"`
+	Kind		string			`json:"kind"`
+	Level		sql.NullString	`json:"level"`
+	CreatedAt	time.Time		`json:"created_at"`
+}
+
 type Chat struct {
-	ID			uuid.UUID	`json:"id"`
-	CreatedAt	time.Time	`json:"created_at"`
+	ID			uuid.UUID		`json:"id"`
+	CreatedAt	time.Time		`json:"created_at"`
+	Title		sql.NullString	`json:"title"`
+	Description	sql.NullString	`json:"description"`
+	AvatarURL	sql.NullString	`json:"avatar_url"`
 }
 
+// ChatRole is the value stored in chat_participant.role
+type ChatRole string
+
+const (
+	RoleAdmin		ChatRole = "admin"
+	RoleModerator	ChatRole = "moderator"
+	RoleMember		ChatRole = "member"
+	RoleReadOnly	ChatRole = "read-only"
+)
+
 type ChatParticipant struct {
 	ChatID		uuid.UUID	`json:"chat_id"`
 	UserID		string		`json:"user_id"`
-	Role		string		`json:"role"`
+	Role		ChatRole	`json:"role"`
 	JoinedAt	time.Time	`json:"joined_at"`
 }
 
@@ -35,6 +95,143 @@ type Message struct {
 	Content		string		`json:"content"`
 	CreatedAt	time.Time	`json:"created_at"`
 	LangCode	string      `json:"lang_code"`		
+	DeletedAt	*time.Time	`json:"deleted_at"`
+	// Seq numbers messages in insertion order, only read by the stats refresh
+	Seq			int64		`json:"-"`
+}
+
+type Translation struct {
+	ID			uuid.UUID	`json:"id"`
+	MessageID	uuid.UUID	`json:"message_id"`
+	Content		string		`json:"content"`
+	LangCode	string		`json:"lang_code"`
+	Provider	string		`json:"provider"`
+	CreatedAt	time.Time	`json:"created_at"`
+}
+
+// ratings stored in translation_feedback
+const (
+	FeedbackUp		= 1
+	FeedbackDown	= -1
+)
+
+// translation_feedback table, a reader rating a translation of a message
+type TranslationFeedback struct {
+	ID				uuid.UUID		`json:"id"`
+	TranslationID	uuid.NullUUID	`json:"translation_id"`
+	MessageID		uuid.UUID		`json:"message_id"`
+	UserID			string			`json:"user_id"`
+	Provider		string			`json:"provider"`
+	SourceLangCode	string			`json:"source_lang_code"`
+	TargetLangCode	string			`json:"target_lang_code"`
+	Rating			int				`json:"rating"`
+	CreatedAt		time.Time		`json:"created_at"`
+	UpdatedAt		time.Time		`json:"updated_at"`
+}
+
+// translation_cache table, a translation shared by every message with the same text
+type TranslationCacheEntry struct {
+	Key			string		`json:"key"`
+	SourceHash	string		`json:"source_hash"`
+	SourceLang	string		`json:"source_lang"`
+	TargetLang	string		`json:"target_lang"`
+	Provider	string		`json:"provider"`
+	Scope		string		`json:"scope"`
+	Content		string		`json:"content"`
+	Hits		int64		`json:"hits"`
+	CreatedAt	time.Time	`json:"created_at"`
+	LastUsedAt	time.Time	`json:"last_used_at"`
+}
+
+// message_edit table, one row per replaced version of a message
+type MessageEdit struct {
+	ID			uuid.UUID	`json:"id"`
+	MessageID	uuid.UUID	`json:"message_id"`
+	Content		string		`json:"content"`
+	LangCode	string		`json:"lang_code"`
+	EditedAt	time.Time	`json:"edited_at"`
+}
+
+// vocabulary_item table, a word or phrase a user saved for learning
+type VocabularyItem struct {
+	ID					uuid.UUID		`json:"id"`
+	UserID				string			`json:"user_id"`
+	LangCode			string			`json:"lang_code"`
+	Term				string			`json:"term"`
+	Translation			string			`json:"translation"`
+	TranslationLangCode	string			`json:"translation_lang_code"`
+	Context				string			`json:"context"`
+	MessageID			uuid.NullUUID	`json:"message_id"`
+	Tags				[]string		`json:"tags"`
+	CreatedAt			time.Time		`json:"created_at"`
+	UpdatedAt			time.Time		`json:"updated_at"`
+}
+
+// chat_glossary_term table, how translations of a chat render a term
+type GlossaryTerm struct {
+	ID				uuid.UUID	`json:"id"`
+	ChatID			uuid.UUID	`json:"chat_id"`
+	Term			string		`json:"term"`
+	TargetLangCode	string		`json:"target_lang_code"`
+	Translation		string		`json:"translation"`
+	DoNotTranslate	bool		`json:"do_not_translate"`
+	CreatedBy		string		`json:"created_by"`
+	CreatedAt		time.Time	`json:"created_at"`
+	UpdatedAt		time.Time	`json:"updated_at"`
+}
+
+// review_card table, the spaced repetition state of a vocabulary item
+type ReviewCard struct {
+	ItemID			uuid.UUID	`json:"item_id"`
+	UserID			string		`json:"user_id"`
+	EaseFactor		float64		`json:"ease_factor"`
+	IntervalDays	int			`json:"interval_days"`
+	Repetitions		int			`json:"repetitions"`
+	Lapses			int			`json:"lapses"`
+	DueAt			time.Time	`json:"due_at"`
+	LastReviewedAt	*time.Time	`json:"last_reviewed_at"`
+}
+
+// user_stats_cursor table, how far the statistics of a user have been aggregated
+type StatsCursor struct {
+	UserID			string		`json:"user_id"`
+	Timezone		string		`json:"timezone"`
+	LastSeq			*int64		`json:"last_seq"`
+}
+
+// user_daily_stat table, activity of a user on one day in one language
+type DailyStat struct {
+	UserID			string		`json:"user_id"`
+	Day				time.Time	`json:"day"`
+	LangCode		string		`json:"lang_code"`
+	MessagesSent	int			`json:"messages_sent"`
+	WordsWritten	int			`json:"words_written"`
+}
+
+// JobStatus is the value stored in job.status
+type JobStatus string
+
+const (
+	JobPending	JobStatus = "pending"
+	JobRunning	JobStatus = "running"
+	JobDone		JobStatus = "done"
+	JobDead		JobStatus = "dead"
+)
+
+// job table, a unit of background work of a kind with a JSON payload
+type Job struct {
+	ID			uuid.UUID		`json:"id"`
+	Kind		string			`json:"kind"`
+	Payload		[]byte			`json:"payload"`
+	DedupeKey	sql.NullString	`json:"dedupe_key"`
+	Status		JobStatus		`json:"status"`
+	Attempts	int				`json:"attempts"`
+	MaxAttempts	int				`json:"max_attempts"`
+	RunAt		time.Time		`json:"run_at"`
+	LastError	sql.NullString	`json:"last_error"`
+	LockedAt	*time.Time		`json:"locked_at"`
+	CreatedAt	time.Time		`json:"created_at"`
+	UpdatedAt	time.Time		`json:"updated_at"`
 }
 
 type CreateChatInvite struct {
@@ -46,4 +243,13 @@ type CreateChatInvite struct {
 	ExpDate		time.Time		`json:"exp_date"`
 	Consumed	bool			`json:"consumed"`
 	ConsumedAt	sql.NullTime	`json:"consumed_at"`
-}
\ No newline at end of file
+}
+
+// TranscriptStatus is the value stored in voice_message.transcript_status
+type TranscriptStatus string
+
+const (
+	TranscriptPending	TranscriptStatus = "pending"
+	TranscriptDone		TranscriptStatus = "done"
+	TranscriptFailed	TranscriptStatus = "failed"
+)
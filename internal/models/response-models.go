@@ -1,6 +1,8 @@
 package models
 
 import (
+	"encoding/json"
+	"strings"
 	"time"
 
 	"github.com/gofrs/uuid"
@@ -8,9 +10,23 @@ import (
 
 type ChatResponse struct {
 	ID 				string		`json:"chatId"`
+	Title			string		`json:"title"`
+	Description		string		`json:"description"`
+	AvatarURL		string		`json:"avatar_url"`
 	Participants	[]string	`json:"participants"`
 	LastMessage		string		`json:"last_message"`
 	LastMessageTime	time.Time	`json:"last_message_time"`
+	LastMessageDeleted	bool	`json:"last_message_deleted"`
+	LastMessagePreview	TranslationPair	`json:"last_message_preview"`
+}
+
+// type TranslationPair for showing what a sender wrote next to its translation for the viewer
+type TranslationPair struct {
+	OriginalContent		string	`json:"original_content"`
+	OriginalLangCode	string	`json:"original_lang_code"`
+	TranslatedContent	string	`json:"translated_content"`
+	TranslatedLangCode	string	`json:"translated_lang_code"`
+	HasTranslation		bool	`json:"has_translation"`
 }
 
 // type ChatMessagesResponse for sending chat messages data
@@ -22,6 +38,141 @@ type MessageResponse struct {
 	Content		string		`json:"content"`
 	CreatedAt	time.Time	`json:"created_at"`
 	LangCode	string		`json:"lang_code"`
+	DetectedLangCode	string	`json:"detected_lang_code,omitempty"`
+	LangConfidence	float64		`json:"lang_confidence,omitempty"`
+	EditedAt	*time.Time	`json:"edited_at,omitempty"`
+	DeletedAt	*time.Time	`json:"deleted_at,omitempty"`
+	ReplyToID	*uuid.UUID	`json:"reply_to_id,omitempty"`
+	ReplyTo		*MessageSnippet	`json:"reply_to,omitempty"`
+	Reactions	[]ReactionCount	`json:"reactions,omitempty"`
+	Corrections	[]CorrectionResponse	`json:"corrections,omitempty"`
+	TranslationPair
+	Learning	*LearningView	`json:"learning,omitempty"`
+	Romanization	string		`json:"romanization,omitempty"`
+	RomanizationScheme	string	`json:"romanization_scheme,omitempty"`
+	Voice		*VoiceAttachment	`json:"voice,omitempty"`
+
+	// translations of a live message keyed by bare lang_code, resolved per viewer by ForViewer
+	Translations	map[string]string	`json:"-"`
+	// learning mode views of a live message keyed by user id
+	LearningViews	map[string]*LearningView	`json:"-"`
+}
+
+// Viewer is the user a message is resolved for
+type Viewer struct {
+	UserID		string
+	LangCode	string
+	Romanize	bool
+}
+
+// ForViewer returns a copy of a live message with content, the translation pair
+// and any learning mode view resolved for viewer, romanization is only kept when viewer opted in
+func (m MessageResponse) ForViewer(viewer Viewer) MessageResponse {
+
+	langCode := viewer.LangCode
+
+	m.OriginalContent = m.Content
+	m.OriginalLangCode = m.LangCode
+	m.TranslatedContent = ""
+	m.TranslatedLangCode = ""
+	m.HasTranslation = false
+
+	if translated, ok := m.Translations[langCode]; ok && langCode != "" && strings.Trim(m.LangCode, "{}") != langCode {
+		// lang_code of translations is sent the same way message history returns it
+		m.TranslatedContent = translated
+		m.TranslatedLangCode = "{" + langCode + "}"
+		m.HasTranslation = true
+		m.Content = m.TranslatedContent
+		m.LangCode = m.TranslatedLangCode
+	}
+
+	// the quoted message is shown in the viewer's language as well
+	if m.ReplyTo != nil {
+		if translated, ok := m.ReplyTo.Translations[langCode]; ok && langCode != "" && strings.Trim(m.ReplyTo.LangCode, "{}") != langCode {
+			replyTo := *m.ReplyTo
+			replyTo.Content = translated
+			m.ReplyTo = &replyTo
+		}
+	}
+
+	// learners read the original with difficult words glossed instead of the translation
+	if view := m.LearningViews[viewer.UserID]; view != nil {
+		m.Learning = view
+		m.Content = m.OriginalContent
+		m.LangCode = m.OriginalLangCode
+	}
+
+	if !viewer.Romanize {
+		m.Romanization = ""
+		m.RomanizationScheme = ""
+	}
+
+	return m
+}
+
+// type LearningView for showing a message in a learner's target language
+// with the words above their level glossed in their native language
+type LearningView struct {
+	LangCode	string			`json:"lang_code"`
+	Level		CEFRLevel		`json:"level"`
+	GlossLangCode	string		`json:"gloss_lang_code"`
+	Tokens		[]GlossToken	`json:"tokens"`
+}
+
+type GlossToken struct {
+	Text	string		`json:"text"`
+	Word	bool		`json:"word"`
+	Level	CEFRLevel	`json:"level,omitempty"`
+	Gloss	string		`json:"gloss,omitempty"`
+}
+
+// LearningProfile is what learning mode needs to know about a participant
+type LearningProfile struct {
+	UserID		string
+	Native		string
+	Targets		map[string]CEFRLevel
+}
+
+// type ReactionCount for the aggregated reactions of a message
+type ReactionCount struct {
+	Emoji		string	`json:"emoji"`
+	Count		int		`json:"count"`
+	ReactedByMe	bool	`json:"reacted_by_me"`
+}
+
+// type ReactionResponse for notifying clients of a reaction change
+type ReactionResponse struct {
+	MessageID	uuid.UUID	`json:"message_id"`
+	ChatID		uuid.UUID	`json:"chat_id"`
+	UserID		string		`json:"user_id"`
+	Emoji		string		`json:"emoji"`
+	Added		bool		`json:"added"`
+}
+
+// type MessageSnippet for quoting the message a reply refers to
+type MessageSnippet struct {
+	ID				uuid.UUID	`json:"id"`
+	SenderUsername	string		`json:"sender_username"`
+	Content			string		`json:"content"`
+	Deleted			bool		`json:"deleted"`
+	LangCode		string				`json:"-"`
+	Translations	map[string]string	`json:"-"`
+}
+
+// type MessageDeletedResponse for notifying clients of a deleted message
+type MessageDeletedResponse struct {
+	MessageID	uuid.UUID	`json:"message_id"`
+	ChatID		uuid.UUID	`json:"chat_id"`
+	DeletedBy	string		`json:"deleted_by"`
+	DeletedAt	time.Time	`json:"deleted_at"`
+	// VoiceBlobKey is the recording of a deleted voice message, left to remove from the blob store
+	VoiceBlobKey	string		`json:"-"`
+	// DeletedContents are the versions of the deleted text, left to purge from the translation cache
+	DeletedContents	[]string	`json:"-"`
+	// DeletedTranslations and DeletedLangCodes are the cleared translations and every language
+	// the message was in, left with DeletedContents to purge synthesized speech of
+	DeletedTranslations	[]string	`json:"-"`
+	DeletedLangCodes	[]string	`json:"-"`
 }
 
 type InviteResponse struct {
@@ -30,3 +181,193 @@ type InviteResponse struct {
 	Username 			string	`json:"username"`
 }
 
+
+type ParticipantResponse struct {
+	UserID		string		`json:"user_id"`
+	Username	string		`json:"username"`
+	Role		ChatRole	`json:"role"`
+	JoinedAt	time.Time	`json:"joined_at"`
+}
+
+// type ChatMetadataResponse for sending editable chat details
+type ChatMetadataResponse struct {
+	ID			uuid.UUID	`json:"chat_id"`
+	Title		string		`json:"title"`
+	Description	string		`json:"description"`
+	AvatarURL	string		`json:"avatar_url"`
+	UpdatedBy	string		`json:"updated_by,omitempty"`
+}
+
+// type VoiceAttachment for the recording of a voice message, its audio is streamed separately
+type VoiceAttachment struct {
+	BlobKey			string				`json:"-"`
+	ContentType		string				`json:"content_type"`
+	SizeBytes		int64				`json:"size_bytes"`
+	DurationMs		*int				`json:"duration_ms,omitempty"`
+	TranscriptStatus	TranscriptStatus	`json:"transcript_status"`
+	TranscribedAt	*time.Time			`json:"transcribed_at,omitempty"`
+}
+
+// type LanguageResponse for describing a language of the supported-language registry
+type LanguageResponse struct {
+	Code		string	`json:"code"`
+	Name		string	`json:"name"`
+	NativeName	string	`json:"native_name"`
+	Script		string	`json:"script"`
+	Direction	string	`json:"direction"`
+	Translation	bool	`json:"translation"`
+}
+
+// type UserLanguagesResponse for sending and receiving the languages of a user
+type UserLanguagesResponse struct {
+	Native	string				`json:"native"`
+	Targets	[]TargetLanguage	`json:"targets"`
+}
+
+type TargetLanguage struct {
+	LangCode	string		`json:"lang_code"`
+	Level		CEFRLevel	`json:"level"`
+}
+
+// type WordGlossResponse for explaining a single word of a message
+type WordGlossResponse struct {
+	MessageID		uuid.UUID	`json:"message_id"`
+	Token			int			`json:"token"`
+	Word			string		`json:"word"`
+	LangCode		string		`json:"lang_code"`
+	Lemma			string		`json:"lemma"`
+	PartOfSpeech	string		`json:"part_of_speech"`
+	Translation		string		`json:"translation"`
+	TargetLangCode	string		`json:"target_lang_code"`
+	Level			CEFRLevel	`json:"level"`
+	Source			string		`json:"source"`
+}
+
+// type GlossaryTermRequest for adding or changing a term of a chat glossary,
+// an empty target_lang_code applies the term to every language
+type GlossaryTermRequest struct {
+	Term			string	`json:"term"`
+	TargetLangCode	string	`json:"target_lang_code"`
+	Translation		string	`json:"translation"`
+	DoNotTranslate	bool	`json:"do_not_translate"`
+}
+
+// type VocabularyRequest for saving or updating a vocabulary item,
+// fields left empty are filled in from the source message when there is one
+type VocabularyRequest struct {
+	Term		string		`json:"term"`
+	LangCode	string		`json:"lang_code"`
+	MessageID	*uuid.UUID	`json:"message_id"`
+	Context		string		`json:"context"`
+	Translation	string		`json:"translation"`
+	Tags		[]string	`json:"tags"`
+}
+
+// type VocabularyExport for downloading the vocabulary of one language
+type VocabularyExport struct {
+	LangCode	string				`json:"lang_code"`
+	ExportedAt	time.Time			`json:"exported_at"`
+	Items		[]VocabularyItem	`json:"items"`
+}
+
+// type DueCardResponse for a vocabulary item waiting to be reviewed
+type DueCardResponse struct {
+	Item	VocabularyItem	`json:"item"`
+	Card	ReviewCard		`json:"card"`
+	New		bool			`json:"new"`
+}
+
+// type ReviewStatsResponse for summarizing a user's flashcard practice
+type ReviewStatsResponse struct {
+	LangCode		string	`json:"lang_code,omitempty"`
+	TotalCards		int		`json:"total_cards"`
+	NewCards		int		`json:"new_cards"`
+	DueCards		int		`json:"due_cards"`
+	MatureCards		int		`json:"mature_cards"`
+	ReviewsToday	int		`json:"reviews_today"`
+	ReviewsWeek		int		`json:"reviews_week"`
+	RetentionRate	float64	`json:"retention_rate"`
+}
+
+// diff operations of a correction
+const (
+	DiffEqual	= "equal"
+	DiffInsert	= "insert"
+	DiffDelete	= "delete"
+)
+
+// type DiffSegment for a run of text a correction kept, added or removed
+type DiffSegment struct {
+	Op		string	`json:"op"`
+	Text	string	`json:"text"`
+}
+
+// type CorrectionResponse for a correction of a message by another participant,
+// Diff turns OriginalContent into Content
+type CorrectionResponse struct {
+	ID					uuid.UUID		`json:"id"`
+	MessageID			uuid.UUID		`json:"message_id"`
+	ChatID				uuid.UUID		`json:"chat_id"`
+	CorrectorID			string			`json:"corrector_id"`
+	CorrectorUsername	string			`json:"corrector_username"`
+	OriginalContent		string			`json:"original_content"`
+	Content				string			`json:"content"`
+	Diff				[]DiffSegment	`json:"diff"`
+	CreatedAt			time.Time		`json:"created_at"`
+	AcceptedAt			*time.Time		`json:"accepted_at"`
+}
+
+// type LanguageStats for the activity of a user in one language
+type LanguageStats struct {
+	LangCode			string	`json:"lang_code"`
+	Kind				string	`json:"kind"`
+	MessagesSent		int		`json:"messages_sent"`
+	WordsWritten		int		`json:"words_written"`
+	WordsUsed			int		`json:"words_used"`
+	CorrectionsReceived	int		`json:"corrections_received"`
+	DaysActive			int		`json:"days_active"`
+}
+
+// type UserStatsResponse for a user's learning statistics, days are counted in Timezone
+type UserStatsResponse struct {
+	Timezone			string			`json:"timezone"`
+	MessagesNative		int				`json:"messages_native"`
+	MessagesTarget		int				`json:"messages_target"`
+	MessagesOther		int				`json:"messages_other"`
+	DaysActive			int				`json:"days_active"`
+	CurrentStreak		int				`json:"current_streak"`
+	LongestStreak		int				`json:"longest_streak"`
+	LastActiveDay		string			`json:"last_active_day,omitempty"`
+	Languages			[]LanguageStats	`json:"languages"`
+}
+
+// type TranslationCacheStatsResponse for the hit rate of the translation cache since startup
+type TranslationCacheStatsResponse struct {
+	MemoryHits		int64	`json:"memory_hits"`
+	StoreHits		int64	`json:"store_hits"`
+	Misses			int64	`json:"misses"`
+	HitRate			float64	`json:"hit_rate"`
+	MemoryEntries	int		`json:"memory_entries"`
+	MemoryCapacity	int		`json:"memory_capacity"`
+}
+
+// type TranslationFeedbackStats for the feedback of one provider and language pair
+type TranslationFeedbackStats struct {
+	Provider		string	`json:"provider"`
+	SourceLangCode	string	`json:"source_lang_code"`
+	TargetLangCode	string	`json:"target_lang_code"`
+	Up				int64	`json:"up"`
+	Down			int64	`json:"down"`
+	Approval		float64	`json:"approval"`
+}
+
+// type DeadJobResponse for inspecting a background job that failed for good
+type DeadJobResponse struct {
+	ID			uuid.UUID		`json:"id"`
+	Kind		string			`json:"kind"`
+	Payload		json.RawMessage	`json:"payload"`
+	Attempts	int				`json:"attempts"`
+	LastError	string			`json:"last_error"`
+	CreatedAt	time.Time		`json:"created_at"`
+	FailedAt	time.Time		`json:"failed_at"`
+}
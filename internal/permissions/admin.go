@@ -0,0 +1,34 @@
+package permissions
+
+import (
+	"strings"
+	"sync"
+)
+
+var (
+	adminsMu sync.RWMutex
+	admins   = make(map[string]bool)
+)
+
+// SetAdmins sets the users allowed on operator endpoints such as translation dashboards,
+// nobody is an admin until it is called
+func SetAdmins(userIDs []string) {
+
+	next := make(map[string]bool, len(userIDs))
+	for _, userID := range userIDs {
+		if userID = strings.TrimSpace(userID); userID != "" {
+			next[userID] = true
+		}
+	}
+
+	adminsMu.Lock()
+	defer adminsMu.Unlock()
+	admins = next
+}
+
+// IsAdmin reports whether userID may use operator endpoints
+func IsAdmin(userID string) bool {
+	adminsMu.RLock()
+	defer adminsMu.RUnlock()
+	return admins[userID]
+}
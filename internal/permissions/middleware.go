@@ -0,0 +1,78 @@
+package permissions
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+)
+
+// RequireChatPermission aborts requests whose user may not perform action in the
+// chat identified by the chatID path parameter, sets "chatRole" for handlers
+func RequireChatPermission(action Action) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		userIDAny, exists := c.Get("userID")
+		if !exists {
+			log.Println("user_id missing")
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+
+		userID, ok := userIDAny.(string)
+		if !ok {
+			log.Println("Failed to convert user_id to string")
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Invalid request"})
+			return
+		}
+
+		chatID := c.Param("chatID")
+		if len(chatID) == 0 {
+			log.Println("ChatId path parameter missing")
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+		if _, err := uuid.FromString(chatID); err != nil {
+			log.Printf("Invalid chatID path parameter: %v", err)
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+
+		role, err := Authorize(c.Request.Context(), chatID, userID, action)
+		if err != nil {
+			switch {
+			case errors.Is(err, ErrNotParticipant):
+				log.Printf("User %s is not a participant of chat %s", userID, chatID)
+				c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Chat not found"})
+			case errors.Is(err, ErrForbidden):
+				log.Printf("User %s with role %s not permitted to %s in chat %s", userID, role, action, chatID)
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			default:
+				log.Printf("Failed to authorize chat action: %v", err)
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			}
+			return
+		}
+
+		c.Set("chatRole", role)
+		c.Next()
+	}
+}
+
+// RequireAdmin aborts requests of users that are not admins of the deployment
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		userID, _ := c.Get("userID")
+		userIDStr, ok := userID.(string)
+		if !ok || !IsAdmin(userIDStr) {
+			log.Printf("User %v not permitted on admin endpoint %s", userID, c.FullPath())
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,117 @@
+package permissions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/database"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// Action is something a chat participant may attempt inside a chat
+type Action string
+
+const (
+	ActionViewChat            Action = "view_chat"
+	ActionSendMessage         Action = "send_message"
+	ActionReact               Action = "react"
+	ActionCorrectMessage      Action = "correct_message"
+	ActionEditGlossary        Action = "edit_glossary"
+	ActionInvite              Action = "invite"
+	ActionRemoveParticipant   Action = "remove_participant"
+	ActionChangeRole          Action = "change_role"
+	ActionRenameChat          Action = "rename_chat"
+	ActionPinMessage          Action = "pin_message"
+	ActionDeleteOthersMessage Action = "delete_others_message"
+)
+
+var (
+	ErrNotParticipant = errors.New("user is not a participant of chat")
+	ErrForbidden      = errors.New("role does not permit action")
+)
+
+// rolePermissions maps every role to the actions it is allowed to perform
+var rolePermissions = map[models.ChatRole]map[Action]bool{
+	models.RoleAdmin: {
+		ActionViewChat:            true,
+		ActionSendMessage:         true,
+		ActionReact:               true,
+		ActionCorrectMessage:      true,
+		ActionEditGlossary:        true,
+		ActionInvite:              true,
+		ActionRemoveParticipant:   true,
+		ActionChangeRole:          true,
+		ActionRenameChat:          true,
+		ActionPinMessage:          true,
+		ActionDeleteOthersMessage: true,
+	},
+	models.RoleModerator: {
+		ActionViewChat:            true,
+		ActionSendMessage:         true,
+		ActionReact:               true,
+		ActionCorrectMessage:      true,
+		ActionEditGlossary:        true,
+		ActionInvite:              true,
+		ActionRemoveParticipant:   true,
+		ActionRenameChat:          true,
+		ActionPinMessage:          true,
+		ActionDeleteOthersMessage: true,
+	},
+	models.RoleMember: {
+		ActionViewChat:       true,
+		ActionSendMessage:    true,
+		ActionReact:          true,
+		ActionCorrectMessage: true,
+		ActionEditGlossary:   true,
+	},
+	models.RoleReadOnly: {
+		ActionViewChat: true,
+	},
+}
+
+// roleRank orders roles so that a participant can only act on roles below their own
+var roleRank = map[models.ChatRole]int{
+	models.RoleAdmin:     3,
+	models.RoleModerator: 2,
+	models.RoleMember:    1,
+	models.RoleReadOnly:  0,
+}
+
+// ValidRole reports whether role is one of the known chat roles
+func ValidRole(role models.ChatRole) bool {
+	_, ok := roleRank[role]
+	return ok
+}
+
+// Can reports whether role is allowed to perform action
+func Can(role models.ChatRole, action Action) bool {
+	return rolePermissions[role][action]
+}
+
+// Outranks reports whether role is strictly above other
+func Outranks(role models.ChatRole, other models.ChatRole) bool {
+	return roleRank[role] > roleRank[other]
+}
+
+// Authorize looks up the role of userID in chatID and checks it against action,
+// returns the role on success so callers can make further decisions with it
+func Authorize(ctx context.Context, chatID string, userID string, action Action) (models.ChatRole, error) {
+
+	db := database.GetPostgresConn()
+
+	role, err := db.GetParticipantRole(ctx, chatID, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrNotParticipant
+		}
+		return "", fmt.Errorf("unable to retrieve participant role: %w", err)
+	}
+
+	if !Can(role, action) {
+		return role, ErrForbidden
+	}
+
+	return role, nil
+}
@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Limiter allows every key a number of events per fixed window of time
+type Limiter struct {
+	mu      sync.Mutex
+	limit   int
+	window  time.Duration
+	windows map[string]*window
+}
+
+type window struct {
+	start time.Time
+	count int
+}
+
+// New returns a limiter allowing limit events per key in every window of length per
+func New(limit int, per time.Duration) *Limiter {
+	return &Limiter{
+		limit:   limit,
+		window:  per,
+		windows: make(map[string]*window),
+	}
+}
+
+// Allow counts an event of key and reports whether it stays within the limit
+func (l *Limiter) Allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) >= l.window {
+		// windows of idle keys are dropped along the way so the map stays bounded by active keys
+		if !ok && len(l.windows) >= 1024 {
+			l.sweep(now)
+		}
+		w = &window{start: now}
+		l.windows[key] = w
+	}
+
+	if w.count >= l.limit {
+		return false
+	}
+	w.count++
+	return true
+}
+
+// sweep drops the windows that have ended
+func (l *Limiter) sweep(now time.Time) {
+	for key, w := range l.windows {
+		if now.Sub(w.start) >= l.window {
+			delete(l.windows, key)
+		}
+	}
+}
+
+// PerUser aborts requests of users that went over the limit of limiter
+func PerUser(limiter *Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		userID := c.GetString("userID")
+		if !limiter.Allow(userID) {
+			log.Printf("User %s rate limited on %s", userID, c.FullPath())
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests"})
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,93 @@
+package srs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/database"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// grades follow SM-2, 0 is a complete blackout and 5 a perfect recall
+const (
+	MinGrade  = 0
+	MaxGrade  = 5
+	passGrade = 3
+
+	minEaseFactor = 1.3
+)
+
+var ErrInvalidGrade = errors.New("grade must be between 0 and 5")
+
+// Schedule applies an SM-2 review with grade at now to card and returns the rescheduled card
+func Schedule(card models.ReviewCard, grade int, now time.Time) models.ReviewCard {
+
+	if grade >= passGrade {
+		switch card.Repetitions {
+		case 0:
+			card.IntervalDays = 1
+		case 1:
+			card.IntervalDays = 6
+		default:
+			card.IntervalDays = int(math.Round(float64(card.IntervalDays) * card.EaseFactor))
+		}
+		card.Repetitions++
+	} else {
+		// forgotten cards start over, a lapse is only counted once the card was learned
+		if card.Repetitions > 0 {
+			card.Lapses++
+		}
+		card.Repetitions = 0
+		card.IntervalDays = 1
+	}
+
+	missed := float64(MaxGrade - grade)
+	card.EaseFactor = math.Max(minEaseFactor, card.EaseFactor+0.1-missed*(0.08+missed*0.02))
+
+	card.DueAt = now.AddDate(0, 0, card.IntervalDays)
+	card.LastReviewedAt = &now
+
+	return card
+}
+
+// Review grades a vocabulary item of userID and stores its next review,
+// pgx.ErrNoRows is returned when the item is not in the user's vocabulary
+func Review(ctx context.Context, userID string, itemID string, grade int) (models.DueCardResponse, error) {
+
+	if grade < MinGrade || grade > MaxGrade {
+		return models.DueCardResponse{}, ErrInvalidGrade
+	}
+
+	db := database.GetPostgresConn()
+
+	// the card is read and rescheduled under a lock so concurrent reviews do not overwrite each other
+	tx, err := db.Pool().BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return models.DueCardResponse{}, fmt.Errorf("unable to begin review transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	dueCard, err := db.LockReviewCard(ctx, tx, userID, itemID)
+	if err != nil {
+		return models.DueCardResponse{}, err
+	}
+
+	dueCard.Card = Schedule(dueCard.Card, grade, time.Now().UTC())
+	dueCard.New = false
+
+	err = db.SaveReview(ctx, tx, &dueCard.Card, grade)
+	if err != nil {
+		return models.DueCardResponse{}, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return models.DueCardResponse{}, fmt.Errorf("unable to commit review transaction: %w", err)
+	}
+
+	return dueCard, nil
+}
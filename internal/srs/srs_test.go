@@ -0,0 +1,51 @@
+package srs
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+)
+
+func TestSchedule(t *testing.T) {
+
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name            string
+		card            models.ReviewCard
+		grade           int
+		wantInterval    int
+		wantRepetitions int
+		wantLapses      int
+		wantEaseFactor  float64
+	}{
+		{"first repetition", models.ReviewCard{EaseFactor: 2.5}, 4, 1, 1, 0, 2.5},
+		{"second repetition", models.ReviewCard{EaseFactor: 2.5, IntervalDays: 1, Repetitions: 1}, 5, 6, 2, 0, 2.6},
+		{"later repetition", models.ReviewCard{EaseFactor: 2.5, IntervalDays: 6, Repetitions: 2}, 3, 15, 3, 0, 2.36},
+		{"lapse of a learned card", models.ReviewCard{EaseFactor: 2.5, IntervalDays: 15, Repetitions: 3}, 1, 1, 0, 1, 1.96},
+		{"forgotten new card", models.ReviewCard{EaseFactor: 2.5}, 2, 1, 0, 0, 2.18},
+		{"ease factor floor", models.ReviewCard{EaseFactor: 1.4, IntervalDays: 6, Repetitions: 2, Lapses: 2}, 0, 1, 0, 3, 1.3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Schedule(tt.card, tt.grade, now)
+
+			if got.IntervalDays != tt.wantInterval || got.Repetitions != tt.wantRepetitions || got.Lapses != tt.wantLapses {
+				t.Errorf("Schedule() interval %d, repetitions %d, lapses %d, want %d, %d, %d",
+					got.IntervalDays, got.Repetitions, got.Lapses, tt.wantInterval, tt.wantRepetitions, tt.wantLapses)
+			}
+			if math.Abs(got.EaseFactor-tt.wantEaseFactor) > 1e-9 {
+				t.Errorf("Schedule() ease factor = %v, want %v", got.EaseFactor, tt.wantEaseFactor)
+			}
+			if want := now.AddDate(0, 0, tt.wantInterval); !got.DueAt.Equal(want) {
+				t.Errorf("Schedule() due at %v, want %v", got.DueAt, want)
+			}
+			if got.LastReviewedAt == nil || !got.LastReviewedAt.Equal(now) {
+				t.Errorf("Schedule() last reviewed at %v, want %v", got.LastReviewedAt, now)
+			}
+		})
+	}
+}
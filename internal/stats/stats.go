@@ -0,0 +1,355 @@
+package stats
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/database"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/learning"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/translation"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// messages counted per refresh batch
+	refreshBatchSize = 500
+
+	// messages younger than this are left to the next refresh, so one whose insert commits
+	// after a later message of the same sender is not passed by the cursor
+	settleDelay = 10 * time.Second
+)
+
+// kind of a language a user wrote in that is neither native nor target
+const kindOther = "other"
+
+// Refresh counts the messages userID sent since the last refresh into daily stats bucketed in
+// the timezone set with SetTimezone, UTC until one is set
+func Refresh(ctx context.Context, userID string) error {
+
+	db := database.GetPostgresConn()
+
+	tx, err := db.Pool().BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("unable to begin stats transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	cursor, err := db.LockStatsCursor(ctx, tx, userID, time.UTC.String())
+	if err != nil {
+		return err
+	}
+
+	location, err := time.LoadLocation(cursor.Timezone)
+	if err != nil {
+		return fmt.Errorf("unable to load stats timezone %s: %w", cursor.Timezone, err)
+	}
+
+	settledBefore := time.Now().Add(-settleDelay)
+	for {
+		messages, err := db.GetMessagesAfterCursor(ctx, tx, cursor, settledBefore, refreshBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(messages) == 0 {
+			break
+		}
+
+		err = countMessages(ctx, tx, userID, messages, location, 1)
+		if err != nil {
+			return err
+		}
+
+		last := messages[len(messages)-1]
+		err = db.AdvanceStatsCursor(ctx, tx, userID, last)
+		if err != nil {
+			return err
+		}
+		cursor.LastSeq = &last.Seq
+
+		if len(messages) < refreshBatchSize {
+			break
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// SetTimezone sets the timezone the days of userID are counted in. Stats counted in another
+// timezone are dropped and counted again from the first message on the next refresh
+func SetTimezone(ctx context.Context, userID string, location *time.Location) error {
+
+	db := database.GetPostgresConn()
+
+	tx, err := db.Pool().BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("unable to begin stats transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	cursor, err := db.LockStatsCursor(ctx, tx, userID, location.String())
+	if err != nil {
+		return err
+	}
+
+	if cursor.Timezone == location.String() {
+		return nil
+	}
+
+	err = db.ResetUserStats(ctx, tx, userID, location.String())
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Recount moves the contribution of a message already counted into the stats of its sender
+// from before to after, when it is edited, transcribed or, with after nil, deleted for everyone.
+// Messages a refresh has not reached yet are left to it
+func Recount(ctx context.Context, before models.Message, after *models.Message) error {
+
+	if before.DeletedAt != nil {
+		return nil
+	}
+
+	db := database.GetPostgresConn()
+
+	tx, err := db.Pool().BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("unable to begin stats transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	cursor, err := db.LockStatsCursor(ctx, tx, before.SenderID, time.UTC.String())
+	if err != nil {
+		return err
+	}
+
+	counted, err := db.IsMessageCounted(ctx, tx, cursor, before.ID.String())
+	if err != nil {
+		return err
+	}
+	if !counted {
+		return nil
+	}
+
+	location, err := time.LoadLocation(cursor.Timezone)
+	if err != nil {
+		return fmt.Errorf("unable to load stats timezone %s: %w", cursor.Timezone, err)
+	}
+
+	err = countMessages(ctx, tx, before.SenderID, []models.Message{before}, location, -1)
+	if err != nil {
+		return err
+	}
+
+	if after != nil {
+		err = countMessages(ctx, tx, before.SenderID, []models.Message{*after}, location, 1)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = db.DeleteEmptyStats(ctx, tx, before.SenderID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// countMessages adds a batch of messages to the daily stats and word uses of userID, or takes
+// them away with sign -1. Messages deleted before they were counted are skipped
+func countMessages(ctx context.Context, tx pgx.Tx, userID string, messages []models.Message, location *time.Location, sign int) error {
+
+	db := database.GetPostgresConn()
+
+	type dayKey struct {
+		day      time.Time
+		langCode string
+	}
+	dailyStats := make(map[dayKey]*models.DailyStat)
+	wordUses := make(map[string]map[string]int)
+
+	for _, msg := range messages {
+		if msg.DeletedAt != nil {
+			continue
+		}
+
+		langCode := translation.BareLangCode(msg.LangCode)
+		local := msg.CreatedAt.In(location)
+		key := dayKey{day: time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC), langCode: langCode}
+
+		dailyStat, ok := dailyStats[key]
+		if !ok {
+			dailyStat = &models.DailyStat{UserID: userID, Day: key.day, LangCode: langCode}
+			dailyStats[key] = dailyStat
+		}
+
+		words := learning.Words(msg.Content)
+		dailyStat.MessagesSent += sign
+		dailyStat.WordsWritten += sign * len(words)
+
+		if wordUses[langCode] == nil {
+			wordUses[langCode] = make(map[string]int)
+		}
+		for _, word := range words {
+			wordUses[langCode][strings.ToLower(word)] += sign
+		}
+	}
+
+	flattened := make([]models.DailyStat, 0, len(dailyStats))
+	for _, dailyStat := range dailyStats {
+		flattened = append(flattened, *dailyStat)
+	}
+
+	err := db.AddDailyStats(ctx, tx, flattened)
+	if err != nil {
+		return err
+	}
+
+	usedAt := messages[len(messages)-1].CreatedAt
+	for langCode, words := range wordUses {
+		err := db.AddWordUses(ctx, tx, userID, langCode, words, usedAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Get refreshes and returns the learning statistics of userID with days counted in their timezone
+func Get(ctx context.Context, userID string) (models.UserStatsResponse, error) {
+
+	err := Refresh(ctx, userID)
+	if err != nil {
+		return models.UserStatsResponse{}, err
+	}
+
+	db := database.GetPostgresConn()
+
+	timezone, err := db.GetStatsTimezone(ctx, userID)
+	if err != nil {
+		return models.UserStatsResponse{}, err
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return models.UserStatsResponse{}, fmt.Errorf("unable to load stats timezone %s: %w", timezone, err)
+	}
+
+	dailyStats, err := db.GetDailyStats(ctx, userID)
+	if err != nil {
+		return models.UserStatsResponse{}, err
+	}
+
+	wordsUsed, err := db.GetWordsUsed(ctx, userID)
+	if err != nil {
+		return models.UserStatsResponse{}, err
+	}
+
+	correctionsReceived, err := db.GetCorrectionsReceived(ctx, userID)
+	if err != nil {
+		return models.UserStatsResponse{}, err
+	}
+
+	languages, err := db.GetUserLanguages(ctx, userID)
+	if err != nil {
+		return models.UserStatsResponse{}, err
+	}
+
+	kinds := map[string]string{languages.Native: models.LanguageNative}
+	for _, target := range languages.Targets {
+		kinds[target.LangCode] = models.LanguageTarget
+	}
+
+	stats := models.UserStatsResponse{Timezone: location.String(), Languages: []models.LanguageStats{}}
+
+	byLangCode := make(map[string]*models.LanguageStats)
+	languageStats := func(langCode string) *models.LanguageStats {
+		if byLangCode[langCode] == nil {
+			kind, ok := kinds[langCode]
+			if !ok {
+				kind = kindOther
+			}
+			byLangCode[langCode] = &models.LanguageStats{LangCode: langCode, Kind: kind}
+		}
+		return byLangCode[langCode]
+	}
+
+	var activeDays []time.Time
+	for _, dailyStat := range dailyStats {
+		language := languageStats(dailyStat.LangCode)
+		language.MessagesSent += dailyStat.MessagesSent
+		language.WordsWritten += dailyStat.WordsWritten
+		language.DaysActive++
+
+		switch language.Kind {
+		case models.LanguageNative:
+			stats.MessagesNative += dailyStat.MessagesSent
+		case models.LanguageTarget:
+			stats.MessagesTarget += dailyStat.MessagesSent
+		default:
+			stats.MessagesOther += dailyStat.MessagesSent
+		}
+
+		// daily stats are ordered newest day first
+		if len(activeDays) == 0 || !activeDays[len(activeDays)-1].Equal(dailyStat.Day) {
+			activeDays = append(activeDays, dailyStat.Day)
+		}
+	}
+
+	for langCode, count := range wordsUsed {
+		languageStats(langCode).WordsUsed = count
+	}
+	for langCode, count := range correctionsReceived {
+		languageStats(langCode).CorrectionsReceived = count
+	}
+
+	for _, language := range byLangCode {
+		stats.Languages = append(stats.Languages, *language)
+	}
+	sort.Slice(stats.Languages, func(i, j int) bool {
+		return stats.Languages[i].MessagesSent > stats.Languages[j].MessagesSent ||
+			(stats.Languages[i].MessagesSent == stats.Languages[j].MessagesSent && stats.Languages[i].LangCode < stats.Languages[j].LangCode)
+	})
+
+	now := time.Now().In(location)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	stats.DaysActive = len(activeDays)
+	stats.CurrentStreak, stats.LongestStreak = Streaks(activeDays, today)
+	if len(activeDays) > 0 {
+		stats.LastActiveDay = activeDays[0].Format(time.DateOnly)
+	}
+
+	return stats, nil
+}
+
+// Streaks returns the current and longest run of consecutive days in activeDays, which are
+// distinct midnights ordered newest first. The current streak still counts until today ends
+// when the last active day was yesterday
+func Streaks(activeDays []time.Time, today time.Time) (current int, longest int) {
+
+	run := 0
+	for i, day := range activeDays {
+		if i > 0 && activeDays[i-1].AddDate(0, 0, -1).Equal(day) {
+			run++
+		} else {
+			run = 1
+		}
+		longest = max(longest, run)
+
+		if i == 0 && (day.Equal(today) || day.Equal(today.AddDate(0, 0, -1))) {
+			current = 1
+		} else if i > 0 && current == i && run == i+1 {
+			current = run
+		}
+	}
+
+	return current, longest
+}
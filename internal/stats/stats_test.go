@@ -0,0 +1,43 @@
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStreaks(t *testing.T) {
+
+	today := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	daysAgo := func(days ...int) []time.Time {
+		activeDays := make([]time.Time, 0, len(days))
+		for _, n := range days {
+			activeDays = append(activeDays, today.AddDate(0, 0, -n))
+		}
+		return activeDays
+	}
+
+	tests := []struct {
+		name        string
+		activeDays  []time.Time
+		wantCurrent int
+		wantLongest int
+	}{
+		{"no activity", nil, 0, 0},
+		{"today only", daysAgo(0), 1, 1},
+		{"yesterday only", daysAgo(1), 1, 1},
+		{"run ending yesterday", daysAgo(1, 2, 3), 3, 3},
+		{"last active two days ago", daysAgo(2, 3), 0, 2},
+		{"gap after today", daysAgo(0, 1, 3, 4), 2, 2},
+		{"longest run in the past", daysAgo(0, 1, 5, 6, 7, 8), 2, 4},
+		{"longest run is the current one", daysAgo(0, 1, 2, 10, 11), 3, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current, longest := Streaks(tt.activeDays, today)
+			if current != tt.wantCurrent || longest != tt.wantLongest {
+				t.Errorf("Streaks() = %d, %d, want %d, %d", current, longest, tt.wantCurrent, tt.wantLongest)
+			}
+		})
+	}
+}
@@ -0,0 +1,302 @@
+package translation
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/database"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// entries kept in the in-process layer in front of the translation_cache table
+	defaultCacheCapacity = 10000
+
+	// hits are counted in memory and written to translation_cache once this many
+	// entries were hit or the interval passed, whichever comes first
+	hitFlushSize     = 100
+	hitFlushInterval = time.Minute
+
+	// CacheTTL is how long a cached translation nobody looked up is kept before it expires
+	CacheTTL = 90 * 24 * time.Hour
+)
+
+var (
+	cache = newLRUCache(defaultCacheCapacity)
+
+	memoryHits atomic.Int64
+	storeHits  atomic.Int64
+	misses     atomic.Int64
+
+	hitsMu       sync.Mutex
+	pendingHits  = make(map[string]int64)
+	lastHitFlush = time.Now()
+)
+
+// lruCache is a fixed size map of cache keys to translations, evicting the least recently used
+type lruCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	items    map[string]*list.Element
+}
+
+type lruEntry struct {
+	key        string
+	content    string
+	sourceHash string
+	provider   string
+	scope      string
+}
+
+func newLRUCache(capacity int) *lruCache {
+	return &lruCache{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+func (c *lruCache) get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.items[key]
+	if !ok {
+		return "", false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*lruEntry).content, true
+}
+
+func (c *lruCache) put(entry lruEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.items[entry.key]; ok {
+		element.Value = &entry
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.items[entry.key] = c.order.PushFront(&entry)
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry).key)
+	}
+}
+
+func (c *lruCache) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.order.Init()
+	c.items = make(map[string]*list.Element)
+}
+
+// purgeWhere drops the entries match returns true for
+func (c *lruCache) purgeWhere(match func(entry *lruEntry) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for element := c.order.Front(); element != nil; {
+		next := element.Next()
+		if entry := element.Value.(*lruEntry); match(entry) {
+			c.order.Remove(element)
+			delete(c.items, entry.key)
+		}
+		element = next
+	}
+}
+
+func (c *lruCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+// normalizeText trims text and collapses runs of whitespace within lines, line breaks
+// and case are kept since they change translations
+func normalizeText(text string) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// sourceHash identifies normalized source text in translation_cache, which never stores the text itself
+func sourceHash(normalized string) string {
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// cacheKey addresses a translation by its content, scope separates translations that depend on more
+// than the text such as a chat glossary
+func cacheKey(text string, sourceLang string, targetLang string, provider string, scope string) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{text, sourceLang, targetLang, provider, scope}, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// cachedTranslate returns the translation of text from the in-process cache, the translation_cache
+// table or the translator, in that order. Translator results are written to both layers
+func cachedTranslate(ctx context.Context, t Translator, text string, sourceLang string, targetLang string, scope string) (string, error) {
+
+	normalized := normalizeText(text)
+	key := cacheKey(normalized, sourceLang, targetLang, t.Name(), scope)
+	memoryEntry := lruEntry{key: key, sourceHash: sourceHash(normalized), provider: t.Name(), scope: scope}
+
+	if content, ok := cache.get(key); ok {
+		memoryHits.Add(1)
+		countHit(key)
+		return content, nil
+	}
+
+	db := database.GetPostgresConn()
+
+	entry, err := db.GetCachedTranslation(ctx, key)
+	if err == nil {
+		storeHits.Add(1)
+		countHit(key)
+		memoryEntry.content = entry.Content
+		cache.put(memoryEntry)
+		return entry.Content, nil
+	}
+	if err != pgx.ErrNoRows {
+		// the cache is an optimization, fall through to the translator
+		log.Printf("Failed to read translation cache: %v", err)
+	}
+
+	misses.Add(1)
+
+	content, err := t.Translate(ctx, normalized, sourceLang, targetLang)
+	if err != nil {
+		return "", err
+	}
+
+	memoryEntry.content = content
+	cache.put(memoryEntry)
+	err = db.PutCachedTranslation(ctx, &models.TranslationCacheEntry{
+		Key:        key,
+		SourceHash: memoryEntry.sourceHash,
+		SourceLang: sourceLang,
+		TargetLang: targetLang,
+		Provider:   t.Name(),
+		Scope:      scope,
+		Content:    content,
+	})
+	if err != nil {
+		log.Printf("Failed to write translation cache: %v", err)
+	}
+
+	return content, nil
+}
+
+// countHit counts a hit of the cached translation under key, written to translation_cache in batches
+func countHit(key string) {
+
+	hitsMu.Lock()
+	pendingHits[key]++
+	if len(pendingHits) < hitFlushSize && time.Since(lastHitFlush) < hitFlushInterval {
+		hitsMu.Unlock()
+		return
+	}
+	hits := pendingHits
+	pendingHits = make(map[string]int64)
+	lastHitFlush = time.Now()
+	hitsMu.Unlock()
+
+	go func() {
+		err := database.GetPostgresConn().AddCachedTranslationHits(context.Background(), hits, time.Now().UTC())
+		if err != nil {
+			log.Printf("Failed to count translation cache hits: %v", err)
+		}
+	}()
+}
+
+// InvalidateCache drops cached translations of provider in scope, an empty provider matches every provider
+func InvalidateCache(ctx context.Context, provider string, scope string) error {
+
+	cache.purgeWhere(func(entry *lruEntry) bool {
+		return (provider == "" || entry.provider == provider) && entry.scope == scope
+	})
+
+	db := database.GetPostgresConn()
+
+	deleted, err := db.DeleteCachedTranslations(ctx, provider, scope)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Invalidated %d cached translations of provider %q in scope %q", deleted, provider, scope)
+	return nil
+}
+
+// ForgetTexts drops the cached translations of texts, such as the versions of a message deleted
+// for everyone, along with those made with the glossary of chatID as its terms changed the text sent
+func ForgetTexts(ctx context.Context, chatID string, texts []string) error {
+
+	if len(texts) == 0 {
+		return nil
+	}
+
+	hashes := make(map[string]bool, len(texts))
+	sourceHashes := make([]string, 0, len(texts))
+	for _, text := range texts {
+		hash := sourceHash(normalizeText(text))
+		if !hashes[hash] {
+			hashes[hash] = true
+			sourceHashes = append(sourceHashes, hash)
+		}
+	}
+
+	cache.purgeWhere(func(entry *lruEntry) bool {
+		return hashes[entry.sourceHash]
+	})
+
+	db := database.GetPostgresConn()
+
+	_, err := db.DeleteCachedTranslationsOfSources(ctx, sourceHashes)
+	if err != nil {
+		return err
+	}
+
+	return InvalidateGlossary(ctx, chatID)
+}
+
+// ExpireCache drops the cached translations not looked up since unusedSince, so translations
+// of providers taken out of the configuration age out instead of being dropped at startup
+func ExpireCache(ctx context.Context, unusedSince time.Time) (int64, error) {
+
+	db := database.GetPostgresConn()
+
+	return db.DeleteStaleCachedTranslations(ctx, unusedSince)
+}
+
+// CacheStats returns the hit rate of the translation cache since startup
+func CacheStats() models.TranslationCacheStatsResponse {
+
+	stats := models.TranslationCacheStatsResponse{
+		MemoryHits:     memoryHits.Load(),
+		StoreHits:      storeHits.Load(),
+		Misses:         misses.Load(),
+		MemoryEntries:  cache.len(),
+		MemoryCapacity: cache.capacity,
+	}
+
+	if lookups := stats.MemoryHits + stats.StoreHits + stats.Misses; lookups > 0 {
+		stats.HitRate = float64(stats.MemoryHits+stats.StoreHits) / float64(lookups)
+	}
+
+	return stats
+}
@@ -0,0 +1,161 @@
+package translation
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// provider types accepted in RouterConfig
+const (
+	ProviderDeepL          = "deepl"
+	ProviderLibreTranslate = "libretranslate"
+	ProviderFake           = "fake"
+)
+
+// defaultDeepLEndpoint is the DeepL API used when DEEPL_API_URL is not set
+const defaultDeepLEndpoint = "https://api-free.deepl.com"
+
+// ProviderConfig configures one translation provider, Name defaults to Type.
+// The API key is read from the variable named by APIKeyEnv when APIKey is empty
+type ProviderConfig struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Endpoint  string `json:"endpoint"`
+	APIKey    string `json:"api_key"`
+	APIKeyEnv string `json:"api_key_env"`
+}
+
+// RouterConfig is the translation config file, for example
+//
+//	{
+//	  "timeout": "5s",
+//	  "providers": [
+//	    {"type": "deepl", "api_key_env": "DEEPL_API_KEY"},
+//	    {"name": "libre", "type": "libretranslate", "endpoint": "http://localhost:5000"}
+//	  ],
+//	  "routes": [
+//	    {"source": "*", "target": "*", "providers": ["deepl", "libre"]},
+//	    {"source": "es", "target": "en", "providers": ["libre", "deepl"]}
+//	  ]
+//	}
+type RouterConfig struct {
+	Timeout   string           `json:"timeout"`
+	Providers []ProviderConfig `json:"providers"`
+	Routes    []Route          `json:"routes"`
+}
+
+// LoadRouter builds the router from the config file at path,
+// from the environment when path is empty
+func LoadRouter(path string) (*Router, error) {
+
+	if path == "" {
+		return RouterFromEnv()
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read translation config: %w", err)
+	}
+
+	var config RouterConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid translation config %s: %w", path, err)
+	}
+
+	return NewRouterFromConfig(config, nil)
+}
+
+// NewRouterFromConfig builds the providers and routes of config,
+// HTTP providers share client which may be nil
+func NewRouterFromConfig(config RouterConfig, client *http.Client) (*Router, error) {
+
+	var timeout time.Duration
+	if config.Timeout != "" {
+		var err error
+		timeout, err = time.ParseDuration(config.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid translation timeout %q: %w", config.Timeout, err)
+		}
+	}
+
+	providers := make([]Translator, 0, len(config.Providers))
+	for _, providerConfig := range config.Providers {
+		provider, err := newProvider(providerConfig, client)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, provider)
+	}
+
+	return NewRouter(providers, config.Routes, timeout)
+}
+
+func newProvider(config ProviderConfig, client *http.Client) (Translator, error) {
+
+	name := config.Name
+	if name == "" {
+		name = config.Type
+	}
+
+	apiKey := config.APIKey
+	if apiKey == "" && config.APIKeyEnv != "" {
+		apiKey = os.Getenv(config.APIKeyEnv)
+	}
+
+	switch config.Type {
+	case ProviderDeepL:
+		if apiKey == "" {
+			return nil, fmt.Errorf("provider %s needs an api key", name)
+		}
+		endpoint := config.Endpoint
+		if endpoint == "" {
+			endpoint = defaultDeepLEndpoint
+		}
+		return NewDeepLTranslator(name, endpoint, apiKey, client), nil
+	case ProviderLibreTranslate:
+		if config.Endpoint == "" {
+			return nil, fmt.Errorf("provider %s needs an endpoint", name)
+		}
+		return NewLibreTranslator(name, config.Endpoint, apiKey, client), nil
+	case ProviderFake:
+		if name != ProviderFake {
+			return nil, fmt.Errorf("provider %s: the fake provider can not be renamed", name)
+		}
+		return NewFakeTranslator(), nil
+	default:
+		return nil, fmt.Errorf("provider %s has unknown type %q", name, config.Type)
+	}
+}
+
+// RouterFromEnv builds a router without routes from DEEPL_API_KEY (and DEEPL_API_URL),
+// LIBRETRANSLATE_URL (and LIBRETRANSLATE_API_KEY) and TRANSLATION_TIMEOUT, providers
+// are tried in that order. The fake provider is used only when none is configured
+func RouterFromEnv() (*Router, error) {
+
+	config := RouterConfig{Timeout: os.Getenv("TRANSLATION_TIMEOUT")}
+
+	if os.Getenv("DEEPL_API_KEY") != "" {
+		config.Providers = append(config.Providers, ProviderConfig{
+			Type:      ProviderDeepL,
+			Endpoint:  os.Getenv("DEEPL_API_URL"),
+			APIKeyEnv: "DEEPL_API_KEY",
+		})
+	}
+
+	if os.Getenv("LIBRETRANSLATE_URL") != "" {
+		config.Providers = append(config.Providers, ProviderConfig{
+			Type:      ProviderLibreTranslate,
+			Endpoint:  os.Getenv("LIBRETRANSLATE_URL"),
+			APIKeyEnv: "LIBRETRANSLATE_API_KEY",
+		})
+	}
+
+	if len(config.Providers) == 0 {
+		config.Providers = append(config.Providers, ProviderConfig{Type: ProviderFake})
+	}
+
+	return NewRouterFromConfig(config, nil)
+}
@@ -0,0 +1,87 @@
+package translation
+
+import (
+	"slices"
+	"unicode/utf8"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/langdetect"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/languages"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+)
+
+const (
+	// detection overrides the lang_code a client claims only when it is this sure
+	overrideConfidence = 0.75
+
+	// messages with fewer letters in distinct words ("ok ok ok", "jaja", a link) are
+	// too ambiguous to override the client
+	minOverrideLength = 12
+)
+
+var detector langdetect.Detector
+
+// SetDetector sets the detector used by DetectMessageLanguage, messages keep the
+// lang_code the client sent without one
+func SetDetector(d langdetect.Detector) {
+	detector = d
+}
+
+// DetectMessageLanguage detects the language of a new message before it is stored and translated.
+// A missing lang_code is set to the detected language, a claimed one is replaced when the
+// detector is confident, the message long enough and the detector able to tell both
+// languages apart, otherwise it is kept
+func DetectMessageLanguage(msg *models.MessageResponse) {
+
+	if detector == nil {
+		return
+	}
+
+	result, ok := detector.Detect(msg.Content)
+	if !ok {
+		return
+	}
+
+	msg.DetectedLangCode = result.LangCode
+	msg.LangConfidence = result.Confidence
+
+	claimed := BareLangCode(msg.LangCode)
+	if claimed == result.LangCode {
+		return
+	}
+
+	if claimed == "" || (result.Confidence >= overrideConfidence && detectableLength(msg.Content) >= minOverrideLength &&
+		canOverride(claimed, result.LangCode)) {
+		msg.LangCode = StoredLangCode(result.LangCode)
+	}
+}
+
+// detectableLength counts the letters of the words detection looks at
+func detectableLength(content string) int {
+	length := 0
+	for _, word := range langdetect.Words(content) {
+		length += utf8.RuneCountInString(word)
+	}
+	return length
+}
+
+// canOverride reports whether the detector can tell claimed from detected. Both need an n-gram
+// profile, unless detected is the only language written in its script and claimed is written
+// in another one. Languages without a profile are otherwise detected as the closest one that has
+func canOverride(claimed string, detected string) bool {
+
+	profiled := detector.Languages()
+	if slices.Contains(profiled, claimed) && slices.Contains(profiled, detected) {
+		return true
+	}
+
+	claimedLanguage, ok := languages.Lookup(claimed)
+	if !ok {
+		return false
+	}
+	detectedLanguage, ok := languages.Lookup(detected)
+	if !ok {
+		return false
+	}
+
+	return languages.UniqueScript(detected) && claimedLanguage.Script != detectedLanguage.Script
+}
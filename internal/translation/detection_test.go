@@ -0,0 +1,47 @@
+package translation
+
+import (
+	"testing"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/langdetect"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+)
+
+func TestDetectMessageLanguage(t *testing.T) {
+
+	ngramDetector, err := langdetect.NewNGramDetector()
+	if err != nil {
+		t.Fatalf("NewNGramDetector() error = %v", err)
+	}
+	SetDetector(ngramDetector)
+	t.Cleanup(func() { SetDetector(nil) })
+
+	tests := []struct {
+		name     string
+		content  string
+		claimed  string
+		wantLang string
+	}{
+		{"repeated short word", "ok ok ok ok ok ok", "{es}", "{es}"},
+		{"link only", "https://example.com/path/to/thing", "{es}", "{es}"},
+		{"numbers only", "1234 5678 91011 121314", "{es}", "{es}"},
+		{"short word", "hello", "{es}", "{es}"},
+		{"ambiguous words", "pizza pasta espresso", "{en}", "{en}"},
+		{"english sentence claimed spanish", "Can you send me the report before the meeting tomorrow", "{es}", "{en}"},
+		{"spanish sentence claimed english", "¿Dónde está la estación de tren más cercana?", "{en}", "{es}"},
+		{"matching claim", "Where is the nearest train station?", "{en}", "{en}"},
+		{"missing claim", "Ich habe keine Zeit heute", "", "{de}"},
+		{"unique script", "안녕하세요, 오늘 어떻게 지내세요?", "{en}", "{ko}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := models.MessageResponse{Content: tt.content, LangCode: tt.claimed}
+			DetectMessageLanguage(&msg)
+			if msg.LangCode != tt.wantLang {
+				t.Errorf("DetectMessageLanguage(%q) lang_code = %s, want %s (detected %s with confidence %.2f)",
+					tt.content, msg.LangCode, tt.wantLang, msg.DetectedLangCode, msg.LangConfidence)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package translation
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+)
+
+// glossaryScope separates cached translations of text protected with the glossary of chatID
+func glossaryScope(chatID string) string {
+	return "glossary:" + chatID
+}
+
+// InvalidateGlossary drops the cached translations made with the glossary of chatID,
+// called whenever the glossary changes
+func InvalidateGlossary(ctx context.Context, chatID string) error {
+	return InvalidateCache(ctx, "", glossaryScope(chatID))
+}
+
+// glossaryPlaceholder stands in for a glossary term while the text is with the translator,
+// providers leave bracketed tokens of digits and capitals alone
+func glossaryPlaceholder(prefix string, i int) string {
+	return fmt.Sprintf("[[%s%d]]", prefix, i)
+}
+
+// placeholderPrefix returns a placeholder prefix that does not occur in text,
+// so text that already looks like a placeholder is not mistaken for one
+func placeholderPrefix(text string) string {
+	prefix := "G"
+	for strings.Contains(text, "[["+prefix) {
+		prefix += "G"
+	}
+	return prefix
+}
+
+type glossarySpan struct {
+	start, end int
+	value      string
+}
+
+// protectTerms replaces every whole-word occurrence of a glossary term in text with a placeholder
+// and returns the placeholders each followed by the value it is restored to. Terms for targetLang
+// win over terms for every language and longer terms win over the shorter terms they contain
+func protectTerms(text string, targetLang string, terms []models.GlossaryTerm) (string, []string) {
+
+	applicable := make(map[string]models.GlossaryTerm)
+	for _, term := range terms {
+		if term.TargetLangCode != "" && term.TargetLangCode != targetLang {
+			continue
+		}
+		key := strings.ToLower(term.Term)
+		if existing, ok := applicable[key]; ok && existing.TargetLangCode != "" {
+			continue
+		}
+		applicable[key] = term
+	}
+	if len(applicable) == 0 {
+		return text, nil
+	}
+
+	ordered := make([]models.GlossaryTerm, 0, len(applicable))
+	for _, term := range applicable {
+		ordered = append(ordered, term)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		li, lj := utf8.RuneCountInString(ordered[i].Term), utf8.RuneCountInString(ordered[j].Term)
+		if li != lj {
+			return li > lj
+		}
+		return strings.ToLower(ordered[i].Term) < strings.ToLower(ordered[j].Term)
+	})
+
+	var spans []glossarySpan
+	for _, term := range ordered {
+		for _, match := range findFold(text, term.Term) {
+			if !atWordBoundary(text, match[0], match[1]) || overlapsSpan(spans, match[0], match[1]) {
+				continue
+			}
+
+			value := term.Translation
+			if term.DoNotTranslate {
+				value = text[match[0]:match[1]]
+			}
+			spans = append(spans, glossarySpan{start: match[0], end: match[1], value: value})
+		}
+	}
+	if len(spans) == 0 {
+		return text, nil
+	}
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	prefix := placeholderPrefix(text)
+
+	var protected strings.Builder
+	replacements := make([]string, 0, 2*len(spans))
+	last := 0
+	for i, span := range spans {
+		placeholder := glossaryPlaceholder(prefix, i)
+		protected.WriteString(text[last:span.start])
+		protected.WriteString(placeholder)
+		replacements = append(replacements, placeholder, span.value)
+		last = span.end
+	}
+	protected.WriteString(text[last:])
+
+	return protected.String(), replacements
+}
+
+// findFold returns the start and end of every non-overlapping occurrence of term in text under
+// simple case folding, which maps rune to rune so a match has as many runes as term
+func findFold(text string, term string) [][2]int {
+
+	termRunes := utf8.RuneCountInString(term)
+	if termRunes == 0 {
+		return nil
+	}
+
+	var matches [][2]int
+	for start := 0; start < len(text); {
+		end := start
+		for n := 0; n < termRunes && end < len(text); n++ {
+			_, size := utf8.DecodeRuneInString(text[end:])
+			end += size
+		}
+		if strings.EqualFold(text[start:end], term) {
+			matches = append(matches, [2]int{start, end})
+			start = end
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(text[start:])
+		start += size
+	}
+
+	return matches
+}
+
+// restoreTerms puts the glossary values back in place of their placeholders, ok is false
+// when the translator dropped or repeated a placeholder and the values can not be placed
+func restoreTerms(translated string, replacements []string) (string, bool) {
+	for i := 0; i < len(replacements); i += 2 {
+		if strings.Count(translated, replacements[i]) != 1 {
+			return "", false
+		}
+	}
+	return strings.NewReplacer(replacements...).Replace(translated), true
+}
+
+// atWordBoundary reports whether text[start:end] is not part of a longer word,
+// scripts written without spaces have no boundaries to check
+func atWordBoundary(text string, start int, end int) bool {
+
+	if start > 0 {
+		first, _ := utf8.DecodeRuneInString(text[start:])
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		if isGlossaryWordRune(first) && isGlossaryWordRune(before) {
+			return false
+		}
+	}
+
+	if end < len(text) {
+		last, _ := utf8.DecodeLastRuneInString(text[:end])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		if isGlossaryWordRune(last) && isGlossaryWordRune(after) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isGlossaryWordRune(r rune) bool {
+	if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana) {
+		return false
+	}
+	return unicode.IsLetter(r) || unicode.IsMark(r) || unicode.IsDigit(r)
+}
+
+func overlapsSpan(spans []glossarySpan, start int, end int) bool {
+	for _, span := range spans {
+		if start < span.end && span.start < end {
+			return true
+		}
+	}
+	return false
+}
+
+// translateWithGlossary translates text for a chat along chain with its glossary terms protected
+// from the providers and restored afterwards, the provider that made it is returned with it
+func translateWithGlossary(ctx context.Context, chain []Translator, chatID string, text string, sourceLang string, targetLang string, terms []models.GlossaryTerm) (string, string, error) {
+
+	protected, replacements := protectTerms(text, targetLang, terms)
+	if len(replacements) == 0 {
+		return router.translateChain(ctx, chain, text, sourceLang, targetLang, "")
+	}
+
+	translated, provider, err := router.translateChain(ctx, chain, protected, sourceLang, targetLang, glossaryScope(chatID))
+	if err != nil {
+		return "", "", err
+	}
+
+	restored, ok := restoreTerms(translated, replacements)
+	if !ok {
+		log.Printf("Translation by %s lost glossary placeholders, translating without the glossary of chat %s", provider, chatID)
+		return router.translateChain(ctx, chain, text, sourceLang, targetLang, "")
+	}
+
+	return restored, provider, nil
+}
@@ -0,0 +1,162 @@
+package translation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// defaultHTTPTimeout bounds requests of HTTP providers that were not given a client
+const defaultHTTPTimeout = 30 * time.Second
+
+// postJSON sends body as JSON to url and decodes a 200 response into out,
+// other statuses are returned as errors carrying the start of the response
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, body interface{}, out interface{}) error {
+
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("unable to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return fmt.Errorf("unable to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("provider responded %s: %s", resp.Status, strings.TrimSpace(string(snippet)))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("unable to decode response: %w", err)
+	}
+
+	return nil
+}
+
+func httpClientOrDefault(client *http.Client) *http.Client {
+	if client != nil {
+		return client
+	}
+	return &http.Client{Timeout: defaultHTTPTimeout}
+}
+
+// LibreTranslator talks to a LibreTranslate compatible endpoint, self-hosted or not
+type LibreTranslator struct {
+	name     string
+	endpoint string
+	apiKey   string
+	client   *http.Client
+}
+
+// NewLibreTranslator returns a provider named name for the server at endpoint,
+// apiKey may be empty for servers that do not require one
+func NewLibreTranslator(name string, endpoint string, apiKey string, client *http.Client) *LibreTranslator {
+	return &LibreTranslator{
+		name:     name,
+		endpoint: strings.TrimRight(endpoint, "/"),
+		apiKey:   apiKey,
+		client:   httpClientOrDefault(client),
+	}
+}
+
+func (l *LibreTranslator) Name() string {
+	return l.name
+}
+
+func (l *LibreTranslator) Translate(ctx context.Context, text string, sourceLang string, targetLang string) (string, error) {
+
+	body := map[string]string{
+		"q":      text,
+		"source": sourceLang,
+		"target": targetLang,
+		"format": "text",
+	}
+	if l.apiKey != "" {
+		body["api_key"] = l.apiKey
+	}
+
+	var resp struct {
+		TranslatedText string `json:"translatedText"`
+	}
+	if err := postJSON(ctx, l.client, l.endpoint+"/translate", nil, body, &resp); err != nil {
+		return "", fmt.Errorf("%s: %w", l.name, err)
+	}
+
+	return resp.TranslatedText, nil
+}
+
+// deeplTargetCodes are the regional variants DeepL expects for targets it no longer accepts bare
+var deeplTargetCodes = map[string]string{
+	"en": "EN-US",
+	"pt": "PT-BR",
+}
+
+// DeepLTranslator talks to the DeepL v2 API or a server speaking the same protocol
+type DeepLTranslator struct {
+	name     string
+	endpoint string
+	apiKey   string
+	client   *http.Client
+}
+
+// NewDeepLTranslator returns a provider named name for the API at endpoint,
+// such as https://api-free.deepl.com
+func NewDeepLTranslator(name string, endpoint string, apiKey string, client *http.Client) *DeepLTranslator {
+	return &DeepLTranslator{
+		name:     name,
+		endpoint: strings.TrimRight(endpoint, "/"),
+		apiKey:   apiKey,
+		client:   httpClientOrDefault(client),
+	}
+}
+
+func (d *DeepLTranslator) Name() string {
+	return d.name
+}
+
+func (d *DeepLTranslator) Translate(ctx context.Context, text string, sourceLang string, targetLang string) (string, error) {
+
+	target, ok := deeplTargetCodes[targetLang]
+	if !ok {
+		target = strings.ToUpper(targetLang)
+	}
+
+	body := map[string]interface{}{
+		"text":        []string{text},
+		"source_lang": strings.ToUpper(sourceLang),
+		"target_lang": target,
+	}
+	headers := map[string]string{"Authorization": "DeepL-Auth-Key " + d.apiKey}
+
+	var resp struct {
+		Translations []struct {
+			Text string `json:"text"`
+		} `json:"translations"`
+	}
+	if err := postJSON(ctx, d.client, d.endpoint+"/v2/translate", headers, body, &resp); err != nil {
+		return "", fmt.Errorf("%s: %w", d.name, err)
+	}
+
+	if len(resp.Translations) == 0 {
+		return "", fmt.Errorf("%s: response has no translations", d.name)
+	}
+
+	return resp.Translations[0].Text, nil
+}
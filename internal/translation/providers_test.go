@@ -0,0 +1,157 @@
+package translation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLibreTranslator(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/translate" {
+			t.Errorf("request = %s %s, want POST /translate", r.Method, r.URL.Path)
+		}
+		if contentType := r.Header.Get("Content-Type"); contentType != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", contentType)
+		}
+
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("unable to decode request: %v", err)
+			return
+		}
+		want := map[string]string{"q": "hola", "source": "es", "target": "en", "format": "text", "api_key": "secret"}
+		if !reflect.DeepEqual(body, want) {
+			t.Errorf("request body = %v, want %v", body, want)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"translatedText": "hello"}`))
+	}))
+	defer server.Close()
+
+	// a trailing slash on the endpoint is not doubled
+	libre := NewLibreTranslator("libre", server.URL+"/", "secret", server.Client())
+
+	got, err := libre.Translate(context.Background(), "hola", "es", "en")
+	if err != nil {
+		t.Fatalf("Translate() error = %v", err)
+	}
+	if got != "hello" {
+		t.Errorf("Translate() = %q, want %q", got, "hello")
+	}
+}
+
+func TestLibreTranslatorWithoutAPIKey(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("unable to decode request: %v", err)
+			return
+		}
+		if _, ok := body["api_key"]; ok {
+			t.Errorf("request body has api_key without one configured")
+		}
+		w.Write([]byte(`{"translatedText": "hello"}`))
+	}))
+	defer server.Close()
+
+	libre := NewLibreTranslator("libre", server.URL, "", server.Client())
+	if _, err := libre.Translate(context.Background(), "hola", "es", "en"); err != nil {
+		t.Fatalf("Translate() error = %v", err)
+	}
+}
+
+func TestDeepLTranslator(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		targetLang string
+		wantTarget string
+	}{
+		{"bare target", "de", "DE"},
+		{"regional target", "en", "EN-US"},
+		{"portuguese target", "pt", "PT-BR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost || r.URL.Path != "/v2/translate" {
+					t.Errorf("request = %s %s, want POST /v2/translate", r.Method, r.URL.Path)
+				}
+				if auth := r.Header.Get("Authorization"); auth != "DeepL-Auth-Key secret" {
+					t.Errorf("Authorization = %q, want %q", auth, "DeepL-Auth-Key secret")
+				}
+
+				var body struct {
+					Text       []string `json:"text"`
+					SourceLang string   `json:"source_lang"`
+					TargetLang string   `json:"target_lang"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("unable to decode request: %v", err)
+					return
+				}
+				if !reflect.DeepEqual(body.Text, []string{"hola"}) || body.SourceLang != "ES" || body.TargetLang != tt.wantTarget {
+					t.Errorf("request body = %+v, want text [hola] from ES into %s", body, tt.wantTarget)
+				}
+
+				w.Write([]byte(`{"translations": [{"detected_source_language": "ES", "text": "translated"}]}`))
+			}))
+			defer server.Close()
+
+			deepl := NewDeepLTranslator("deepl", server.URL, "secret", server.Client())
+
+			got, err := deepl.Translate(context.Background(), "hola", "es", tt.targetLang)
+			if err != nil {
+				t.Fatalf("Translate() error = %v", err)
+			}
+			if got != "translated" {
+				t.Errorf("Translate() = %q, want %q", got, "translated")
+			}
+		})
+	}
+}
+
+func TestProviderErrors(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{"error status", http.StatusForbidden, `{"message": "Wrong API key"}`, "403 Forbidden"},
+		{"invalid response", http.StatusOK, `not json`, "unable to decode response"},
+		{"no translations", http.StatusOK, `{"translations": []}`, "response has no translations"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			deepl := NewDeepLTranslator("deepl", server.URL, "secret", server.Client())
+
+			_, err := deepl.Translate(context.Background(), "hola", "es", "en")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Translate() error = %v, want one containing %q", err, tt.wantErr)
+			}
+			if !strings.HasPrefix(err.Error(), "deepl: ") {
+				t.Errorf("Translate() error = %v, want it prefixed with the provider name", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package translation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/database"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrUnknownProvider       = errors.New("unknown translation provider")
+	ErrNoAlternativeProvider = errors.New("no other translation provider available")
+	ErrSameLanguage          = errors.New("message is already in the requested language")
+)
+
+// RetranslateMessage replaces the translation of msg into targetLang with one made by a different
+// provider than the current one. provider picks it when not empty, otherwise the providers routed
+// for the pair are tried first. The chat glossary applies as it does to the first translation
+func RetranslateMessage(ctx context.Context, msg models.MessageResponse, targetLang string, provider string) (models.Translation, error) {
+
+	db := database.GetPostgresConn()
+
+	sourceLang := BareLangCode(msg.LangCode)
+	targetLang = BareLangCode(targetLang)
+	if sourceLang == targetLang {
+		return models.Translation{}, ErrSameLanguage
+	}
+
+	var current string
+	existing, err := db.GetTranslation(ctx, msg.ID.String(), targetLang)
+	if err == nil {
+		current = existing.Provider
+	} else if err != pgx.ErrNoRows {
+		return models.Translation{}, err
+	}
+
+	var chain []Translator
+	if provider != "" {
+		requested, ok := router.Provider(provider)
+		if !ok {
+			return models.Translation{}, ErrUnknownProvider
+		}
+		if provider == current {
+			return models.Translation{}, ErrNoAlternativeProvider
+		}
+		chain = []Translator{requested}
+	} else {
+		chain = router.alternatives(sourceLang, targetLang, current)
+		if len(chain) == 0 {
+			return models.Translation{}, ErrNoAlternativeProvider
+		}
+	}
+
+	glossary, err := db.GetChatGlossary(ctx, msg.ChatID.String())
+	if err != nil {
+		return models.Translation{}, fmt.Errorf("unable to retrieve chat glossary: %w", err)
+	}
+
+	content, usedProvider, err := translateWithGlossary(ctx, chain, msg.ChatID.String(), msg.Content, sourceLang, targetLang, glossary)
+	if err != nil {
+		return models.Translation{}, err
+	}
+
+	return db.SaveTranslation(ctx, &models.Translation{
+		MessageID: msg.ID,
+		Content:   content,
+		LangCode:  targetLang,
+		Provider:  usedProvider,
+	})
+}
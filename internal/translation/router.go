@@ -0,0 +1,200 @@
+package translation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+// AnyLang matches every language in a Route
+const AnyLang = "*"
+
+// defaultProviderTimeout bounds a single provider attempt before the next one is tried
+const defaultProviderTimeout = 10 * time.Second
+
+// Route sends translations from Source into Target to Providers, tried in order
+type Route struct {
+	Source    string   `json:"source"`
+	Target    string   `json:"target"`
+	Providers []string `json:"providers"`
+}
+
+// specificity ranks routes matching a pair, exact languages beat AnyLang and the source counts most
+func (r Route) specificity(sourceLang string, targetLang string) int {
+
+	score := 0
+	switch r.Source {
+	case sourceLang:
+		score += 2
+	case AnyLang:
+	default:
+		return -1
+	}
+
+	switch r.Target {
+	case targetLang:
+		score += 1
+	case AnyLang:
+	default:
+		return -1
+	}
+
+	return score
+}
+
+// Router picks the providers of a language pair and falls back along them
+// when one fails or takes longer than its timeout
+type Router struct {
+	providers map[string]Translator
+	routes    []Route
+	timeout   time.Duration
+
+	// attempt translates with a single provider, through the cache
+	attempt func(ctx context.Context, provider Translator, text string, sourceLang string, targetLang string, scope string) (string, error)
+}
+
+// NewRouter returns a router over providers, every provider a route names must be among them.
+// Without routes every pair goes to every provider in the order given
+func NewRouter(providers []Translator, routes []Route, timeout time.Duration) (*Router, error) {
+
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("at least one translation provider is required")
+	}
+
+	r := &Router{
+		providers: make(map[string]Translator, len(providers)),
+		routes:    routes,
+		timeout:   timeout,
+		attempt:   cachedTranslate,
+	}
+	if r.timeout <= 0 {
+		r.timeout = defaultProviderTimeout
+	}
+
+	var names []string
+	for _, provider := range providers {
+		if _, ok := r.providers[provider.Name()]; ok {
+			return nil, fmt.Errorf("translation provider %s configured twice", provider.Name())
+		}
+		r.providers[provider.Name()] = provider
+		names = append(names, provider.Name())
+	}
+
+	if len(r.routes) == 0 {
+		r.routes = []Route{{Source: AnyLang, Target: AnyLang, Providers: names}}
+	}
+
+	for _, route := range r.routes {
+		if route.Source == "" || route.Target == "" || len(route.Providers) == 0 {
+			return nil, fmt.Errorf("route %s-%s needs a source, a target and providers", route.Source, route.Target)
+		}
+		for _, name := range route.Providers {
+			if _, ok := r.providers[name]; !ok {
+				return nil, fmt.Errorf("route %s-%s names unknown provider %s", route.Source, route.Target, name)
+			}
+		}
+	}
+
+	return r, nil
+}
+
+// chain returns the providers of the most specific route matching the pair,
+// nil when no route matches
+func (r *Router) chain(sourceLang string, targetLang string) []Translator {
+
+	best := -1
+	var names []string
+	for _, route := range r.routes {
+		if score := route.specificity(sourceLang, targetLang); score > best {
+			best = score
+			names = route.Providers
+		}
+	}
+
+	chain := make([]Translator, 0, len(names))
+	for _, name := range names {
+		chain = append(chain, r.providers[name])
+	}
+
+	return chain
+}
+
+// Provider returns the provider registered as name
+func (r *Router) Provider(name string) (Translator, bool) {
+	provider, ok := r.providers[name]
+	return provider, ok
+}
+
+// alternatives returns the providers that may replace exclude for the pair, the routed
+// ones first in their order and then every other provider by name
+func (r *Router) alternatives(sourceLang string, targetLang string, exclude string) []Translator {
+
+	seen := map[string]bool{exclude: true}
+
+	var alternatives []Translator
+	for _, provider := range r.chain(sourceLang, targetLang) {
+		if !seen[provider.Name()] {
+			seen[provider.Name()] = true
+			alternatives = append(alternatives, provider)
+		}
+	}
+
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			alternatives = append(alternatives, r.providers[name])
+		}
+	}
+
+	return alternatives
+}
+
+// translate tries the providers routed for the pair in order, each from the cache first,
+// and returns the translation with the name of the provider that made it
+func (r *Router) translate(ctx context.Context, text string, sourceLang string, targetLang string, scope string) (string, string, error) {
+	return r.translateChain(ctx, r.chain(sourceLang, targetLang), text, sourceLang, targetLang, scope)
+}
+
+// translateChain falls back along chain until a provider translates text
+func (r *Router) translateChain(ctx context.Context, chain []Translator, text string, sourceLang string, targetLang string, scope string) (string, string, error) {
+
+	if len(chain) == 0 {
+		return "", "", fmt.Errorf("no translation provider routed for %s-%s", sourceLang, targetLang)
+	}
+
+	var errs []error
+	for _, provider := range chain {
+		content, err := r.translateWith(ctx, provider, text, sourceLang, targetLang, scope)
+		if err == nil {
+			return content, provider.Name(), nil
+		}
+
+		// the caller gave up, the remaining providers would fail the same way
+		if ctx.Err() != nil {
+			return "", "", ctx.Err()
+		}
+
+		log.Printf("Translation provider %s failed for %s-%s, trying the next: %v", provider.Name(), sourceLang, targetLang, err)
+		errs = append(errs, err)
+	}
+
+	return "", "", fmt.Errorf("every provider failed for %s-%s: %w", sourceLang, targetLang, errors.Join(errs...))
+}
+
+// translateWith runs a single provider attempt bounded by the router timeout
+func (r *Router) translateWith(ctx context.Context, provider Translator, text string, sourceLang string, targetLang string, scope string) (string, error) {
+
+	attemptCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	return r.attempt(attemptCtx, provider, text, sourceLang, targetLang, scope)
+}
@@ -0,0 +1,254 @@
+package translation
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestRouter returns a router over providers that skips the translation cache
+func newTestRouter(t *testing.T, providers []Translator, routes []Route, timeout time.Duration) *Router {
+	t.Helper()
+
+	router, err := NewRouter(providers, routes, timeout)
+	if err != nil {
+		t.Fatalf("NewRouter() error = %v", err)
+	}
+	router.attempt = func(ctx context.Context, provider Translator, text string, sourceLang string, targetLang string, scope string) (string, error) {
+		return provider.Translate(ctx, text, sourceLang, targetLang)
+	}
+
+	return router
+}
+
+// libreServer starts a LibreTranslate stand-in that runs handle for every request
+func libreServer(t *testing.T, name string, handle http.HandlerFunc) *LibreTranslator {
+	t.Helper()
+
+	server := httptest.NewServer(handle)
+	t.Cleanup(server.Close)
+
+	return NewLibreTranslator(name, server.URL, "", server.Client())
+}
+
+func respondWith(text string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"translatedText": "` + text + `"}`))
+	}
+}
+
+func TestRouterFallsBackOnError(t *testing.T) {
+
+	failing := libreServer(t, "failing", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+	})
+	working := libreServer(t, "working", respondWith("hello"))
+
+	router := newTestRouter(t, []Translator{failing, working}, nil, time.Second)
+
+	content, provider, err := router.translate(context.Background(), "hola", "es", "en", "")
+	if err != nil {
+		t.Fatalf("translate() error = %v", err)
+	}
+	if content != "hello" || provider != "working" {
+		t.Errorf("translate() = %q by %s, want %q by working", content, provider, "hello")
+	}
+}
+
+func TestRouterFallsBackOnTimeout(t *testing.T) {
+
+	// the slow provider answers only once the router gave up on it
+	release := make(chan struct{})
+	defer close(release)
+	slow := libreServer(t, "slow", func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Write([]byte(`{"translatedText": "too late"}`))
+	})
+	working := libreServer(t, "working", respondWith("hello"))
+
+	router := newTestRouter(t, []Translator{slow, working}, nil, 50*time.Millisecond)
+
+	start := time.Now()
+	content, provider, err := router.translate(context.Background(), "hola", "es", "en", "")
+	if err != nil {
+		t.Fatalf("translate() error = %v", err)
+	}
+	if content != "hello" || provider != "working" {
+		t.Errorf("translate() = %q by %s, want %q by working", content, provider, "hello")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("translate() took %v, the slow provider was not cut off", elapsed)
+	}
+}
+
+func TestRouterEveryProviderFails(t *testing.T) {
+
+	first := libreServer(t, "first", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	})
+	second := libreServer(t, "second", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	})
+
+	router := newTestRouter(t, []Translator{first, second}, nil, time.Second)
+
+	_, _, err := router.translate(context.Background(), "hola", "es", "en", "")
+	if err == nil {
+		t.Fatal("translate() error = nil, want an error")
+	}
+	for _, want := range []string{"every provider failed for es-en", "first: provider responded 502", "second: provider responded 503"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("translate() error = %v, want it to contain %q", err, want)
+		}
+	}
+}
+
+func TestRouterStopsWhenCallerGivesUp(t *testing.T) {
+
+	calls := 0
+	counting := libreServer(t, "counting", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(`{"translatedText": "hello"}`))
+	})
+	failing := libreServer(t, "failing", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	})
+
+	router := newTestRouter(t, []Translator{failing, counting}, nil, time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, _, err := router.translate(ctx, "hola", "es", "en", "")
+	if err != context.Canceled {
+		t.Errorf("translate() error = %v, want %v", err, context.Canceled)
+	}
+	if calls != 0 {
+		t.Errorf("next provider called %d times after the caller gave up", calls)
+	}
+}
+
+// namedTranslator is a provider that is never called, for tests of routing alone
+type namedTranslator string
+
+func (n namedTranslator) Name() string {
+	return string(n)
+}
+
+func (n namedTranslator) Translate(ctx context.Context, text string, sourceLang string, targetLang string) (string, error) {
+	return "", nil
+}
+
+func TestRouterChain(t *testing.T) {
+
+	providers := []Translator{namedTranslator("libre"), namedTranslator("deepl"), namedTranslator("fake")}
+	routes := []Route{
+		{Source: AnyLang, Target: AnyLang, Providers: []string{"libre", "fake"}},
+		{Source: AnyLang, Target: "ja", Providers: []string{"deepl"}},
+		{Source: "es", Target: AnyLang, Providers: []string{"fake"}},
+		{Source: "es", Target: "en", Providers: []string{"deepl", "libre"}},
+	}
+
+	tests := []struct {
+		name       string
+		sourceLang string
+		targetLang string
+		want       []string
+	}{
+		{"exact pair", "es", "en", []string{"deepl", "libre"}},
+		{"source beats target", "es", "ja", []string{"fake"}},
+		{"target only", "fr", "ja", []string{"deepl"}},
+		{"catch-all", "fr", "de", []string{"libre", "fake"}},
+	}
+
+	router := newTestRouter(t, providers, routes, time.Second)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, provider := range router.chain(tt.sourceLang, tt.targetLang) {
+				got = append(got, provider.Name())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("chain(%s, %s) = %v, want %v", tt.sourceLang, tt.targetLang, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouterWithoutMatchingRoute(t *testing.T) {
+
+	providers := []Translator{namedTranslator("deepl")}
+	routes := []Route{{Source: "es", Target: "en", Providers: []string{"deepl"}}}
+
+	router := newTestRouter(t, providers, routes, time.Second)
+
+	if chain := router.chain("fr", "de"); len(chain) != 0 {
+		t.Errorf("chain(fr, de) has %d providers, want none", len(chain))
+	}
+	if _, _, err := router.translate(context.Background(), "bonjour", "fr", "de", ""); err == nil {
+		t.Error("translate() error = nil for a pair without route")
+	}
+}
+
+func TestRouterDefaultRoute(t *testing.T) {
+
+	providers := []Translator{namedTranslator("libre"), namedTranslator("deepl")}
+	router := newTestRouter(t, providers, nil, time.Second)
+
+	var got []string
+	for _, provider := range router.chain("es", "en") {
+		got = append(got, provider.Name())
+	}
+	if want := []string{"libre", "deepl"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("chain(es, en) = %v, want every provider in order %v", got, want)
+	}
+}
+
+func TestRouterAlternatives(t *testing.T) {
+
+	providers := []Translator{namedTranslator("libre"), namedTranslator("deepl"), namedTranslator("fake"), namedTranslator("argos")}
+	routes := []Route{{Source: AnyLang, Target: AnyLang, Providers: []string{"libre", "deepl"}}}
+
+	router := newTestRouter(t, providers, routes, time.Second)
+
+	var got []string
+	for _, provider := range router.alternatives("es", "en", "libre") {
+		got = append(got, provider.Name())
+	}
+	if want := []string{"deepl", "argos", "fake"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("alternatives(es, en, libre) = %v, want %v", got, want)
+	}
+}
+
+func TestNewRouterRejectsInvalidConfig(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		providers []Translator
+		routes    []Route
+		wantErr   string
+	}{
+		{"no providers", nil, nil, "at least one translation provider"},
+		{"provider twice", []Translator{namedTranslator("libre"), namedTranslator("libre")}, nil, "configured twice"},
+		{"unknown provider", []Translator{namedTranslator("libre")},
+			[]Route{{Source: "es", Target: "en", Providers: []string{"deepl"}}}, "unknown provider deepl"},
+		{"route without providers", []Translator{namedTranslator("libre")},
+			[]Route{{Source: "es", Target: "en"}}, "needs a source, a target and providers"},
+		{"route without target", []Translator{namedTranslator("libre")},
+			[]Route{{Source: "es", Providers: []string{"libre"}}}, "needs a source, a target and providers"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRouter(tt.providers, tt.routes, time.Second)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewRouter() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
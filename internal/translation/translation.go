@@ -0,0 +1,113 @@
+package translation
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/database"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+)
+
+var router = mustRouter(NewFakeTranslator())
+
+func mustRouter(t Translator) *Router {
+	r, err := NewRouter([]Translator{t}, nil, 0)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// SetTranslator routes every translation to t alone
+func SetTranslator(t Translator) {
+	SetRouter(mustRouter(t))
+}
+
+// SetRouter sets the providers used by TranslateMessage and TranslateText, cached
+// translations are keyed by provider so the in-process layer of the old ones is dropped
+func SetRouter(r *Router) {
+	router = r
+	cache.purge()
+}
+
+// BareLangCode strips the array braces lang_code is stored with in message rows
+func BareLangCode(langCode string) string {
+	return strings.Trim(langCode, "{}")
+}
+
+// StoredLangCode wraps a bare lang_code in the array braces message rows store it with,
+// the reverse of BareLangCode
+func StoredLangCode(langCode string) string {
+	return "{" + langCode + "}"
+}
+
+// TranslateText translates a piece of text outside of any message, such as a single word
+func TranslateText(ctx context.Context, text string, sourceLang string, targetLang string) (string, error) {
+	content, _, err := router.translate(ctx, text, BareLangCode(sourceLang), BareLangCode(targetLang), "")
+	return content, err
+}
+
+// TranslateMessage translates msg into the language of every other participant
+// of its chat, replaces any translations previously stored for it and sets
+// msg.Translations so live broadcasts can be resolved per viewer. Terms of the chat
+// glossary are kept out of the translator and rendered as configured. Languages that
+// failed are left out and reported in the returned error so the caller may retry.
+// Nothing is stored when msg was edited or deleted while it was being translated,
+// the error wraps database.ErrMessageChanged or database.ErrMessageDeleted then
+func TranslateMessage(ctx context.Context, msg *models.MessageResponse) error {
+
+	db := database.GetPostgresConn()
+
+	sourceLang := BareLangCode(msg.LangCode)
+
+	targetLangs, err := db.GetChatLangCodes(ctx, msg.ChatID.String())
+	if err != nil {
+		return fmt.Errorf("unable to retrieve chat languages: %w", err)
+	}
+
+	glossary, err := db.GetChatGlossary(ctx, msg.ChatID.String())
+	if err != nil {
+		return fmt.Errorf("unable to retrieve chat glossary: %w", err)
+	}
+
+	var translations []models.Translation
+	var failedLangs []string
+	for _, targetLang := range targetLangs {
+		if targetLang == sourceLang {
+			continue
+		}
+
+		content, provider, err := translateWithGlossary(ctx, router.chain(sourceLang, targetLang), msg.ChatID.String(), msg.Content, sourceLang, targetLang, glossary)
+		if err != nil {
+			// keep the other languages, viewers fall back to the original content
+			log.Printf("Failed to translate message %s into %s: %v", msg.ID, targetLang, err)
+			failedLangs = append(failedLangs, targetLang)
+			continue
+		}
+
+		translations = append(translations, models.Translation{
+			MessageID: msg.ID,
+			Content:   content,
+			LangCode:  targetLang,
+			Provider:  provider,
+		})
+	}
+
+	err = db.ReplaceTranslations(ctx, msg.ID.String(), msg.Content, msg.LangCode, translations)
+	if err != nil {
+		return fmt.Errorf("unable to store translations: %w", err)
+	}
+
+	msg.Translations = make(map[string]string, len(translations))
+	for _, translation := range translations {
+		msg.Translations[translation.LangCode] = translation.Content
+	}
+
+	if len(failedLangs) > 0 {
+		return fmt.Errorf("unable to translate message into %s", strings.Join(failedLangs, ", "))
+	}
+
+	return nil
+}
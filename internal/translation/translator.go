@@ -0,0 +1,33 @@
+package translation
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Translator translates text from one language into another
+type Translator interface {
+	// Name identifies the provider, stored alongside translations
+	Name() string
+	Translate(ctx context.Context, text string, sourceLang string, targetLang string) (string, error)
+}
+
+// FakeTranslator is the built-in stand-in used for development,
+// it tags the text with the target language instead of translating it
+type FakeTranslator struct{}
+
+func NewFakeTranslator() *FakeTranslator {
+	return &FakeTranslator{}
+}
+
+func (f *FakeTranslator) Name() string {
+	return "fake"
+}
+
+func (f *FakeTranslator) Translate(ctx context.Context, text string, sourceLang string, targetLang string) (string, error) {
+	if strings.TrimSpace(text) == "" {
+		return "", fmt.Errorf("nothing to translate")
+	}
+	return fmt.Sprintf("[%s] %s", targetLang, text), nil
+}
@@ -0,0 +1,76 @@
+package transliteration
+
+import (
+	"strings"
+)
+
+// arabicLatin is a simplified ALA-LC transliteration without diacritics,
+// short vowels only appear when the text carries harakat
+var arabicLatin = map[rune]string{
+	'ء': "'", 'آ': "aa", 'أ': "a", 'ؤ': "'", 'إ': "i", 'ئ': "'", 'ا': "a", 'ب': "b",
+	'ة': "a", 'ت': "t", 'ث': "th", 'ج': "j", 'ح': "h", 'خ': "kh", 'د': "d", 'ذ': "dh",
+	'ر': "r", 'ز': "z", 'س': "s", 'ش': "sh", 'ص': "s", 'ض': "d", 'ط': "t", 'ظ': "z",
+	'ع': "'", 'غ': "gh", 'ف': "f", 'ق': "q", 'ك': "k", 'ل': "l", 'م': "m", 'ن': "n",
+	'ه': "h", 'و': "w", 'ى': "a", 'ي': "y",
+
+	// harakat
+	'\u064B': "an", '\u064C': "un", '\u064D': "in", '\u064E': "a", '\u064F': "u",
+	'\u0650': "i", '\u0652': "",
+
+	// punctuation and tatweel
+	'،': ",", '؛': ";", '؟': "?", 'ـ': "",
+}
+
+// arabicShadda doubles the consonant before it
+const arabicShadda = '\u0651'
+
+// isHaraka reports whether r is a short vowel mark
+func isHaraka(r rune) bool {
+	return r >= '\u064B' && r <= '\u0652' && r != arabicShadda
+}
+
+func romanizeArabic(text string) string {
+
+	// canonical order puts shadda after a vowel mark, the doubled consonant comes first
+	runes := []rune(text)
+	for i := 1; i < len(runes); i++ {
+		if runes[i] == arabicShadda && isHaraka(runes[i-1]) {
+			runes[i-1], runes[i] = runes[i], runes[i-1]
+		}
+	}
+
+	var out strings.Builder
+	lastConsonant := ""
+	for i, r := range runes {
+		// the alif carrying fathatan is silent
+		if r == 'ا' && i > 0 && runes[i-1] == '\u064B' {
+			continue
+		}
+
+		if r == arabicShadda {
+			out.WriteString(lastConsonant)
+			continue
+		}
+
+		// Arabic-Indic digits
+		if r >= '٠' && r <= '٩' {
+			out.WriteRune('0' + r - '٠')
+			lastConsonant = ""
+			continue
+		}
+
+		latin, ok := arabicLatin[r]
+		if !ok {
+			out.WriteRune(r)
+			lastConsonant = ""
+			continue
+		}
+
+		out.WriteString(latin)
+		if !isHaraka(r) {
+			lastConsonant = latin
+		}
+	}
+
+	return out.String()
+}
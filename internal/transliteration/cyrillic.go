@@ -0,0 +1,58 @@
+package transliteration
+
+import (
+	"strings"
+	"unicode"
+)
+
+// russianLatin follows BGN/PCGN without diacritics, hard and soft signs are dropped
+var russianLatin = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya",
+}
+
+// ukrainianLatin follows the Ukrainian national system of 2010
+var ukrainianLatin = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "h", 'ґ': "g", 'д': "d", 'е': "e", 'є': "ie",
+	'ж': "zh", 'з': "z", 'и': "y", 'і': "i", 'ї': "i", 'й': "i", 'к': "k", 'л': "l",
+	'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch", 'ь': "", 'ю': "iu",
+	'я': "ia", '\'': "", '’': "",
+}
+
+// ukrainianInitial replaces the letters spelled differently at the start of a word
+var ukrainianInitial = map[rune]string{
+	'є': "ye", 'ї': "yi", 'й': "y", 'ю': "yu", 'я': "ya",
+}
+
+// romanizeCyrillic transliterates text letter by letter with table, initial overrides
+// letters at the start of a word. Upper case letters keep their case on the first Latin letter
+func romanizeCyrillic(text string, table map[rune]string, initial map[rune]string) string {
+
+	var out strings.Builder
+	wordStart := true
+	for _, r := range text {
+		lower := unicode.ToLower(r)
+
+		latin, ok := initial[lower]
+		if !ok || !wordStart {
+			latin, ok = table[lower]
+		}
+		if !ok {
+			out.WriteRune(r)
+			wordStart = !unicode.IsLetter(r)
+			continue
+		}
+
+		if r != lower && latin != "" {
+			latin = strings.ToUpper(latin[:1]) + latin[1:]
+		}
+		out.WriteString(latin)
+		wordStart = false
+	}
+
+	return out.String()
+}
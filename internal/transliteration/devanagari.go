@@ -0,0 +1,126 @@
+package transliteration
+
+import (
+	"strings"
+)
+
+// devanagariConsonants carry an inherent "a" unless a vowel sign or virama follows
+var devanagariConsonants = map[rune]string{
+	'क': "k", 'ख': "kh", 'ग': "g", 'घ': "gh", 'ङ': "n",
+	'च': "ch", 'छ': "chh", 'ज': "j", 'झ': "jh", 'ञ': "n",
+	'ट': "t", 'ठ': "th", 'ड': "d", 'ढ': "dh", 'ण': "n",
+	'त': "t", 'थ': "th", 'द': "d", 'ध': "dh", 'न': "n",
+	'प': "p", 'फ': "ph", 'ब': "b", 'भ': "bh", 'म': "m",
+	'य': "y", 'र': "r", 'ल': "l", 'व': "v",
+	'श': "sh", 'ष': "sh", 'स': "s", 'ह': "h",
+
+	// precomposed nukta consonants
+	'\u0958': "q", '\u0959': "kh", '\u095A': "gh", '\u095B': "z",
+	'\u095C': "r", '\u095D': "rh", '\u095E': "f", '\u095F': "y",
+}
+
+// devanagariNukta changes the consonant before it when written as a separate mark
+var devanagariNukta = map[string]string{
+	"k": "q", "kh": "kh", "g": "gh", "j": "z", "d": "r", "dh": "rh", "ph": "f",
+}
+
+var devanagariVowels = map[rune]string{
+	'अ': "a", 'आ': "aa", 'इ': "i", 'ई': "ii", 'उ': "u", 'ऊ': "uu",
+	'ऋ': "ri", 'ए': "e", 'ऐ': "ai", 'ओ': "o", 'औ': "au", 'ऑ': "o",
+}
+
+var devanagariVowelSigns = map[rune]string{
+	'\u093E': "aa", '\u093F': "i", '\u0940': "ii", '\u0941': "u", '\u0942': "uu",
+	'\u0943': "ri", '\u0947': "e", '\u0948': "ai", '\u094B': "o", '\u094C': "au",
+	'\u0949': "o",
+}
+
+const (
+	devanagariVirama      = '\u094D'
+	devanagariNuktaSign   = '\u093C'
+	devanagariAnusvara    = '\u0902'
+	devanagariCandrabindu = '\u0901'
+	devanagariVisarga     = '\u0903'
+)
+
+// romanizeDevanagari transliterates Hindi in a Hunterian style without diacritics.
+// The inherent vowel of the last consonant of a word is dropped as Hindi pronounces it
+func romanizeDevanagari(text string) string {
+
+	var out strings.Builder
+
+	// pendingA is set after a consonant until it is known whether its inherent vowel is spoken
+	pendingA := false
+	lastConsonant := ""
+	syllables := 0
+
+	flush := func(wordEnd bool) {
+		if pendingA && !(wordEnd && syllables > 1) {
+			out.WriteString("a")
+		}
+		pendingA = false
+	}
+
+	for _, r := range text {
+		if latin, ok := devanagariConsonants[r]; ok {
+			flush(false)
+			out.WriteString(latin)
+			lastConsonant = latin
+			pendingA = true
+			syllables++
+			continue
+		}
+
+		switch {
+		case r == devanagariNuktaSign:
+			if replacement, ok := devanagariNukta[lastConsonant]; ok && pendingA {
+				current := out.String()
+				out.Reset()
+				out.WriteString(strings.TrimSuffix(current, lastConsonant) + replacement)
+				lastConsonant = replacement
+			}
+			continue
+		case r == devanagariVirama:
+			pendingA = false
+			continue
+		case r == devanagariAnusvara || r == devanagariCandrabindu:
+			flush(false)
+			out.WriteString("n")
+			continue
+		case r == devanagariVisarga:
+			flush(false)
+			out.WriteString("h")
+			continue
+		}
+
+		if latin, ok := devanagariVowelSigns[r]; ok {
+			pendingA = false
+			out.WriteString(latin)
+			continue
+		}
+
+		if latin, ok := devanagariVowels[r]; ok {
+			flush(false)
+			out.WriteString(latin)
+			syllables++
+			continue
+		}
+
+		// anything else ends the word
+		flush(true)
+		syllables = 0
+		lastConsonant = ""
+
+		switch {
+		case r == '।' || r == '॥':
+			out.WriteString(".")
+		case r >= '०' && r <= '९':
+			out.WriteRune('0' + r - '०')
+		default:
+			out.WriteRune(r)
+		}
+	}
+	flush(true)
+
+	return out.String()
+}
@@ -0,0 +1,134 @@
+package transliteration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// defaultRomanizeTimeout bounds a romanization request, messages are sent without one past it
+const defaultRomanizeTimeout = 5 * time.Second
+
+// HTTPTransliterator talks to a romanization server for what the bundled tables do not cover,
+// such as Japanese kanji or rare Han characters. The server takes
+// POST /romanize {"text": "...", "lang": "ja"} and answers {"romanized": "...", "scheme": "romaji"},
+// an empty romanized means it does not support the language
+type HTTPTransliterator struct {
+	endpoint string
+	apiKey   string
+	client   *http.Client
+}
+
+// NewHTTPTransliterator returns a transliterator for the server at endpoint, apiKey may be
+// empty for servers that do not require one
+func NewHTTPTransliterator(endpoint string, apiKey string, client *http.Client) *HTTPTransliterator {
+
+	if client == nil {
+		client = &http.Client{Timeout: defaultRomanizeTimeout}
+	}
+
+	return &HTTPTransliterator{
+		endpoint: strings.TrimRight(endpoint, "/"),
+		apiKey:   apiKey,
+		client:   client,
+	}
+}
+
+func (t *HTTPTransliterator) Romanize(ctx context.Context, text string, langCode string) (string, string, bool, error) {
+
+	body, err := json.Marshal(map[string]string{"text": text, "lang": langCode})
+	if err != nil {
+		return "", "", false, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint+"/romanize", bytes.NewReader(body))
+	if err != nil {
+		return "", "", false, fmt.Errorf("unable to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if t.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+t.apiKey)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return "", "", false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", "", false, fmt.Errorf("romanization server responded %s: %s", resp.Status, strings.TrimSpace(string(snippet)))
+	}
+
+	var result struct {
+		Romanized string `json:"romanized"`
+		Scheme    string `json:"scheme"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", "", false, fmt.Errorf("unable to decode response: %w", err)
+	}
+	if result.Romanized == "" {
+		return "", "", false, nil
+	}
+	if result.Scheme == "" {
+		result.Scheme = SchemeLatin
+	}
+
+	return result.Romanized, result.Scheme, true, nil
+}
+
+// ChainTransliterator asks each transliterator in order until one supports the text
+type ChainTransliterator []Transliterator
+
+func (c ChainTransliterator) Romanize(ctx context.Context, text string, langCode string) (string, string, bool, error) {
+
+	var errs []error
+	for _, t := range c {
+		romanized, scheme, ok, err := t.Romanize(ctx, text, langCode)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if ok {
+			return romanized, scheme, true, nil
+		}
+	}
+
+	if len(errs) != 0 {
+		return "", "", false, errors.Join(errs...)
+	}
+	return "", "", false, nil
+}
+
+// TransliteratorFromEnv returns the OfflineTransliterator, followed by an HTTPTransliterator
+// when ROMANIZATION_URL is set. ROMANIZATION_API_KEY and ROMANIZATION_TIMEOUT are optional
+func TransliteratorFromEnv() (Transliterator, error) {
+
+	offline, err := NewOfflineTransliterator()
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := os.Getenv("ROMANIZATION_URL")
+	if endpoint == "" {
+		return offline, nil
+	}
+
+	timeout := defaultRomanizeTimeout
+	if timeoutStr := os.Getenv("ROMANIZATION_TIMEOUT"); timeoutStr != "" {
+		timeout, err = time.ParseDuration(timeoutStr)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("invalid ROMANIZATION_TIMEOUT %q", timeoutStr)
+		}
+	}
+
+	return ChainTransliterator{offline, NewHTTPTransliterator(endpoint, os.Getenv("ROMANIZATION_API_KEY"), &http.Client{Timeout: timeout})}, nil
+}
@@ -0,0 +1,141 @@
+package transliteration
+
+import (
+	"strings"
+)
+
+// hiraganaRomaji is modified Hepburn, katakana is looked up by its hiragana counterpart
+var hiraganaRomaji = map[string]string{
+	"あ": "a", "い": "i", "う": "u", "え": "e", "お": "o",
+	"か": "ka", "き": "ki", "く": "ku", "け": "ke", "こ": "ko",
+	"さ": "sa", "し": "shi", "す": "su", "せ": "se", "そ": "so",
+	"た": "ta", "ち": "chi", "つ": "tsu", "て": "te", "と": "to",
+	"な": "na", "に": "ni", "ぬ": "nu", "ね": "ne", "の": "no",
+	"は": "ha", "ひ": "hi", "ふ": "fu", "へ": "he", "ほ": "ho",
+	"ま": "ma", "み": "mi", "む": "mu", "め": "me", "も": "mo",
+	"や": "ya", "ゆ": "yu", "よ": "yo",
+	"ら": "ra", "り": "ri", "る": "ru", "れ": "re", "ろ": "ro",
+	"わ": "wa", "ゐ": "i", "ゑ": "e", "を": "o", "ん": "n",
+	"が": "ga", "ぎ": "gi", "ぐ": "gu", "げ": "ge", "ご": "go",
+	"ざ": "za", "じ": "ji", "ず": "zu", "ぜ": "ze", "ぞ": "zo",
+	"だ": "da", "ぢ": "ji", "づ": "zu", "で": "de", "ど": "do",
+	"ば": "ba", "び": "bi", "ぶ": "bu", "べ": "be", "ぼ": "bo",
+	"ぱ": "pa", "ぴ": "pi", "ぷ": "pu", "ぺ": "pe", "ぽ": "po",
+	"ゔ": "vu",
+	"ぁ": "a", "ぃ": "i", "ぅ": "u", "ぇ": "e", "ぉ": "o",
+	"ゃ": "ya", "ゅ": "yu", "ょ": "yo", "ゎ": "wa",
+
+	"きゃ": "kya", "きゅ": "kyu", "きょ": "kyo",
+	"しゃ": "sha", "しゅ": "shu", "しょ": "sho", "しぇ": "she",
+	"ちゃ": "cha", "ちゅ": "chu", "ちょ": "cho", "ちぇ": "che",
+	"にゃ": "nya", "にゅ": "nyu", "にょ": "nyo",
+	"ひゃ": "hya", "ひゅ": "hyu", "ひょ": "hyo",
+	"みゃ": "mya", "みゅ": "myu", "みょ": "myo",
+	"りゃ": "rya", "りゅ": "ryu", "りょ": "ryo",
+	"ぎゃ": "gya", "ぎゅ": "gyu", "ぎょ": "gyo",
+	"じゃ": "ja", "じゅ": "ju", "じょ": "jo", "じぇ": "je",
+	"ぢゃ": "ja", "ぢゅ": "ju", "ぢょ": "jo",
+	"びゃ": "bya", "びゅ": "byu", "びょ": "byo",
+	"ぴゃ": "pya", "ぴゅ": "pyu", "ぴょ": "pyo",
+
+	// combinations used to write loanwords in katakana
+	"ふぁ": "fa", "ふぃ": "fi", "ふぇ": "fe", "ふぉ": "fo",
+	"てぃ": "ti", "でぃ": "di", "とぅ": "tu", "どぅ": "du",
+	"うぃ": "wi", "うぇ": "we", "うぉ": "wo",
+	"ゔぁ": "va", "ゔぃ": "vi", "ゔぇ": "ve", "ゔぉ": "vo",
+	"つぁ": "tsa", "つぃ": "tsi", "つぇ": "tse", "つぉ": "tso",
+}
+
+// japanesePunctuation is written with ASCII punctuation in romaji
+var japanesePunctuation = map[rune]string{
+	'。': ". ", '、': ", ", '・': " ", '「': "\"", '」': "\"", '『': "\"", '』': "\"",
+	'〜': "~", '　': " ",
+}
+
+const (
+	smallTsu      = 'っ'
+	prolongedMark = 'ー'
+)
+
+// toHiragana maps katakana onto hiragana, other runes are returned unchanged
+func toHiragana(r rune) rune {
+	if r >= 'ァ' && r <= 'ヶ' {
+		return r - ('ァ' - 'ぁ')
+	}
+	return r
+}
+
+// romanizeKana writes hiragana and katakana in Hepburn romaji. Kanji need a dictionary
+// of readings and are kept as written, for the caller to tell
+func romanizeKana(text string) string {
+
+	runes := []rune(text)
+	for i, r := range runes {
+		runes[i] = toHiragana(r)
+	}
+
+	var out strings.Builder
+	geminate := false
+	lastVowel := byte(0)
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		if r == smallTsu {
+			geminate = true
+			continue
+		}
+
+		if r == prolongedMark {
+			if lastVowel != 0 {
+				out.WriteByte(lastVowel)
+			}
+			continue
+		}
+
+		romaji, width := "", 0
+		if i+1 < len(runes) {
+			romaji, width = hiraganaRomaji[string(runes[i:i+2])], 2
+		}
+		if romaji == "" {
+			romaji, width = hiraganaRomaji[string(r)], 1
+		}
+
+		if romaji == "" {
+			geminate = false
+			lastVowel = 0
+			if punctuation, ok := japanesePunctuation[r]; ok {
+				out.WriteString(punctuation)
+			} else {
+				out.WriteRune(r)
+			}
+			continue
+		}
+
+		// っ doubles the next consonant, ch becomes tch
+		if geminate {
+			if strings.HasPrefix(romaji, "ch") {
+				out.WriteByte('t')
+			} else if !strings.ContainsRune("aiueon", rune(romaji[0])) {
+				out.WriteByte(romaji[0])
+			}
+			geminate = false
+		}
+
+		// ん before a vowel or y is followed by an apostrophe to keep syllables apart
+		if r == 'ん' && i+1 < len(runes) {
+			if next := hiraganaRomaji[string(runes[i+1])]; next != "" && strings.ContainsRune("aiueoy", rune(next[0])) {
+				romaji = "n'"
+			}
+		}
+
+		out.WriteString(romaji)
+		lastVowel = romaji[len(romaji)-1]
+		if !strings.ContainsRune("aiueo", rune(lastVowel)) {
+			lastVowel = 0
+		}
+		i += width - 1
+	}
+
+	return out.String()
+}
@@ -0,0 +1,57 @@
+package transliteration
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// OfflineTransliterator romanizes with bundled tables. It covers Japanese kana (Hepburn),
+// the common Han characters listed in pinyin.tsv, Russian, Bulgarian and Ukrainian Cyrillic,
+// Arabic and Devanagari. Japanese kanji and Han characters missing from pinyin.tsv cannot be
+// romanized, text containing them is not romanized rather than romanized partly. Chain it
+// with an HTTPTransliterator to romanize those
+type OfflineTransliterator struct {
+	pinyin map[rune]string
+}
+
+// NewOfflineTransliterator parses the bundled pinyin readings
+func NewOfflineTransliterator() (*OfflineTransliterator, error) {
+
+	readings, err := parsePinyin(pinyinDataset)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pinyin dataset: %w", err)
+	}
+
+	return &OfflineTransliterator{pinyin: readings}, nil
+}
+
+func (t *OfflineTransliterator) Romanize(ctx context.Context, text string, langCode string) (string, string, bool, error) {
+
+	switch langCode {
+	case "ja":
+		return withoutHan(romanizeKana(text), SchemeRomaji)
+	case "zh":
+		return withoutHan(romanizePinyin(text, t.pinyin), SchemePinyin)
+	case "ru", "bg":
+		return romanizeCyrillic(text, russianLatin, nil), SchemeLatin, true, nil
+	case "uk":
+		return romanizeCyrillic(text, ukrainianLatin, ukrainianInitial), SchemeLatin, true, nil
+	case "ar":
+		return romanizeArabic(text), SchemeLatin, true, nil
+	case "hi", "mr", "ne":
+		return romanizeDevanagari(text), SchemeLatin, true, nil
+	}
+
+	return "", "", false, nil
+}
+
+// withoutHan returns romanized in scheme unless Han characters were left in it,
+// mixing Latin and Han is harder to read than either
+func withoutHan(romanized string, scheme string) (string, string, bool, error) {
+	if strings.ContainsFunc(romanized, func(r rune) bool { return unicode.Is(unicode.Han, r) }) {
+		return "", "", false, nil
+	}
+	return romanized, scheme, true, nil
+}
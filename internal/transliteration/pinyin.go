@@ -0,0 +1,83 @@
+package transliteration
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+//go:embed pinyin.tsv
+var pinyinDataset []byte
+
+// parsePinyin reads character<TAB>reading lines, lines starting with # are comments
+func parsePinyin(data []byte) (map[rune]string, error) {
+
+	readings := make(map[rune]string)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != 2 || utf8.RuneCountInString(fields[0]) != 1 {
+			return nil, fmt.Errorf("line %d: expected character<TAB>reading", lineNum)
+		}
+
+		char, _ := utf8.DecodeRuneInString(fields[0])
+		readings[char] = fields[1]
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return readings, nil
+}
+
+// chinesePunctuation is written with ASCII punctuation in pinyin
+var chinesePunctuation = map[rune]string{
+	'，': ", ", '。': ". ", '、': ", ", '！': "! ", '？': "? ", '：': ": ", '；': "; ",
+	'“': "\"", '”': "\"", '‘': "'", '’': "'", '（': " (", '）': ") ", '　': " ",
+}
+
+// romanizePinyin writes every Han character with a known reading as a pinyin syllable with
+// tone marks, syllables are separated by spaces since words are not segmented. Characters
+// without a known reading are kept as written
+func romanizePinyin(text string, readings map[rune]string) string {
+
+	var out strings.Builder
+	afterSyllable := false
+	for _, r := range text {
+		if reading, ok := readings[r]; ok {
+			if out.Len() > 0 && !strings.HasSuffix(out.String(), " ") && !strings.HasSuffix(out.String(), "\"") {
+				out.WriteByte(' ')
+			}
+			out.WriteString(reading)
+			afterSyllable = true
+			continue
+		}
+
+		if punctuation, ok := chinesePunctuation[r]; ok {
+			out.WriteString(punctuation)
+			afterSyllable = false
+			continue
+		}
+
+		if afterSyllable && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			out.WriteByte(' ')
+		}
+		out.WriteRune(r)
+		afterSyllable = false
+	}
+
+	return strings.TrimSpace(out.String())
+}
@@ -0,0 +1,103 @@
+package transliteration
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"unicode"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/database"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/languages"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/translation"
+)
+
+// romanization schemes
+const (
+	SchemeRomaji = "romaji"
+	SchemePinyin = "pinyin"
+	SchemeLatin  = "latin"
+)
+
+// Transliterator writes text of a non-Latin script in Latin letters
+type Transliterator interface {
+	// Romanize writes text written in langCode in Latin letters and names the scheme used,
+	// ok is false when the transliterator does not support the language
+	Romanize(ctx context.Context, text string, langCode string) (romanized string, scheme string, ok bool, err error)
+}
+
+var transliterator Transliterator
+
+// SetTransliterator sets the romanization backend, messages are not romanized without one
+func SetTransliterator(t Transliterator) {
+	transliterator = t
+}
+
+// needsRomanization reports whether content written in langCode has anything to romanize
+func needsRomanization(content string, langCode string) bool {
+	if !languages.NonLatin(langCode) {
+		return false
+	}
+	return strings.ContainsFunc(content, func(r rune) bool {
+		return unicode.IsLetter(r) && !unicode.Is(unicode.Latin, r)
+	})
+}
+
+// romanize returns the romanization of content written in langCode, empty when there is none
+func romanize(ctx context.Context, content string, langCode string) (string, string) {
+
+	bareLangCode := translation.BareLangCode(langCode)
+	if transliterator == nil || !needsRomanization(content, bareLangCode) {
+		return "", ""
+	}
+
+	romanized, scheme, ok, err := transliterator.Romanize(ctx, content, bareLangCode)
+	if err != nil {
+		log.Printf("Failed to romanize text in %s: %v", bareLangCode, err)
+		return "", ""
+	}
+	if !ok || romanized == content {
+		return "", ""
+	}
+
+	return romanized, scheme
+}
+
+// AttachRomanization romanizes a live message, ForViewer only sends it to viewers who opted in
+func AttachRomanization(ctx context.Context, msg *models.MessageResponse) {
+
+	msg.Romanization, msg.RomanizationScheme = "", ""
+	if msg.DeletedAt != nil {
+		return
+	}
+
+	msg.Romanization, msg.RomanizationScheme = romanize(ctx, msg.Content, msg.LangCode)
+}
+
+// ApplyRomanization romanizes the original content of history messages when userID opted in
+func ApplyRomanization(ctx context.Context, userID string, messages []models.MessageResponse) error {
+
+	if transliterator == nil || len(messages) == 0 {
+		return nil
+	}
+
+	db := database.GetPostgresConn()
+
+	enabled, err := db.GetUserRomanization(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("unable to retrieve romanization setting: %w", err)
+	}
+	if !enabled {
+		return nil
+	}
+
+	for i := range messages {
+		if messages[i].DeletedAt != nil {
+			continue
+		}
+		messages[i].Romanization, messages[i].RomanizationScheme = romanize(ctx, messages[i].OriginalContent, messages[i].OriginalLangCode)
+	}
+
+	return nil
+}
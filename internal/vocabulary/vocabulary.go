@@ -0,0 +1,205 @@
+package vocabulary
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/database"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/languages"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/learning"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/permissions"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/translation"
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	maxTermLength    = 100
+	maxContextLength = 500
+	maxTags          = 20
+	maxTagLength     = 30
+)
+
+var (
+	ErrInvalidItem     = errors.New("invalid vocabulary item")
+	ErrMessageNotFound = errors.New("source message not found")
+)
+
+// Save adds a term to the vocabulary of userID. When the request names a source message
+// the user must be able to see it, and the language and context sentence default to the message's.
+// Without a translation the term is glossed into the user's native language, or saved without one when that fails
+func Save(ctx context.Context, userID string, req models.VocabularyRequest) (models.VocabularyItem, error) {
+
+	db := database.GetPostgresConn()
+
+	item := models.VocabularyItem{
+		UserID:      userID,
+		LangCode:    req.LangCode,
+		Term:        strings.TrimSpace(req.Term),
+		Translation: strings.TrimSpace(req.Translation),
+		Context:     strings.TrimSpace(req.Context),
+	}
+
+	if req.MessageID != nil {
+		msg, err := db.GetMessage(ctx, req.MessageID.String())
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return models.VocabularyItem{}, ErrMessageNotFound
+			}
+			return models.VocabularyItem{}, err
+		}
+
+		_, err = permissions.Authorize(ctx, msg.ChatID.String(), userID, permissions.ActionViewChat)
+		if err != nil {
+			if errors.Is(err, permissions.ErrNotParticipant) || errors.Is(err, permissions.ErrForbidden) {
+				return models.VocabularyItem{}, ErrMessageNotFound
+			}
+			return models.VocabularyItem{}, err
+		}
+
+		if msg.DeletedAt != nil {
+			return models.VocabularyItem{}, ErrMessageNotFound
+		}
+
+		item.MessageID = uuid.NullUUID{UUID: msg.ID, Valid: true}
+		if item.LangCode == "" {
+			item.LangCode = translation.BareLangCode(msg.LangCode)
+		}
+		if item.Context == "" {
+			item.Context = ContextSentence(msg.Content, item.Term)
+		}
+	}
+
+	tags, err := NormalizeTags(req.Tags)
+	if err != nil {
+		return models.VocabularyItem{}, err
+	}
+	item.Tags = tags
+
+	if item.LangCode == "" || item.Term == "" || utf8.RuneCountInString(item.Term) > maxTermLength {
+		return models.VocabularyItem{}, fmt.Errorf("%w: term and lang_code are required", ErrInvalidItem)
+	}
+
+	langCode, err := languages.Normalize(item.LangCode)
+	if err != nil {
+		return models.VocabularyItem{}, fmt.Errorf("%w: %w", ErrInvalidItem, err)
+	}
+	item.LangCode = langCode
+	item.Context = truncate(item.Context, maxContextLength)
+
+	nativeLang, err := db.GetUserLangCode(ctx, userID)
+	if err != nil {
+		return models.VocabularyItem{}, err
+	}
+	item.TranslationLangCode = nativeLang
+
+	if item.Translation == "" && nativeLang != "" && nativeLang != item.LangCode {
+		// the item is saved without a translation when it can not be glossed, it can be added later
+		gloss, err := learning.GlossTerm(ctx, item.Term, item.LangCode, nativeLang)
+		if err != nil {
+			log.Printf("Failed to gloss vocabulary term in %s: %v", item.LangCode, err)
+		} else {
+			item.Translation = gloss.Translation
+		}
+	}
+
+	return db.CreateVocabularyItem(ctx, &item)
+}
+
+// Update replaces the translation, context and tags of a vocabulary item of userID
+func Update(ctx context.Context, userID string, itemID string, req models.VocabularyRequest) (models.VocabularyItem, error) {
+
+	db := database.GetPostgresConn()
+
+	item, err := db.GetVocabularyItem(ctx, userID, itemID)
+	if err != nil {
+		return models.VocabularyItem{}, err
+	}
+
+	tags, err := NormalizeTags(req.Tags)
+	if err != nil {
+		return models.VocabularyItem{}, err
+	}
+
+	item.Translation = strings.TrimSpace(req.Translation)
+	item.Context = truncate(strings.TrimSpace(req.Context), maxContextLength)
+	item.Tags = tags
+
+	return db.UpdateVocabularyItem(ctx, &item)
+}
+
+// NormalizeTags lowercases and trims tags and drops empty and repeated ones
+func NormalizeTags(tags []string) ([]string, error) {
+
+	normalized := []string{}
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > maxTagLength {
+			return nil, fmt.Errorf("%w: tag %q is too long", ErrInvalidItem, tag)
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+
+	if len(normalized) > maxTags {
+		return nil, fmt.Errorf("%w: at most %d tags", ErrInvalidItem, maxTags)
+	}
+
+	return normalized, nil
+}
+
+// ContextSentence returns the sentence of content the term appears in,
+// or all of content when the term can not be found
+func ContextSentence(content string, term string) string {
+
+	// lowercasing may change byte lengths, then only an exact match keeps offsets valid
+	index := strings.Index(content, term)
+	loweredContent, loweredTerm := strings.ToLower(content), strings.ToLower(term)
+	if len(loweredContent) == len(content) && len(loweredTerm) == len(term) {
+		index = strings.Index(loweredContent, loweredTerm)
+	}
+	if term == "" || index < 0 {
+		return strings.TrimSpace(content)
+	}
+
+	start := 0
+	if offset := strings.LastIndexFunc(content[:index], isSentenceEnd); offset >= 0 {
+		_, size := utf8.DecodeRuneInString(content[offset:])
+		start = offset + size
+	}
+
+	end := len(content)
+	if offset := strings.IndexFunc(content[index+len(term):], isSentenceEnd); offset >= 0 {
+		end = index + len(term) + offset
+		_, size := utf8.DecodeRuneInString(content[end:])
+		end += size
+	}
+
+	return strings.TrimSpace(content[start:end])
+}
+
+func isSentenceEnd(r rune) bool {
+	switch r {
+	case '.', '!', '?', '。', '！', '？', '\n':
+		return true
+	}
+	return false
+}
+
+// truncate shortens text to at most max runes
+func truncate(text string, max int) string {
+	if utf8.RuneCountInString(text) <= max {
+		return text
+	}
+	return strings.TrimRightFunc(string([]rune(text)[:max]), unicode.IsSpace)
+}
@@ -0,0 +1,29 @@
+package vocabulary
+
+import "testing"
+
+func TestContextSentence(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		content string
+		term    string
+		want    string
+	}{
+		{"sentence of the term", "Hola. Me gusta el café. Adiós", "café", "Me gusta el café."},
+		{"case insensitive", "Hola. Me gusta el Café. Adiós", "café", "Me gusta el Café."},
+		{"term not found", "  Hola amigo  ", "café", "Hola amigo"},
+		{"empty term", "Hola. Adiós", "", "Hola. Adiós"},
+		// the Kelvin sign lowercases to a one byte k
+		{"term changes length when lowercased", "ok", "K", "ok"},
+		{"content changes length when lowercased", "K. the term.", "term", "the term."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ContextSentence(tt.content, tt.term); got != tt.want {
+				t.Errorf("ContextSentence(%q, %q) = %q, want %q", tt.content, tt.term, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package voice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultSpeechModel and defaultSpeechVoice are what OpenAI compatible servers expect when none is configured
+	defaultSpeechModel = "tts-1"
+	defaultSpeechVoice = "alloy"
+
+	// defaultSpeechTimeout bounds synthesis while the listener waits for the audio
+	defaultSpeechTimeout = 20 * time.Second
+
+	// maxSpeechSize bounds the audio read back from the server
+	maxSpeechSize = 8 << 20
+)
+
+// SpeechSynthesizer talks to a text-to-speech server speaking the OpenAI speech API,
+// such as a self-hosted Piper or Kokoro server
+type SpeechSynthesizer struct {
+	endpoint string
+	apiKey   string
+	model    string
+	voice    string
+	client   *http.Client
+}
+
+// NewSpeechSynthesizer returns a synthesizer for the server at endpoint, apiKey may be empty
+// for servers that do not require one, model defaults to tts-1 and voice to alloy
+func NewSpeechSynthesizer(endpoint string, apiKey string, model string, voice string, client *http.Client) *SpeechSynthesizer {
+
+	if model == "" {
+		model = defaultSpeechModel
+	}
+	if voice == "" {
+		voice = defaultSpeechVoice
+	}
+	if client == nil {
+		client = &http.Client{Timeout: defaultSpeechTimeout}
+	}
+
+	return &SpeechSynthesizer{
+		endpoint: strings.TrimRight(endpoint, "/"),
+		apiKey:   apiKey,
+		model:    model,
+		voice:    voice,
+		client:   client,
+	}
+}
+
+func (s *SpeechSynthesizer) Name() string {
+	return "speech:" + s.model + ":" + s.voice
+}
+
+func (s *SpeechSynthesizer) Synthesize(ctx context.Context, text string, langCode string) ([]byte, string, error) {
+
+	body, err := json.Marshal(map[string]string{
+		"model":           s.model,
+		"voice":           s.voice,
+		"input":           text,
+		"language":        langCode,
+		"response_format": "opus",
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint+"/v1/audio/speech", bytes.NewReader(body))
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, "", fmt.Errorf("synthesizer responded %s: %s", resp.Status, strings.TrimSpace(string(snippet)))
+	}
+
+	audio, err := io.ReadAll(io.LimitReader(resp.Body, maxSpeechSize+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to read audio: %w", err)
+	}
+	if len(audio) == 0 || len(audio) > maxSpeechSize {
+		return nil, "", fmt.Errorf("synthesizer returned %d bytes of audio", len(audio))
+	}
+
+	// servers that ignore response_format still say what they sent, under various names
+	contentType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch {
+	case err != nil || contentType == "application/octet-stream" || contentType == "audio/opus":
+		contentType = "audio/ogg"
+	case contentType == "audio/mp3":
+		contentType = "audio/mpeg"
+	case contentType == "audio/x-wav" || contentType == "audio/wave":
+		contentType = "audio/wav"
+	}
+
+	return audio, contentType, nil
+}
+
+// SynthesizerFromEnv returns a SpeechSynthesizer when TTS_URL is set, the built-in ToneSynthesizer
+// when TTS_STANDIN is set to tone for development and nil otherwise.
+// TTS_API_KEY, TTS_MODEL, TTS_VOICE and TTS_TIMEOUT are optional
+func SynthesizerFromEnv() (Synthesizer, error) {
+
+	endpoint := os.Getenv("TTS_URL")
+	if endpoint == "" {
+		switch standin := os.Getenv("TTS_STANDIN"); standin {
+		case "":
+			return nil, nil
+		case "tone":
+			return NewToneSynthesizer(), nil
+		default:
+			return nil, fmt.Errorf("invalid TTS_STANDIN %q", standin)
+		}
+	}
+
+	timeout := defaultSpeechTimeout
+	if timeoutStr := os.Getenv("TTS_TIMEOUT"); timeoutStr != "" {
+		var err error
+		timeout, err = time.ParseDuration(timeoutStr)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("invalid TTS_TIMEOUT %q", timeoutStr)
+		}
+	}
+
+	return NewSpeechSynthesizer(endpoint, os.Getenv("TTS_API_KEY"), os.Getenv("TTS_MODEL"), os.Getenv("TTS_VOICE"), &http.Client{Timeout: timeout}), nil
+}
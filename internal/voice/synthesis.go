@@ -0,0 +1,197 @@
+package voice
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/blobstore"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/database"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/translation"
+)
+
+var ErrNoSynthesizer = errors.New("no synthesizer configured")
+
+const (
+	// SpeechCacheTTL is how long cached speech nobody listened to is kept before it is evicted
+	SpeechCacheTTL = 30 * 24 * time.Hour
+
+	// speechEvictionBatch bounds the cached speech evicted by a single PruneSpeech
+	speechEvictionBatch = 500
+)
+
+// speechExtensions are the audio formats synthesized speech is cached in,
+// probed in this order when looking up cached audio
+var speechExtensions = []struct {
+	contentType string
+	extension   string
+}{
+	{"audio/ogg", ".ogg"},
+	{"audio/mpeg", ".mp3"},
+	{"audio/wav", ".wav"},
+	{"audio/webm", ".webm"},
+}
+
+// Synthesizer reads text out loud
+type Synthesizer interface {
+	// Name identifies the synthesizer and its voice, cached speech of another name is not reused
+	Name() string
+	// Synthesize returns speech of text read in langCode and the content type of the audio
+	Synthesize(ctx context.Context, text string, langCode string) (audio []byte, contentType string, err error)
+}
+
+var synthesizer Synthesizer
+
+// SetSynthesizer sets the text-to-speech backend, message audio is unavailable without one
+func SetSynthesizer(s Synthesizer) {
+	synthesizer = s
+}
+
+// speechKey is the blob key of speech of text in langCode without extension,
+// it only depends on what is read out so identical texts share the audio
+func speechKey(synthesizerName string, text string, langCode string) string {
+	sum := sha256.Sum256([]byte(synthesizerName + "\x00" + langCode + "\x00" + text))
+	return "tts/" + hex.EncodeToString(sum[:])
+}
+
+// Speak returns speech of text read in langCode and its content type, audio synthesized
+// before is read from the blob store and new audio is stored there
+func Speak(ctx context.Context, text string, langCode string) (io.ReadSeekCloser, blobstore.Info, string, error) {
+
+	if synthesizer == nil {
+		return nil, blobstore.Info{}, "", ErrNoSynthesizer
+	}
+	if store == nil {
+		return nil, blobstore.Info{}, "", ErrNoStore
+	}
+
+	key := speechKey(synthesizer.Name(), text, langCode)
+
+	db := database.GetPostgresConn()
+
+	for _, format := range speechExtensions {
+		audio, info, err := store.Open(ctx, key+format.extension)
+		if err == nil {
+			// eviction is an optimization, the audio is served either way
+			if err := db.TouchCachedSpeech(ctx, key+format.extension, info.Size, time.Now().UTC()); err != nil {
+				log.Printf("Failed to record use of cached speech: %v", err)
+			}
+			return audio, info, format.contentType, nil
+		}
+		if !errors.Is(err, blobstore.ErrNotFound) {
+			return nil, blobstore.Info{}, "", fmt.Errorf("unable to open cached speech: %w", err)
+		}
+	}
+
+	audio, contentType, err := synthesizer.Synthesize(ctx, text, langCode)
+	if err != nil {
+		return nil, blobstore.Info{}, "", fmt.Errorf("unable to synthesize speech: %w", err)
+	}
+
+	extension := ""
+	for _, format := range speechExtensions {
+		if format.contentType == contentType {
+			extension = format.extension
+		}
+	}
+	if extension == "" {
+		return nil, blobstore.Info{}, "", fmt.Errorf("synthesizer %s returned unsupported audio %s", synthesizer.Name(), contentType)
+	}
+
+	info, err := store.Put(ctx, key+extension, bytes.NewReader(audio))
+	if err != nil {
+		return nil, blobstore.Info{}, "", fmt.Errorf("unable to cache speech: %w", err)
+	}
+	if err := db.TouchCachedSpeech(ctx, key+extension, info.Size, time.Now().UTC()); err != nil {
+		log.Printf("Failed to record cached speech: %v", err)
+	}
+
+	return readSeekNopCloser{bytes.NewReader(audio)}, info, contentType, nil
+}
+
+// PruneSpeech evicts cached speech nobody listened to since unusedSince from the blob store,
+// it is synthesized again when asked for. It returns how much speech was evicted
+func PruneSpeech(ctx context.Context, unusedSince time.Time) (int, error) {
+
+	if store == nil {
+		return 0, ErrNoStore
+	}
+
+	db := database.GetPostgresConn()
+
+	blobKeys, err := db.GetStaleCachedSpeech(ctx, unusedSince, speechEvictionBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	evicted := 0
+	for _, blobKey := range blobKeys {
+		// the blob goes first, a row left behind by a failed delete is retried by the next prune
+		if err := store.Delete(ctx, blobKey); err != nil {
+			return evicted, fmt.Errorf("unable to delete cached speech: %w", err)
+		}
+		deleted, err := db.DeleteCachedSpeech(ctx, blobKey, unusedSince)
+		if err != nil {
+			return evicted, err
+		}
+		if deleted {
+			evicted++
+		}
+	}
+
+	return evicted, nil
+}
+
+// ForgetSpeech removes the speech cached by the current synthesizer for each of texts read in
+// each of langCodes, such as the versions and translations of a message deleted for everyone
+func ForgetSpeech(ctx context.Context, texts []string, langCodes []string) error {
+
+	if synthesizer == nil || len(texts) == 0 {
+		return nil
+	}
+	if store == nil {
+		return ErrNoStore
+	}
+
+	seen := make(map[string]bool)
+	var blobKeys []string
+	for _, text := range texts {
+		if text == "" {
+			continue
+		}
+		for _, langCode := range langCodes {
+			key := speechKey(synthesizer.Name(), text, translation.BareLangCode(langCode))
+			for _, format := range speechExtensions {
+				if !seen[key+format.extension] {
+					seen[key+format.extension] = true
+					blobKeys = append(blobKeys, key+format.extension)
+				}
+			}
+		}
+	}
+
+	// blobs go first, rows left behind by a failed delete are evicted by the next prune
+	for _, blobKey := range blobKeys {
+		if err := store.Delete(ctx, blobKey); err != nil && !errors.Is(err, blobstore.ErrNotFound) {
+			return fmt.Errorf("unable to delete cached speech: %w", err)
+		}
+	}
+
+	db := database.GetPostgresConn()
+
+	_, err := db.DeleteCachedSpeechKeys(ctx, blobKeys)
+	return err
+}
+
+// readSeekNopCloser serves freshly synthesized audio without reading it back from the store
+type readSeekNopCloser struct {
+	io.ReadSeeker
+}
+
+func (readSeekNopCloser) Close() error { return nil }
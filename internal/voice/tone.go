@@ -0,0 +1,115 @@
+package voice
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+const (
+	toneSampleRate = 16000
+
+	// toneMaxDuration bounds the audio of a single text, in samples
+	toneMaxDuration = 60 * toneSampleRate
+)
+
+// ToneSynthesizer is the built-in stand-in used for development, it reads every
+// word as a hum whose length follows the word and whose pitch depends on the language,
+// so clients can exercise playback without a speech server
+type ToneSynthesizer struct{}
+
+func NewToneSynthesizer() *ToneSynthesizer {
+	return &ToneSynthesizer{}
+}
+
+func (t *ToneSynthesizer) Name() string {
+	return "tone"
+}
+
+func (t *ToneSynthesizer) Synthesize(ctx context.Context, text string, langCode string) ([]byte, string, error) {
+
+	if strings.TrimSpace(text) == "" {
+		return nil, "", fmt.Errorf("nothing to synthesize")
+	}
+
+	// each language hums at its own pitch between 180 and 360 Hz
+	h := fnv.New32a()
+	h.Write([]byte(langCode))
+	base := 180 + float64(h.Sum32()%180)
+
+	var samples []int16
+	for i, word := range strings.Fields(text) {
+		if len(samples) >= toneMaxDuration {
+			break
+		}
+
+		letters := 0
+		for _, r := range word {
+			if unicode.IsLetter(r) || unicode.IsNumber(r) {
+				letters++
+			}
+		}
+		if letters > 0 {
+			// 70ms a letter, rising and falling a little from word to word
+			length := min(letters, 12) * 70 * toneSampleRate / 1000
+			samples = appendTone(samples, base*(1+0.06*float64(i%3)), length)
+		}
+
+		pause := 60
+		if strings.ContainsAny(word, ".,;:!?。、！？") {
+			pause = 250
+		}
+		samples = append(samples, make([]int16, pause*toneSampleRate/1000)...)
+	}
+	if len(samples) > toneMaxDuration {
+		samples = samples[:toneMaxDuration]
+	}
+
+	return encodeWAV(samples), "audio/wav", nil
+}
+
+// appendTone appends length samples of a sine at frequency, faded in and out so words do not click
+func appendTone(samples []int16, frequency float64, length int) []int16 {
+
+	fade := min(length/4, 160)
+	for n := 0; n < length; n++ {
+		gain := 1.0
+		if n < fade {
+			gain = float64(n) / float64(fade)
+		} else if n >= length-fade {
+			gain = float64(length-n) / float64(fade)
+		}
+		value := 0.3 * gain * math.Sin(2*math.Pi*frequency*float64(n)/toneSampleRate)
+		samples = append(samples, int16(value*math.MaxInt16))
+	}
+
+	return samples
+}
+
+// encodeWAV wraps 16-bit mono samples in a RIFF WAVE container
+func encodeWAV(samples []int16) []byte {
+
+	dataSize := uint32(len(samples) * 2)
+
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, 36+dataSize)
+	buf.WriteString("WAVEfmt ")
+	binary.Write(&buf, binary.LittleEndian, uint32(16))               // fmt chunk size
+	binary.Write(&buf, binary.LittleEndian, uint16(1))                // PCM
+	binary.Write(&buf, binary.LittleEndian, uint16(1))                // mono
+	binary.Write(&buf, binary.LittleEndian, uint32(toneSampleRate))   // sample rate
+	binary.Write(&buf, binary.LittleEndian, uint32(toneSampleRate*2)) // byte rate
+	binary.Write(&buf, binary.LittleEndian, uint16(2))                // block align
+	binary.Write(&buf, binary.LittleEndian, uint16(16))               // bits per sample
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, dataSize)
+	binary.Write(&buf, binary.LittleEndian, samples)
+
+	return buf.Bytes()
+}
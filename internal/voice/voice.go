@@ -0,0 +1,174 @@
+package voice
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/blobstore"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/database"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/languages"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/translation"
+	"github.com/gofrs/uuid"
+)
+
+// audio formats accepted for voice messages, both carry Opus
+const (
+	ContentTypeWebM = "audio/webm"
+	ContentTypeOgg  = "audio/ogg"
+)
+
+var (
+	ErrUnsupportedAudio = errors.New("recording is not Opus audio in WebM or Ogg")
+	ErrNoStore          = errors.New("no blob store configured")
+	ErrNoTranscriber    = errors.New("no transcriber configured")
+	ErrNotVoiceMessage  = errors.New("message is not a voice message")
+)
+
+// Transcript is the text spoken in a recording
+type Transcript struct {
+	Text string
+	// LangCode is the language the transcriber heard, empty when it does not report one
+	LangCode string
+}
+
+// Transcriber turns speech into text
+type Transcriber interface {
+	// Transcribe returns the text spoken in audio of contentType, langCode is the language
+	// the speaker claims to use and is empty when the transcriber should detect it
+	Transcribe(ctx context.Context, audio io.Reader, contentType string, langCode string) (Transcript, error)
+}
+
+var (
+	store       blobstore.Store
+	transcriber Transcriber
+)
+
+// SetStore sets where recordings are kept, voice messages are rejected without one
+func SetStore(s blobstore.Store) {
+	store = s
+}
+
+// SetTranscriber sets the speech-to-text backend, voice messages stay without text without one
+func SetTranscriber(t Transcriber) {
+	transcriber = t
+}
+
+// SniffRecording returns the content type of a recording by its magic bytes and
+// reports whether it is Opus in a WebM or Ogg container
+func SniffRecording(data []byte) (string, bool) {
+
+	// the codec is declared in the container header, looking at its start is enough
+	header := data[:min(len(data), 4096)]
+
+	switch {
+	case bytes.HasPrefix(data, []byte{0x1A, 0x45, 0xDF, 0xA3}):
+		return ContentTypeWebM, bytes.Contains(header, []byte("A_OPUS"))
+	case bytes.HasPrefix(data, []byte("OggS")):
+		return ContentTypeOgg, bytes.Contains(header, []byte("OpusHead"))
+	}
+
+	return "", false
+}
+
+// SaveRecording stores the recording data and returns the attachment of the voice message
+// it belongs to, durationMs is reported by the recording client and may be nil
+func SaveRecording(ctx context.Context, data []byte, durationMs *int) (models.VoiceAttachment, error) {
+
+	if store == nil {
+		return models.VoiceAttachment{}, ErrNoStore
+	}
+
+	contentType, ok := SniffRecording(data)
+	if !ok {
+		return models.VoiceAttachment{}, ErrUnsupportedAudio
+	}
+
+	blobID, err := uuid.NewV4()
+	if err != nil {
+		return models.VoiceAttachment{}, fmt.Errorf("unable to generate uuid %w", err)
+	}
+
+	extension := ".webm"
+	if contentType == ContentTypeOgg {
+		extension = ".ogg"
+	}
+	key := "voice/" + blobID.String() + extension
+
+	info, err := store.Put(ctx, key, bytes.NewReader(data))
+	if err != nil {
+		return models.VoiceAttachment{}, fmt.Errorf("unable to store recording: %w", err)
+	}
+
+	return models.VoiceAttachment{
+		BlobKey:          key,
+		ContentType:      contentType,
+		SizeBytes:        info.Size,
+		DurationMs:       durationMs,
+		TranscriptStatus: models.TranscriptPending,
+	}, nil
+}
+
+// DeleteRecording removes a recording whose message could not be created or was deleted for everyone
+func DeleteRecording(ctx context.Context, voice models.VoiceAttachment) error {
+	if store == nil {
+		return ErrNoStore
+	}
+	return store.Delete(ctx, voice.BlobKey)
+}
+
+// OpenRecording returns the audio of a voice message for playback
+func OpenRecording(ctx context.Context, voice models.VoiceAttachment) (io.ReadSeekCloser, blobstore.Info, error) {
+	if store == nil {
+		return nil, blobstore.Info{}, ErrNoStore
+	}
+	return store.Open(ctx, voice.BlobKey)
+}
+
+// TranscribeMessage transcribes the recording of a live voice message and stores the
+// transcript as its content. Without a language chosen by the sender the one the transcriber
+// heard is used, either is then checked against the transcript like for typed messages
+func TranscribeMessage(ctx context.Context, msg *models.MessageResponse) error {
+
+	if msg.Voice == nil {
+		return ErrNotVoiceMessage
+	}
+	if transcriber == nil {
+		return ErrNoTranscriber
+	}
+	if store == nil {
+		return ErrNoStore
+	}
+
+	recording, _, err := store.Open(ctx, msg.Voice.BlobKey)
+	if err != nil {
+		return fmt.Errorf("unable to open recording: %w", err)
+	}
+	defer recording.Close()
+
+	claimed := translation.BareLangCode(msg.LangCode)
+
+	transcript, err := transcriber.Transcribe(ctx, recording, msg.Voice.ContentType, claimed)
+	if err != nil {
+		return fmt.Errorf("unable to transcribe recording: %w", err)
+	}
+
+	msg.Content = strings.TrimSpace(transcript.Text)
+
+	if claimed == "" {
+		if heard, err := languages.Normalize(transcript.LangCode); err == nil {
+			msg.LangCode = translation.StoredLangCode(heard)
+		} else if heard, ok := languages.ByName(transcript.LangCode); ok {
+			msg.LangCode = translation.StoredLangCode(heard)
+		}
+	}
+	translation.DetectMessageLanguage(msg)
+
+	db := database.GetPostgresConn()
+
+	return db.SetVoiceTranscript(ctx, msg)
+}
@@ -0,0 +1,150 @@
+package voice
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultWhisperModel is the model name OpenAI compatible servers expect when none is configured
+	defaultWhisperModel = "whisper-1"
+
+	// defaultTranscriptionTimeout stays below the timeout of a background job
+	defaultTranscriptionTimeout = 50 * time.Second
+)
+
+// WhisperTranscriber talks to a speech-to-text server speaking the OpenAI transcription API,
+// such as a self-hosted whisper.cpp or faster-whisper server, so audio never leaves the deployment
+type WhisperTranscriber struct {
+	endpoint string
+	apiKey   string
+	model    string
+	client   *http.Client
+}
+
+// NewWhisperTranscriber returns a transcriber for the server at endpoint, apiKey may be empty
+// for servers that do not require one and model defaults to whisper-1
+func NewWhisperTranscriber(endpoint string, apiKey string, model string, client *http.Client) *WhisperTranscriber {
+
+	if model == "" {
+		model = defaultWhisperModel
+	}
+	if client == nil {
+		client = &http.Client{Timeout: defaultTranscriptionTimeout}
+	}
+
+	return &WhisperTranscriber{
+		endpoint: strings.TrimRight(endpoint, "/"),
+		apiKey:   apiKey,
+		model:    model,
+		client:   client,
+	}
+}
+
+func (w *WhisperTranscriber) Transcribe(ctx context.Context, audio io.Reader, contentType string, langCode string) (Transcript, error) {
+
+	// the recording is streamed into the multipart body instead of being buffered
+	bodyReader, bodyWriter := io.Pipe()
+	form := multipart.NewWriter(bodyWriter)
+
+	go func() {
+		bodyWriter.CloseWithError(writeTranscriptionForm(form, audio, contentType, w.model, langCode))
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.endpoint+"/v1/audio/transcriptions", bodyReader)
+	if err != nil {
+		bodyReader.Close()
+		return Transcript{}, fmt.Errorf("unable to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	if w.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+w.apiKey)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		bodyReader.Close()
+		return Transcript{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return Transcript{}, fmt.Errorf("transcriber responded %s: %s", resp.Status, strings.TrimSpace(string(snippet)))
+	}
+
+	var body struct {
+		Text     string `json:"text"`
+		Language string `json:"language"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return Transcript{}, fmt.Errorf("unable to decode response: %w", err)
+	}
+
+	return Transcript{Text: body.Text, LangCode: body.Language}, nil
+}
+
+// writeTranscriptionForm writes the fields of a transcription request followed by the recording
+func writeTranscriptionForm(form *multipart.Writer, audio io.Reader, contentType string, model string, langCode string) error {
+
+	fields := map[string]string{
+		"model":           model,
+		"response_format": "verbose_json",
+	}
+	if langCode != "" {
+		fields["language"] = langCode
+	}
+	for name, value := range fields {
+		if err := form.WriteField(name, value); err != nil {
+			return err
+		}
+	}
+
+	filename := "recording.webm"
+	if contentType == ContentTypeOgg {
+		filename = "recording.ogg"
+	}
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%s"`, filename))
+	header.Set("Content-Type", contentType)
+
+	part, err := form.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, audio); err != nil {
+		return err
+	}
+
+	return form.Close()
+}
+
+// TranscriberFromEnv returns a WhisperTranscriber when WHISPER_URL is set, nil otherwise.
+// WHISPER_API_KEY, WHISPER_MODEL and TRANSCRIPTION_TIMEOUT are optional
+func TranscriberFromEnv() (Transcriber, error) {
+
+	endpoint := os.Getenv("WHISPER_URL")
+	if endpoint == "" {
+		return nil, nil
+	}
+
+	timeout := defaultTranscriptionTimeout
+	if timeoutStr := os.Getenv("TRANSCRIPTION_TIMEOUT"); timeoutStr != "" {
+		var err error
+		timeout, err = time.ParseDuration(timeoutStr)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("invalid TRANSCRIPTION_TIMEOUT %q", timeoutStr)
+		}
+	}
+
+	return NewWhisperTranscriber(endpoint, os.Getenv("WHISPER_API_KEY"), os.Getenv("WHISPER_MODEL"), &http.Client{Timeout: timeout}), nil
+}
@@ -6,10 +6,16 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/JohnSalinas123/linguachat-backend-go/internal/database"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/jobs"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/languages"
 	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/permissions"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/translation"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/transliteration"
 	"github.com/gin-gonic/gin"
 	"github.com/gofrs/uuid"
 	"github.com/gorilla/websocket"
@@ -38,15 +44,61 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
+// frame types a client may send, frames without a type create a message
+const (
+	frameMessageCreate    = "message.create"
+	frameMessageEdit      = "message.edit"
+	frameMessageDelete    = "message.delete"
+	frameReaction         = "reaction.toggle"
+	frameCorrectionCreate = "correction.create"
+	frameCorrectionAccept = "correction.accept"
+)
+
+// scopes of a message.delete frame, frames without a scope delete for the sender only
+const (
+	deleteScopeEveryone = "everyone"
+	deleteScopeMe       = "me"
+)
+
+// inboundFrame is a frame received from a client
+type inboundFrame struct {
+	Type         string `json:"type"`
+	MessageID    string `json:"message_id"`
+	Content      string `json:"content"`
+	LangCode     string `json:"lang_code"`
+	Scope        string `json:"scope"`
+	ReplyToID    string `json:"reply_to_id"`
+	Emoji        string `json:"emoji"`
+	CorrectionID string `json:"correction_id"`
+}
+
 type Client struct {
 	userID string
 	chatID uuid.UUID
+	// role is kept current by the hub as the participant's role changes
+	roleMu sync.Mutex
+	role models.ChatRole
 	langCode string
+	romanize bool
+	// envelope is set for clients of models.EventProtocolVersion
+	envelope bool
 	hub *Hub
 	conn *websocket.Conn
 	send chan []byte
 }
 
+func (c *Client) currentRole() models.ChatRole {
+	c.roleMu.Lock()
+	defer c.roleMu.Unlock()
+	return c.role
+}
+
+func (c *Client) setRole(role models.ChatRole) {
+	c.roleMu.Lock()
+	defer c.roleMu.Unlock()
+	c.role = role
+}
+
 func (c *Client) readPump() {
 	defer func() {
 		c.hub.unregister <- c
@@ -65,29 +117,128 @@ func (c *Client) readPump() {
 			break
 		}
 
-		var msg models.MessageResponse
-		if err := json.Unmarshal(bytes.TrimSpace(message), &msg); err != nil {
+		var frame inboundFrame
+		if err := json.Unmarshal(bytes.TrimSpace(message), &frame); err != nil {
 			log.Printf("Invalid message format: %v", err)
 			continue
 		}
 
-		msg.ChatID = c.chatID
+		// role changes reach the client through the hub, removed participants are disconnected
+		role := c.currentRole()
 
-		log.Println("MESSAGE RECEIVED")
-		// save message to database, no translation so far
-		db := database.GetPostgresConn()
+		switch frame.Type {
+		case "", frameMessageCreate, frameMessageEdit:
+			if !permissions.Can(role, permissions.ActionSendMessage) {
+				log.Printf("User %s with role %s not permitted to send message in chat %s", c.userID, role, c.chatID)
+				continue
+			}
+			if frame.Type == frameMessageEdit {
+				_, err := EditMessage(context.Background(), c.hub, c.chatID.String(), frame.MessageID, c.userID, frame.Content)
+				if err != nil {
+					log.Printf("Failed to edit message %s: %v", frame.MessageID, err)
+				}
+				continue
+			}
+			c.createMessage(frame)
+		case frameMessageDelete:
+			var err error
+			switch frame.Scope {
+			case deleteScopeEveryone:
+				_, err = DeleteMessageForEveryone(context.Background(), c.hub, c.chatID.String(), frame.MessageID, c.userID, role)
+			case "", deleteScopeMe:
+				err = database.GetPostgresConn().HideMessageForUser(context.Background(), c.chatID.String(), frame.MessageID, c.userID)
+			default:
+				log.Printf("Unknown delete scope %s", frame.Scope)
+				continue
+			}
+			if err != nil {
+				log.Printf("Failed to delete message %s: %v", frame.MessageID, err)
+			}
+		case frameReaction:
+			if !permissions.Can(role, permissions.ActionReact) {
+				log.Printf("User %s with role %s not permitted to react in chat %s", c.userID, role, c.chatID)
+				continue
+			}
+			_, err := ToggleReaction(context.Background(), c.hub, c.chatID.String(), frame.MessageID, c.userID, frame.Emoji)
+			if err != nil {
+				log.Printf("Failed to toggle reaction on message %s: %v", frame.MessageID, err)
+			}
+		case frameCorrectionCreate:
+			if !permissions.Can(role, permissions.ActionCorrectMessage) {
+				log.Printf("User %s with role %s not permitted to correct messages in chat %s", c.userID, role, c.chatID)
+				continue
+			}
+			_, err := CreateCorrection(context.Background(), c.hub, c.chatID.String(), frame.MessageID, c.userID, frame.Content)
+			if err != nil {
+				log.Printf("Failed to correct message %s: %v", frame.MessageID, err)
+			}
+		case frameCorrectionAccept:
+			_, err := AcceptCorrection(context.Background(), c.hub, c.chatID.String(), frame.MessageID, frame.CorrectionID, c.userID)
+			if err != nil {
+				log.Printf("Failed to accept correction %s: %v", frame.CorrectionID, err)
+			}
+		default:
+			log.Printf("Unknown frame type %s", frame.Type)
+		}
+	}
 
-		newMessage, dbError := db.CreateMessage(context.Background(), &msg)
-		if dbError != nil {
-			log.Printf("Failed to create message: %v", dbError)
-			continue
+
+}
+
+// createMessage saves a new message sent by the client, broadcasts it and queues its translation
+func (c *Client) createMessage(frame inboundFrame) {
+
+	msg := models.MessageResponse{
+		ChatID:   c.chatID,
+		SenderID: c.userID,
+		Content:  frame.Content,
+	}
+
+	// an unsupported lang_code is rejected, a missing one is left to detection
+	if frame.LangCode != "" {
+		langCode, err := languages.Normalize(frame.LangCode)
+		if err != nil {
+			log.Printf("Invalid lang_code in message frame: %v", err)
+			c.hub.Send(c, models.ChatEvent{Type: models.EventError, ChatID: c.chatID, Payload: models.EventErrorResponse{
+				Frame: frameMessageCreate,
+				Error: "Unsupported language",
+			}})
+			return
+		}
+		msg.LangCode = translation.StoredLangCode(langCode)
+	}
+
+	if frame.ReplyToID != "" {
+		replyToID, err := uuid.FromString(frame.ReplyToID)
+		if err != nil {
+			log.Printf("Invalid reply_to_id %s: %v", frame.ReplyToID, err)
+			return
 		}
- 
+		msg.ReplyToID = &replyToID
+	}
 
-		c.hub.broadcast <- newMessage
+	// clients may send the wrong lang_code, learners often write in the other language
+	translation.DetectMessageLanguage(&msg)
+
+	log.Println("MESSAGE RECEIVED")
+	db := database.GetPostgresConn()
+
+	newMessage, dbError := db.CreateMessage(context.Background(), &msg)
+	if dbError != nil {
+		log.Printf("Failed to create message: %v", dbError)
+		return
 	}
 
+	// romanizing runs offline, unlike translation it does not need to wait for the job
+	transliteration.AttachRomanization(context.Background(), &newMessage)
 
+	// broadcast the original right away, message.translated follows once the job ran
+	c.hub.Publish(models.ChatEvent{Type: models.EventMessageCreated, ChatID: c.chatID, Payload: newMessage})
+
+	err := jobs.EnqueueTranslation(context.Background(), newMessage.ID.String())
+	if err != nil {
+		log.Printf("Failed to queue translation of message %s: %v", newMessage.ID, err)
+	}
 }
 
 
@@ -149,6 +300,13 @@ func ServeWs(hub *Hub, c *gin.Context, userID string) {
 		return
 	}
 
+	role, err := permissions.Authorize(c.Request.Context(), chatIDStr, userID, permissions.ActionViewChat)
+	if err != nil {
+		log.Printf("User %s not permitted to join chat %s: %v", userID, chatIDStr, err)
+		c.JSON(http.StatusForbidden, gin.H{"error" : "Forbidden"})
+		return
+	}
+
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		log.Println("Websocket upgrade error:", err)
@@ -156,7 +314,22 @@ func ServeWs(hub *Hub, c *gin.Context, userID string) {
 		return
 	}
 
-	client := &Client{userID: userID, chatID: chatID, hub: hub, conn: conn, send: make(chan []byte, 256)}
+	// live messages are resolved into the user's language before being sent
+	langCode, err := database.GetPostgresConn().GetUserLangCode(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("Failed to retrieve lang_code of user %s, sending untranslated messages: %v", userID, err)
+	}
+
+	// romanization is sent to users who opted in
+	romanize, err := database.GetPostgresConn().GetUserRomanization(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("Failed to retrieve romanization setting of user %s: %v", userID, err)
+	}
+
+	// events arrive in envelopes only for clients that asked for them
+	envelope := c.Query("protocol") == models.EventProtocolVersion
+
+	client := &Client{userID: userID, chatID: chatID, role: role, langCode: langCode, romanize: romanize, envelope: envelope, hub: hub, conn: conn, send: make(chan []byte, 256)}
 	client.hub.register <- client
 
 	// goroutines
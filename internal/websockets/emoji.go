@@ -0,0 +1,168 @@
+package websockets
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxEmojiLength bounds a reaction to a single emoji including modifiers and joiners
+const maxEmojiLength = 8
+
+const (
+	zeroWidthJoiner   = '\u200D'
+	variationEmoji    = '\uFE0F'
+	combiningKeycap   = '\u20E3'
+	cancelTag         = '\U000E007F'
+	skinToneFirst     = '\U0001F3FB'
+	skinToneLast      = '\U0001F3FF'
+	regionalIndicator = '\U0001F1E6'
+)
+
+// pictographic holds the Extended_Pictographic characters of Unicode, the bases an emoji is built on
+var pictographic = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x00A9, Hi: 0x00AE, Stride: 5},
+		{Lo: 0x203C, Hi: 0x2049, Stride: 13},
+		{Lo: 0x2122, Hi: 0x2139, Stride: 23},
+		{Lo: 0x2194, Hi: 0x2199, Stride: 1},
+		{Lo: 0x21A9, Hi: 0x21AA, Stride: 1},
+		{Lo: 0x231A, Hi: 0x231B, Stride: 1},
+		{Lo: 0x2328, Hi: 0x2388, Stride: 96},
+		{Lo: 0x23CF, Hi: 0x23CF, Stride: 1},
+		{Lo: 0x23E9, Hi: 0x23F3, Stride: 1},
+		{Lo: 0x23F8, Hi: 0x23FA, Stride: 1},
+		{Lo: 0x24C2, Hi: 0x24C2, Stride: 1},
+		{Lo: 0x25AA, Hi: 0x25AB, Stride: 1},
+		{Lo: 0x25B6, Hi: 0x25C0, Stride: 10},
+		{Lo: 0x25FB, Hi: 0x25FE, Stride: 1},
+		{Lo: 0x2600, Hi: 0x2605, Stride: 1},
+		{Lo: 0x2607, Hi: 0x2612, Stride: 1},
+		{Lo: 0x2614, Hi: 0x2685, Stride: 1},
+		{Lo: 0x2690, Hi: 0x2705, Stride: 1},
+		{Lo: 0x2708, Hi: 0x2712, Stride: 1},
+		{Lo: 0x2714, Hi: 0x2716, Stride: 2},
+		{Lo: 0x271D, Hi: 0x2721, Stride: 4},
+		{Lo: 0x2728, Hi: 0x2728, Stride: 1},
+		{Lo: 0x2733, Hi: 0x2734, Stride: 1},
+		{Lo: 0x2744, Hi: 0x2747, Stride: 3},
+		{Lo: 0x274C, Hi: 0x274E, Stride: 2},
+		{Lo: 0x2753, Hi: 0x2755, Stride: 1},
+		{Lo: 0x2757, Hi: 0x2757, Stride: 1},
+		{Lo: 0x2763, Hi: 0x2767, Stride: 1},
+		{Lo: 0x2795, Hi: 0x2797, Stride: 1},
+		{Lo: 0x27A1, Hi: 0x27B0, Stride: 15},
+		{Lo: 0x27BF, Hi: 0x27BF, Stride: 1},
+		{Lo: 0x2934, Hi: 0x2935, Stride: 1},
+		{Lo: 0x2B05, Hi: 0x2B07, Stride: 1},
+		{Lo: 0x2B1B, Hi: 0x2B1C, Stride: 1},
+		{Lo: 0x2B50, Hi: 0x2B55, Stride: 5},
+		{Lo: 0x3030, Hi: 0x303D, Stride: 13},
+		{Lo: 0x3297, Hi: 0x3299, Stride: 2},
+	},
+	R32: []unicode.Range32{
+		{Lo: 0x1F000, Hi: 0x1F0FF, Stride: 1},
+		{Lo: 0x1F10D, Hi: 0x1F10F, Stride: 1},
+		{Lo: 0x1F12F, Hi: 0x1F12F, Stride: 1},
+		{Lo: 0x1F16C, Hi: 0x1F171, Stride: 1},
+		{Lo: 0x1F17E, Hi: 0x1F17F, Stride: 1},
+		{Lo: 0x1F18E, Hi: 0x1F18E, Stride: 1},
+		{Lo: 0x1F191, Hi: 0x1F19A, Stride: 1},
+		{Lo: 0x1F1AD, Hi: 0x1F1E5, Stride: 1},
+		{Lo: 0x1F201, Hi: 0x1F20F, Stride: 1},
+		{Lo: 0x1F21A, Hi: 0x1F22F, Stride: 21},
+		{Lo: 0x1F232, Hi: 0x1F23A, Stride: 1},
+		{Lo: 0x1F23C, Hi: 0x1F23F, Stride: 1},
+		{Lo: 0x1F249, Hi: 0x1F3FA, Stride: 1},
+		{Lo: 0x1F400, Hi: 0x1F53D, Stride: 1},
+		{Lo: 0x1F546, Hi: 0x1F64F, Stride: 1},
+		{Lo: 0x1F680, Hi: 0x1F6FF, Stride: 1},
+		{Lo: 0x1F774, Hi: 0x1F77F, Stride: 1},
+		{Lo: 0x1F7D5, Hi: 0x1F7FF, Stride: 1},
+		{Lo: 0x1F80C, Hi: 0x1F80F, Stride: 1},
+		{Lo: 0x1F848, Hi: 0x1F84F, Stride: 1},
+		{Lo: 0x1F85A, Hi: 0x1F85F, Stride: 1},
+		{Lo: 0x1F888, Hi: 0x1F88F, Stride: 1},
+		{Lo: 0x1F8AE, Hi: 0x1F8FF, Stride: 1},
+		{Lo: 0x1F90C, Hi: 0x1F93A, Stride: 1},
+		{Lo: 0x1F93C, Hi: 0x1F945, Stride: 1},
+		{Lo: 0x1F947, Hi: 0x1FAFF, Stride: 1},
+		{Lo: 0x1FC00, Hi: 0x1FFFD, Stride: 1},
+	},
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= regionalIndicator && r <= regionalIndicator+25
+}
+
+func isSkinTone(r rune) bool {
+	return r >= skinToneFirst && r <= skinToneLast
+}
+
+func isTag(r rune) bool {
+	return r >= '\U000E0020' && r <= '\U000E007E'
+}
+
+// NormalizeEmoji checks that emoji is a single emoji and returns it without a trailing
+// variation selector, so "❤" and "❤️" are the same reaction. Accepted are pictographs joined
+// by zero width joiners, each with an optional variation selector, skin tone and tag sequence,
+// flags of two regional indicators and keycaps
+func NormalizeEmoji(emoji string) (string, bool) {
+
+	emoji = strings.TrimSuffix(emoji, string(variationEmoji))
+	if emoji == "" || utf8.RuneCountInString(emoji) > maxEmojiLength {
+		return "", false
+	}
+
+	runes := []rune(emoji)
+
+	// keycaps are a digit, # or * made an emoji by the combining keycap
+	if strings.ContainsRune("0123456789#*", runes[0]) {
+		rest := runes[1:]
+		if len(rest) > 0 && rest[0] == variationEmoji {
+			rest = rest[1:]
+		}
+		if len(rest) != 1 || rest[0] != combiningKeycap {
+			return "", false
+		}
+		return emoji, true
+	}
+
+	// flags are a pair of regional indicators
+	if isRegionalIndicator(runes[0]) {
+		if len(runes) != 2 || !isRegionalIndicator(runes[1]) {
+			return "", false
+		}
+		return emoji, true
+	}
+
+	i := 0
+	for {
+		if i == len(runes) || !unicode.Is(pictographic, runes[i]) {
+			return "", false
+		}
+		i++
+		if i < len(runes) && runes[i] == variationEmoji {
+			i++
+		}
+		if i < len(runes) && isSkinTone(runes[i]) {
+			i++
+		}
+		if i < len(runes) && isTag(runes[i]) {
+			for i < len(runes) && isTag(runes[i]) {
+				i++
+			}
+			if i == len(runes) || runes[i] != cancelTag {
+				return "", false
+			}
+			i++
+		}
+		if i == len(runes) {
+			return emoji, true
+		}
+		if runes[i] != zeroWidthJoiner {
+			return "", false
+		}
+		i++
+	}
+}
@@ -3,6 +3,7 @@ package websockets
 import (
 	"encoding/json"
 	"log"
+	"sync"
 
 	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
 	"github.com/gofrs/uuid"
@@ -12,22 +13,78 @@ type Hub struct {
 	// registered clients, mapped by chatID
 	chats map[uuid.UUID]map[*Client]bool
 
-	broadcast chan models.MessageResponse
+	broadcast chan models.ChatEvent
+
+	// events for a single client
+	direct chan directEvent
+
+	// participant changes applied to the open connections of a user
+	participants chan participantChange
 
 	register chan *Client
 
 	unregister chan *Client
 }
 
+var (
+	hubInstance	*Hub
+	hubOnce		sync.Once
+)
+
 func NewHub() *Hub {
 	return &Hub{
-		broadcast:  make(chan models.MessageResponse),
+		broadcast:  make(chan models.ChatEvent),
+		direct:     make(chan directEvent),
+		participants: make(chan participantChange),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
 		chats:    make(map[uuid.UUID]map[*Client]bool),
 	}
 }
 
+// GetHub returns the hub shared by the websocket server and the REST handlers
+func GetHub() *Hub {
+	hubOnce.Do(func() {
+		hubInstance = NewHub()
+	})
+	return hubInstance
+}
+
+// Publish sends event to every client connected to event.ChatID
+func (h *Hub) Publish(event models.ChatEvent) {
+	h.broadcast <- event
+}
+
+// directEvent is an event for client alone
+type directEvent struct {
+	client *Client
+	event  models.ChatEvent
+}
+
+// Send sends event to client alone, such as the rejection of a frame it sent. Clients of
+// the original protocol do not receive it
+func (h *Hub) Send(client *Client, event models.ChatEvent) {
+	h.direct <- directEvent{client: client, event: event}
+}
+
+// participantChange is a new role of userID in chatID, an empty role removes the user from the chat
+type participantChange struct {
+	chatID uuid.UUID
+	userID string
+	role   models.ChatRole
+}
+
+// Disconnect closes every connection of userID to chatID, called once the user
+// is no longer a participant so they stop receiving the chat's events
+func (h *Hub) Disconnect(chatID uuid.UUID, userID string) {
+	h.participants <- participantChange{chatID: chatID, userID: userID}
+}
+
+// SetRole updates the role the open connections of userID to chatID act with
+func (h *Hub) SetRole(chatID uuid.UUID, userID string, role models.ChatRole) {
+	h.participants <- participantChange{chatID: chatID, userID: userID, role: role}
+}
+
 func (h *Hub) Run() {
 	for {
 		select {
@@ -71,19 +128,43 @@ func (h *Hub) Run() {
 			}
 
 		// broadcast messages to clients in chat 
-		case message := <-h.broadcast:
-			chat := h.chats[message.ChatID]
+		case event := <-h.broadcast:
+			chat := h.chats[event.ChatID]
 			if chat != nil {
 
-				messageBytes, err := json.Marshal(message)
-				if err !=nil {
-					log.Printf("Failed to convert message to []bytes: %v", err)
-					continue
-				}
-
+				// message payloads differ per viewer, marshal once per group of equal viewers
+				eventBytes := make(map[string][]byte)
 
 				for client := range chat { 
 
+					viewer := models.Viewer{UserID: client.userID, LangCode: client.langCode, Romanize: client.romanize}
+					viewerKey := event.ViewerKey(viewer)
+
+					// clients of the original protocol only receive new messages, without envelope
+					if !client.envelope {
+						if !event.Legacy() {
+							continue
+						}
+						viewerKey = "legacy:" + viewerKey
+					}
+
+					messageBytes, ok := eventBytes[viewerKey]
+					if !ok {
+						resolved := event.ForViewer(viewer)
+						var frame interface{} = resolved
+						if !client.envelope {
+							frame = resolved.Payload
+						}
+
+						var err error
+						messageBytes, err = json.Marshal(frame)
+						if err !=nil {
+							log.Printf("Failed to convert event to []bytes: %v", err)
+							continue
+						}
+						eventBytes[viewerKey] = messageBytes
+					}
+
 					select {
 						case client.send <- messageBytes:
 						default:
@@ -93,10 +174,53 @@ func (h *Hub) Run() {
 				}
 				if len(chat) == 0 {
 					// chat emptied when broadcasting
-					delete(h.chats, message.ChatID)
+					delete(h.chats, event.ChatID)
 				}
 			}
 
+		// apply role changes and removals of participants
+		case change := <-h.participants:
+			chat := h.chats[change.chatID]
+			for client := range chat {
+				if client.userID != change.userID {
+					continue
+				}
+				if change.role != "" {
+					client.setRole(change.role)
+					continue
+				}
+				log.Printf("User %s removed from chat %s, closing connection", client.userID, client.chatID)
+				delete(chat, client)
+				close(client.send)
+			}
+			if chat != nil && len(chat) == 0 {
+				delete(h.chats, change.chatID)
+			}
+
+		// send events to a single client
+		case direct := <-h.direct:
+			client := direct.client
+			chat := h.chats[client.chatID]
+			if !chat[client] || !client.envelope {
+				continue
+			}
+
+			messageBytes, err := json.Marshal(direct.event)
+			if err != nil {
+				log.Printf("Failed to convert event to []bytes: %v", err)
+				continue
+			}
+
+			select {
+				case client.send <- messageBytes:
+				default:
+					close(client.send)
+					delete(chat, client)
+					if len(chat) == 0 {
+						delete(h.chats, client.chatID)
+					}
+			}
+
 		}
 	}
 }
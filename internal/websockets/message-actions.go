@@ -0,0 +1,283 @@
+package websockets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/database"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/jobs"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/learning"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/models"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/permissions"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/stats"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/translation"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/transliteration"
+	"github.com/JohnSalinas123/linguachat-backend-go/internal/voice"
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// CreateVoiceMessage stores the recording data sent by userID as a new message of chatID,
+// broadcasts message.created and queues its transcription. langCode may be empty
+// when the sender leaves the language to the transcriber
+func CreateVoiceMessage(ctx context.Context, hub *Hub, chatID uuid.UUID, userID string, langCode string, replyToID *uuid.UUID, data []byte, durationMs *int) (models.MessageResponse, error) {
+
+	recording, err := voice.SaveRecording(ctx, data, durationMs)
+	if err != nil {
+		return models.MessageResponse{}, err
+	}
+
+	msg := models.MessageResponse{
+		ChatID:    chatID,
+		SenderID:  userID,
+		LangCode:  langCode,
+		ReplyToID: replyToID,
+		Voice:     &recording,
+	}
+
+	db := database.GetPostgresConn()
+
+	newMessage, err := db.CreateMessage(ctx, &msg)
+	if err != nil {
+		if deleteErr := voice.DeleteRecording(ctx, recording); deleteErr != nil {
+			log.Printf("Failed to delete recording %s of uncreated message: %v", recording.BlobKey, deleteErr)
+		}
+		return models.MessageResponse{}, err
+	}
+
+	// the text follows in message.transcribed, then message.translated
+	hub.Publish(models.ChatEvent{Type: models.EventMessageCreated, ChatID: chatID, Payload: newMessage})
+
+	err = jobs.EnqueueTranscription(ctx, newMessage.ID.String())
+	if err != nil {
+		log.Printf("Failed to queue transcription of message %s: %v", newMessage.ID, err)
+	}
+
+	return newMessage, nil
+}
+
+// EditMessage replaces the content of a message sent by userID, broadcasts
+// message.edited and queues its translation, shared by the websocket client and REST handlers
+func EditMessage(ctx context.Context, hub *Hub, chatID string, messageID string, userID string, content string) (models.MessageResponse, error) {
+
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return models.MessageResponse{}, fmt.Errorf("edited content is empty")
+	}
+	if len(content) > maxContentSize {
+		return models.MessageResponse{}, ErrContentTooLong
+	}
+
+	db := database.GetPostgresConn()
+
+	// the stats of the sender counted the previous version, the edit may be in another language
+	before, editedMessage, err := db.EditMessage(ctx, chatID, messageID, userID, content, translation.DetectMessageLanguage)
+	if err != nil {
+		return models.MessageResponse{}, err
+	}
+
+	after := before
+	after.Content = editedMessage.Content
+	after.LangCode = editedMessage.LangCode
+	err = stats.Recount(ctx, before, &after)
+	if err != nil {
+		log.Printf("Failed to recount stats of edited message %s: %v", editedMessage.ID, err)
+	}
+
+	transliteration.AttachRomanization(ctx, &editedMessage)
+
+	hub.Publish(models.ChatEvent{Type: models.EventMessageEdited, ChatID: editedMessage.ChatID, Payload: editedMessage})
+
+	err = jobs.EnqueueTranslation(ctx, editedMessage.ID.String())
+	if err != nil {
+		log.Printf("Failed to queue translation of edited message %s: %v", editedMessage.ID, err)
+	}
+
+	return editedMessage, nil
+}
+
+// DeleteMessageForEveryone tombstones a message and broadcasts message.deleted,
+// the sender may always delete and role decides whether others' messages may be deleted
+func DeleteMessageForEveryone(ctx context.Context, hub *Hub, chatID string, messageID string, userID string, role models.ChatRole) (models.MessageDeletedResponse, error) {
+
+	db := database.GetPostgresConn()
+
+	canDeleteOthers := permissions.Can(role, permissions.ActionDeleteOthersMessage)
+
+	// the stats of the sender counted the message
+	before, err := db.GetMessage(ctx, messageID)
+	if err != nil {
+		return models.MessageDeletedResponse{}, err
+	}
+
+	deleted, err := db.DeleteMessageForEveryone(ctx, chatID, messageID, userID, canDeleteOthers)
+	if err != nil {
+		return models.MessageDeletedResponse{}, err
+	}
+
+	err = stats.Recount(ctx, before, nil)
+	if err != nil {
+		log.Printf("Failed to recount stats of deleted message %s: %v", deleted.MessageID, err)
+	}
+
+	hub.Publish(models.ChatEvent{Type: models.EventMessageDeleted, ChatID: deleted.ChatID, Payload: deleted})
+
+	err = translation.ForgetTexts(ctx, deleted.ChatID.String(), deleted.DeletedContents)
+	if err != nil {
+		log.Printf("Failed to purge cached translations of message %s: %v", deleted.MessageID, err)
+	}
+
+	err = voice.ForgetSpeech(ctx, append(deleted.DeletedContents, deleted.DeletedTranslations...), deleted.DeletedLangCodes)
+	if err != nil && !errors.Is(err, voice.ErrNoStore) {
+		log.Printf("Failed to purge synthesized speech of message %s: %v", deleted.MessageID, err)
+	}
+
+	if deleted.VoiceBlobKey != "" {
+		err = jobs.EnqueueRecordingDeletion(ctx, deleted.VoiceBlobKey)
+		if err != nil {
+			log.Printf("Failed to queue deletion of recording %s: %v", deleted.VoiceBlobKey, err)
+		}
+	}
+
+	return deleted, nil
+}
+
+// ToggleReaction adds or removes the reaction of userID on a message
+// and broadcasts reaction.added or reaction.removed
+func ToggleReaction(ctx context.Context, hub *Hub, chatID string, messageID string, userID string, emoji string) (models.ReactionResponse, error) {
+
+	normalized, ok := NormalizeEmoji(emoji)
+	if !ok {
+		return models.ReactionResponse{}, fmt.Errorf("invalid emoji %q", emoji)
+	}
+
+	db := database.GetPostgresConn()
+
+	reaction, err := db.ToggleReaction(ctx, chatID, messageID, userID, normalized)
+	if err != nil {
+		return models.ReactionResponse{}, err
+	}
+
+	eventType := models.EventReactionRemoved
+	if reaction.Added {
+		eventType = models.EventReactionAdded
+	}
+
+	hub.Publish(models.ChatEvent{Type: eventType, ChatID: reaction.ChatID, Payload: reaction})
+
+	return reaction, nil
+}
+
+var ErrCorrectOwnMessage = errors.New("participants can not correct their own messages")
+
+var ErrContentTooLong = errors.New("content too long")
+
+// maxContentSize bounds the content of messages sent through REST like a websocket frame bounds it
+const maxContentSize = maxMessageSize
+
+// CreateCorrection saves the correction of userID for a message of another participant with
+// a word-level diff against the current content and broadcasts correction.created
+func CreateCorrection(ctx context.Context, hub *Hub, chatID string, messageID string, userID string, content string) (models.CorrectionResponse, error) {
+
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return models.CorrectionResponse{}, fmt.Errorf("corrected content is empty")
+	}
+	if len(content) > maxContentSize {
+		return models.CorrectionResponse{}, ErrContentTooLong
+	}
+
+	db := database.GetPostgresConn()
+
+	msg, err := db.GetMessage(ctx, messageID)
+	if err != nil {
+		return models.CorrectionResponse{}, err
+	}
+
+	if msg.ChatID.String() != chatID {
+		return models.CorrectionResponse{}, pgx.ErrNoRows
+	}
+
+	if msg.DeletedAt != nil {
+		return models.CorrectionResponse{}, database.ErrMessageDeleted
+	}
+
+	if msg.SenderID == userID {
+		return models.CorrectionResponse{}, ErrCorrectOwnMessage
+	}
+
+	correction, err := db.SaveCorrection(ctx, &models.CorrectionResponse{
+		MessageID:       msg.ID,
+		ChatID:          msg.ChatID,
+		CorrectorID:     userID,
+		OriginalContent: msg.Content,
+		Content:         content,
+		Diff:            learning.DiffWords(msg.Content, content),
+	})
+	if err != nil {
+		return models.CorrectionResponse{}, err
+	}
+
+	hub.Publish(models.ChatEvent{Type: models.EventCorrectionCreated, ChatID: correction.ChatID, Payload: correction})
+
+	return correction, nil
+}
+
+// AcceptCorrection lets the sender of a message accept a correction of it and broadcasts correction.accepted
+func AcceptCorrection(ctx context.Context, hub *Hub, chatID string, messageID string, correctionID string, userID string) (models.CorrectionResponse, error) {
+
+	db := database.GetPostgresConn()
+
+	correction, err := db.AcceptCorrection(ctx, chatID, messageID, correctionID, userID)
+	if err != nil {
+		return models.CorrectionResponse{}, err
+	}
+
+	hub.Publish(models.ChatEvent{Type: models.EventCorrectionAccepted, ChatID: correction.ChatID, Payload: correction})
+
+	return correction, nil
+}
+
+// RetranslateMessage replaces the translation of a message of chatID into langCode with one
+// from a different provider and broadcasts message.translated with every translation of it
+func RetranslateMessage(ctx context.Context, hub *Hub, chatID string, messageID string, langCode string, provider string) (models.Translation, error) {
+
+	db := database.GetPostgresConn()
+
+	msg, err := db.GetLiveMessage(ctx, messageID)
+	if err != nil {
+		return models.Translation{}, err
+	}
+
+	if msg.ChatID.String() != chatID {
+		return models.Translation{}, pgx.ErrNoRows
+	}
+
+	if msg.DeletedAt != nil {
+		return models.Translation{}, database.ErrMessageDeleted
+	}
+
+	retranslated, err := translation.RetranslateMessage(ctx, msg, langCode, provider)
+	if err != nil {
+		return models.Translation{}, err
+	}
+
+	msg.Translations, err = db.GetMessageTranslations(ctx, messageID)
+	if err != nil {
+		return models.Translation{}, err
+	}
+
+	err = learning.AttachLearningViews(ctx, &msg)
+	if err != nil {
+		log.Printf("Failed to build learning views of message %s: %v", msg.ID, err)
+	}
+
+	transliteration.AttachRomanization(ctx, &msg)
+
+	hub.Publish(models.ChatEvent{Type: models.EventMessageTranslated, ChatID: msg.ChatID, Payload: msg})
+
+	return retranslated, nil
+}